@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/marc"
+)
+
+const exportUsage = `usage: export [flags]
+
+Exports books as MARC 21 records, binary or MARCXML, like GET /admin/export/marc. Every
+book is exported unless -id or -q pick some.
+
+flags:`
+
+// runExport implements the export subcommand
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", marc.FormatBinary, "marc or marcxml")
+	idList := flags.String("id", "", "IDs of the books to export, as id,id")
+	query := flags.String("q", "", "search query of the books to export")
+	limit := flags.Int("limit", 10, "maximum books found by -q")
+	output := flags.String("o", "", "file to write, standard output by default")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, exportUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 || (*format != marc.FormatBinary && *format != marc.FormatXML) || *limit < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var ids []uint
+	if *idList != "" {
+		for _, value := range strings.Split(*idList, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 0)
+			if err != nil {
+				log.Fatalf("invalid book ID %q", value)
+			}
+			ids = append(ids, uint(id))
+		}
+	}
+
+	db := database.New()
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *query != "" {
+		results, err := db.Search(ctx, *query, *limit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		found := []uint{}
+		for _, result := range results {
+			if result.EntityType == database.SearchEntityBook {
+				found = append(found, result.EntityID)
+			}
+		}
+		ids = append(found, ids...)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	writer, err := marc.NewWriter(out, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	count := 0
+	err = db.ExportBooks(ctx, ids, func(book *models.Book) error {
+		count++
+		return writer.Write(marc.RecordOf(book))
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Exported %d books", count)
+}
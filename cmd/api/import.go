@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/importer"
+)
+
+const importUsage = `usage: import [flags] <file>
+
+Imports books, authors or artists from a CSV file with a header row or an NDJSON file
+with an object per line, or books from an ONIX 3.0 message or MARC 21 records, see
+POST /admin/imports for the fields. The import is listed with the jobs of the API, its
+changes are not recorded in the audit log.
+
+flags:`
+
+// runImport implements the import subcommand
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	entity := flags.String("entity", importer.EntityBooks, "what the file holds, one of books, authors or artists")
+	format := flags.String("format", "", "csv, ndjson, onix, marc or marcxml, detected from the file name by default")
+	mapping := flags.String("map", "", "columns of the file to import into fields, as column=field,column=field")
+	dryRun := flags.Bool("dry-run", false, "only validate the rows")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, importUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	path := flags.Arg(0)
+
+	columns := map[string]string{}
+	if *mapping != "" {
+		for _, pair := range strings.Split(*mapping, ",") {
+			column, field, ok := strings.Cut(pair, "=")
+			if !ok {
+				log.Fatalf("invalid mapping %q, use column=field", pair)
+			}
+			columns[strings.TrimSpace(column)] = strings.TrimSpace(field)
+		}
+	}
+
+	if *format == "" {
+		*format = importer.FormatOf(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rows, err := importer.Read(file, *format, *entity, columns)
+	file.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db := database.New()
+	defer db.Close()
+
+	// Stop between two rows on Ctrl+C, the rows imported so far stay
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	job := models.ImportJob{
+		Entity:   *entity,
+		Format:   *format,
+		FileName: filepath.Base(path),
+		DryRun:   *dryRun,
+		Status:   models.ImportStatusRunning,
+		Total:    len(rows),
+	}
+	if err := db.Create(ctx, &job); err != nil {
+		log.Fatal(err)
+	}
+
+	im := importer.New(db, nil)
+	im.Progress = func(job *models.ImportJob) {
+		log.Printf("Imported %d of %d rows", job.Processed, job.Total)
+		if err := db.Update(context.WithoutCancel(ctx), job); err != nil {
+			log.Printf("could not save import job %d: %v", job.ID, err)
+		}
+	}
+	err = im.Run(ctx, &job, rows)
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Status = models.ImportStatusSucceeded
+	if err != nil {
+		job.Status = models.ImportStatusFailed
+		job.Message = err.Error()
+	}
+	if err := db.Update(context.WithoutCancel(ctx), &job); err != nil {
+		log.Printf("could not save import job %d: %v", job.ID, err)
+	}
+
+	if len(job.Errors) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "LINE\tFIELD\tERROR")
+		for _, rowErr := range job.Errors {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", rowErr.Row, rowErr.Field, rowErr.Message)
+		}
+		w.Flush()
+	}
+
+	verb := "Imported"
+	if job.DryRun {
+		verb = "Checked"
+	}
+	log.Printf("%s %d of %d rows: %d created, %d updated, %d unchanged, %d rejected",
+		verb, job.Processed, job.Total, job.Created, job.Updated, job.Unchanged, job.Failed)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+	if job.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"go-playground/internal/database"
+)
+
+const migrateUsage = `usage: migrate <command>
+
+commands:
+  up        apply all pending migrations
+  down [n]  revert the last n applied migrations (default 1)
+  status    list the migrations and whether they are applied`
+
+// runMigrate implements the migrate subcommand
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	migrator, err := database.NewMigrator()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up()
+		for _, migration := range done {
+			log.Printf("Applied %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(done) == 0 {
+			log.Println("Database schema is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("invalid number of migrations %q", args[1])
+			}
+		}
+
+		done, err := migrator.Down(steps)
+		for _, migration := range done {
+			log.Printf("Reverted %04d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(done) == 0 {
+			log.Println("No migrations to revert")
+		}
+
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, entry := range status {
+			appliedAt := "pending"
+			if entry.AppliedAt != nil {
+				appliedAt = entry.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", entry.Version, entry.Name, appliedAt)
+		}
+		w.Flush()
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
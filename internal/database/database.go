@@ -5,101 +5,264 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"go-playground/internal/database/models"
+	"go-playground/internal/database/query"
 	"go-playground/internal/server/utils"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	_ "github.com/joho/godotenv/autoload"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Service represents a service that interacts with a database.
 type Service interface {
 	// Health returns a map of health status information.
 	// The keys and values in the map are service-specific.
-	Health() map[string]string
+	Health(ctx context.Context) map[string]string
 
 	// Close terminates the database connection.
 	// It returns an error if the connection cannot be closed.
 	Close() error
 
 	// ListAuthors returns a list of authors from the database.
-	Create(entity any) error
-	Read(entity any, id uint) error
-	Update(entity any) error
-	Delete(entity any, id uint) error
-	List(entities any, limit int, offset int) error
-
-	GetAuthor(id uint) (*models.Author, error)
+	Create(ctx context.Context, entity any) error
+	Read(ctx context.Context, entity any, id uint) error
+	// ReadAll loads the records with the given IDs, failing with ErrNotFound when any is missing
+	ReadAll(ctx context.Context, entities any, ids []uint) error
+	// Update saves the fields of entity without its associations. A model embedding
+	// models.Versioned fails with ErrStale when its row was saved since it was read.
+	Update(ctx context.Context, entity any) error
+	Delete(ctx context.Context, entity any, id uint) error
+	List(ctx context.Context, entities any, q query.ListQuery) (query.Page, error)
+
+	// ListTrash, Restore and Purge work on soft deleted records only, anything else is not found
+	ListTrash(ctx context.Context, entities any, q query.ListQuery) (query.Page, error)
+	ListTrashedBefore(ctx context.Context, entity any, before time.Time) ([]uint, error)
+	Restore(ctx context.Context, entity any, id uint) error
+	Purge(ctx context.Context, entity any, id uint) error
+
+	// WithTx runs fn in a transaction, every call on the Service passed to fn is part of it.
+	// It commits when fn returns nil and rolls back when fn fails or panics.
+	WithTx(ctx context.Context, fn func(tx Service) error) error
+
+	GetAuthor(ctx context.Context, id uint) (*models.Author, error)
+
+	ListBooks(ctx context.Context, q query.ListQuery) ([]models.Book, query.Page, error)
+	GetBook(ctx context.Context, id uint) (*models.Book, error)
+
+	GetArtist(ctx context.Context, id uint) (*models.Artist, error)
+
+	GetUser(ctx context.Context, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+	ListUsers(ctx context.Context, q query.ListQuery) ([]models.User, query.Page, error)
+	GetRole(ctx context.Context, name string) (*models.Role, error)
+
+	ListCovers(ctx context.Context, q query.ListQuery) ([]models.Cover, query.Page, error)
+	GetCover(ctx context.Context, id uint) (*models.Cover, error)
+	// SaveCoverImage saves the image fields of a cover and replaces its variants
+	SaveCoverImage(ctx context.Context, cover *models.Cover, variants []models.CoverVariant) error
+
+	GetGenre(ctx context.Context, id uint) (*models.Genre, error)
+	GetGenreBySlug(ctx context.Context, slug string) (*models.Genre, error)
+	GenreDescendantIDs(ctx context.Context, id uint) ([]uint, error)
+	ListBooksByGenre(ctx context.Context, genreIDs []uint, q query.ListQuery) ([]models.Book, query.Page, error)
+
+	ListRevisions(ctx context.Context, entityType string, entityID uint, q query.ListQuery) ([]models.Revision, query.Page, error)
+	GetRevision(ctx context.Context, entityType string, entityID uint, version uint) (*models.Revision, error)
+	AddRevision(ctx context.Context, revision *models.Revision, baseline models.Snapshot) error
+
+	FindBookByISBN(ctx context.Context, isbn string) (*models.Book, error)
+	FindAuthorByName(ctx context.Context, firstName string, lastName string) (*models.Author, error)
+	FindArtistByName(ctx context.Context, firstName string, lastName string) (*models.Artist, error)
+	FailUnfinishedImportJobs(ctx context.Context, message string) error
+
+	// The exports stream every record of a table to fn, see export.go
+	ExportBooks(ctx context.Context, ids []uint, fn func(book *models.Book) error) error
+	ExportAuthors(ctx context.Context, fn func(author *models.Author) error) error
+	ExportArtists(ctx context.Context, fn func(artist *models.Artist) error) error
+	ExportCovers(ctx context.Context, fn func(cover *models.Cover) error) error
+
+	FindAssociation(ctx context.Context, entity any, association string, values any) error
+	ReplaceAssociation(ctx context.Context, entity any, association string, values any) error
+
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+	RebuildSearchIndex(ctx context.Context) error
+
+	GetSessionByTokenHash(ctx context.Context, hash string) (*models.Session, error)
+	RotateSession(ctx context.Context, current *models.Session, next *models.Session) error
+	RevokeSessionFamily(ctx context.Context, familyID string) error
+	RevokeUserSessions(ctx context.Context, userID uint) error
+	ListUserSessions(ctx context.Context, userID uint) ([]models.Session, error)
+}
 
-	ListBooks(limit int, offset int) ([]models.Book, error)
-	GetBook(id uint) (*models.Book, error)
+type service struct {
+	db *gorm.DB
 
-	GetArtist(id uint) (*models.Artist, error)
+	// search is nil when the database does not support full-text search
+	search searchBackend
 
-	GetUser(username string) (*models.User, error)
-	GetUserByRefreshToken(token string) (*models.User, error)
+	// queryTimeout bounds every query, zero leaves them bounded by the caller's context only
+	queryTimeout time.Duration
+}
 
-	ListCovers(limit int, offset int) ([]models.Cover, error)
+// defaultQueryTimeout applies unless BLUEPRINT_DB_QUERY_TIMEOUT is set
+const defaultQueryTimeout = 5 * time.Second
 
-	ClearRefreshToken(token string) error
-}
+// withContext binds queries to the context of the caller, so they are cancelled when
+// the client goes away or the server shuts down, and bounds them by the query timeout.
+func (s *service) withContext(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return s.db.WithContext(ctx), func() {}
+	}
 
-type service struct {
-	db *gorm.DB
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	return s.db.WithContext(ctx), cancel
 }
 
 var (
+	dbdriver   = os.Getenv("BLUEPRINT_DB_DRIVER")
 	dburl      = os.Getenv("BLUEPRINT_DB_URL")
 	dbInstance *service
 )
 
+// open connects to the database configured by BLUEPRINT_DB_DRIVER and BLUEPRINT_DB_URL.
+// The driver is one of sqlite (default), postgres or mysql, the URL is a DSN for that driver.
+func open() (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch dbdriver {
+	case "", "sqlite":
+		dialector = sqlite.Open(dburl)
+	case "postgres":
+		dialector = postgres.Open(dburl)
+	case "mysql":
+		config, err := mysqldriver.ParseDSN(dburl)
+		if err != nil {
+			return nil, err
+		}
+		// Times are scanned into time.Time and migrations contain several statements
+		config.ParseTime = true
+		config.MultiStatements = true
+		dialector = mysql.Open(config.FormatDSN())
+	default:
+		return nil, fmt.Errorf("unknown BLUEPRINT_DB_DRIVER %q, use sqlite, postgres or mysql", dbdriver)
+	}
+
+	return gorm.Open(dialector, &gorm.Config{})
+}
+
 func New() Service {
 	// Reuse Connection
 	if dbInstance != nil {
 		return dbInstance
 	}
 
-	db, err := gorm.Open(sqlite.Open(dburl), &gorm.Config{})
+	db, err := open()
 	if err != nil {
 		// This will not be a connection error, but a DSN parse error or
 		// another initialization error.
 		log.Fatal(err)
 	}
 
-	// AutoMigrate the models to create the table if it doesn't exist
-	err = db.AutoMigrate(&models.Author{}, &models.Artist{}, &models.Book{}, &models.Cover{}, &models.User{})
+	// Refuse to run against a schema the code does not match, migrations are applied with `migrate up`
+	migrator, err := newMigrator(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("Database schema is behind by %d migration(s), run `migrate up` first", len(pending))
+	}
 
-	// Seed the database with an admin user if it doesn't exist
+	// Seed the built-in roles and their permissions
+	if err := seedRoles(db); err != nil {
+		log.Fatal(err)
+	}
+
+	var adminRole models.Role
+	if err := db.Where("name = ?", models.RoleAdmin).First(&adminRole).Error; err != nil {
+		log.Fatal(err)
+	}
+
+	// Seed the database with an admin user if it never existed, a deleted admin stays deleted
 	var user models.User
 	if err := db.Where("username = ?", "admin").First(&user).Error; err != nil {
-		password, err := utils.HashPassword("admin")
-		if err != nil {
+		var deleted int64
+		if err := db.Unscoped().Model(&models.User{}).Where("username = ?", "admin").Count(&deleted).Error; err != nil {
 			log.Fatal(err)
 		}
+		if deleted > 0 {
+			log.Printf("The admin user was deleted, it is not seeded again")
+		} else {
+			password, err := utils.HashPassword("admin")
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if err := db.Create(&models.User{Username: "admin", Password: password, RoleID: &adminRole.ID, MustChangePassword: true}).Error; err != nil {
+				log.Fatal(err)
+			}
+		}
+	} else {
+		// Admin users created before roles existed keep full access
+		if user.RoleID == nil {
+			if err := db.Model(&user).Update("role_id", adminRole.ID).Error; err != nil {
+				log.Fatal(err)
+			}
+		}
 
-		if err := db.Create(&models.User{Username: "admin", Password: password}).Error; err != nil {
-			log.Fatal(err)
+		// An admin still using the seeded password has to change it on the next login
+		if defaultPassword, _ := utils.VerifyPassword(user.Password, "admin"); defaultPassword && !user.MustChangePassword {
+			if err := db.Model(&user).Update("must_change_password", true).Error; err != nil {
+				log.Fatal(err)
+			}
 		}
 	}
 
-	if err != nil {
+	// Full-text search depends on the dialect, SQLite has to be built with FTS5 (-tags sqlite_fts5)
+	search := newSearchBackend(db.Dialector.Name())
+	if err := search.setup(db); err != nil {
+		log.Printf("Full-text search disabled: %v", err)
+		search = nil
+	}
+
+	// Queries of the service report the typed errors of errors.go
+	if err := registerErrorTranslation(db); err != nil {
 		log.Fatal(err)
 	}
 
+	queryTimeout := defaultQueryTimeout
+	if value := os.Getenv("BLUEPRINT_DB_QUERY_TIMEOUT"); value != "" {
+		queryTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("invalid BLUEPRINT_DB_QUERY_TIMEOUT: %v", err)
+		}
+	}
+
 	dbInstance = &service{
 		db: db,
+
+		search:       search,
+		queryTimeout: queryTimeout,
 	}
 	return dbInstance
 }
 
 // Health checks the health of the database connection by pinging the database.
 // It returns a map with keys indicating various health statistics.
-func (s *service) Health() map[string]string {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+func (s *service) Health(ctx context.Context) map[string]string {
+	pingCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
 
 	stats := make(map[string]string)
@@ -113,10 +276,14 @@ func (s *service) Health() map[string]string {
 	}
 
 	// Ping the database
-	err = sqlDB.PingContext(ctx)
+	err = sqlDB.PingContext(pingCtx)
 	if err != nil {
 		stats["status"] = "down"
 		stats["error"] = fmt.Sprintf("db down: %v", err)
+		if ctx.Err() != nil {
+			// The caller gave up, that says nothing about the database
+			return stats
+		}
 		log.Fatalf("db down: %v", err) // Log the error and terminate the program
 		return stats
 	}
@@ -169,132 +336,369 @@ func (s *service) Close() error {
 	return sqlDB.Close()
 }
 
-func (s *service) Create(entity any) error {
-	if !s.db.Migrator().HasTable(entity) {
-		return fmt.Errorf("a table for %v does not exist", entity)
+// requireTable checks that there is a table for entity. When the query context has
+// ended it reports that instead, the lookup fails for that reason too.
+func requireTable(db *gorm.DB, entity any) error {
+	if db.Migrator().HasTable(entity) {
+		return nil
+	}
+	if err := db.Statement.Context.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("a table for %v does not exist", entity)
+}
+
+func (s *service) Create(ctx context.Context, entity any) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	if err := requireTable(db, entity); err != nil {
+		return err
 	}
 
-	result := s.db.Create(entity)
+	// The database default is not read back on every dialect, so set the first version here
+	if versioned, ok := entity.(models.VersionedModel); ok && versioned.CurrentVersion() == 0 {
+		versioned.SetVersion(1)
+	}
+
+	result := db.Create(entity)
 	if result.Error != nil {
 		return result.Error
 	}
 	return nil
 }
 
-func (s *service) Read(entity any, id uint) error {
-	if !s.db.Migrator().HasTable(entity) {
-		return fmt.Errorf("a table for %v does not exist", entity)
+func (s *service) Read(ctx context.Context, entity any, id uint) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	if err := requireTable(db, entity); err != nil {
+		return err
 	}
 
-	result := s.db.First(entity, id)
+	result := db.First(entity, id)
 	if result.Error != nil {
 		return result.Error
 	}
 	return nil
 }
 
-func (s *service) Update(entity any) error {
-	if !s.db.Migrator().HasTable(entity) {
-		return fmt.Errorf("a table for %v does not exist", entity)
+// ReadAll loads the records with the given IDs into entities, a pointer to a slice.
+// The error names every ID without a record.
+func (s *service) ReadAll(ctx context.Context, entities any, ids []uint) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	if err := requireTable(db, entities); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
 	}
 
-	result := s.db.Save(entity)
-	if result.Error != nil {
-		return result.Error
+	if err := db.Find(entities, ids).Error; err != nil {
+		return err
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(entities); err != nil {
+		return err
+	}
+
+	found := map[uint]bool{}
+	rows := reflect.Indirect(reflect.ValueOf(entities))
+	for i := 0; i < rows.Len(); i++ {
+		id, _ := stmt.Schema.PrioritizedPrimaryField.ValueOf(ctx, reflect.Indirect(rows.Index(i)))
+		found[id.(uint)] = true
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, strconv.FormatUint(uint64(id), 10))
+			found[id] = true
+		}
+	}
+	if len(missing) > 0 {
+		return &Error{Kind: ErrNotFound, Entity: stmt.Schema.Name, Message: "not found: " + strings.Join(missing, ", ")}
 	}
 	return nil
 }
 
-func (s *service) Delete(entity any, id uint) error {
-	if !s.db.Migrator().HasTable(entity) {
-		return fmt.Errorf("a table for %v does not exist", entity)
+func (s *service) Update(ctx context.Context, entity any) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	if err := requireTable(db, entity); err != nil {
+		return err
 	}
 
-	result := s.db.Delete(entity, id)
+	return save(db, entity)
+}
+
+// save writes every field of entity. A versioned model is only written when its row still has
+// the version the model was read with, the version is incremented with every write.
+func save(db *gorm.DB, entity any) error {
+	versioned, ok := entity.(models.VersionedModel)
+	if !ok {
+		return db.Save(entity).Error
+	}
+
+	version := versioned.CurrentVersion()
+	versioned.SetVersion(version + 1)
+
+	// Save would insert the row when the update matches none, so update all fields instead.
+	// Unlike Save this leaves associations alone, they are replaced through ReplaceAssociation.
+	result := db.Model(entity).Where("version = ?", version).Select("*").Omit(clause.Associations).Updates(entity)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = &Error{Kind: ErrStale, Entity: reflect.Indirect(reflect.ValueOf(entity)).Type().Name()}
+	}
 	if result.Error != nil {
+		versioned.SetVersion(version)
 		return result.Error
 	}
 	return nil
 }
 
-func (s *service) List(entities any, limit int, offset int) error {
-	if !s.db.Migrator().HasTable(entities) {
-		return fmt.Errorf("a table for %v does not exist", entities)
+func (s *service) Delete(ctx context.Context, entity any, id uint) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	if err := requireTable(db, entity); err != nil {
+		return err
 	}
 
-	result := s.db.Limit(limit).Offset(offset).Find(entities)
+	result := db.Delete(entity, id)
 	if result.Error != nil {
 		return result.Error
 	}
-
 	return nil
 }
 
-func (s *service) GetAuthor(id uint) (*models.Author, error) {
+func (s *service) List(ctx context.Context, entities any, q query.ListQuery) (query.Page, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	if err := requireTable(db, entities); err != nil {
+		return query.Page{}, err
+	}
+
+	return findPage(db, entities, q)
+}
+
+// WithTx runs fn with a Service bound to a new transaction. Each query in it is bounded
+// by the query timeout, the transaction as a whole only by ctx.
+func (s *service) WithTx(ctx context.Context, fn func(tx Service) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&service{db: tx, search: s.search, queryTimeout: s.queryTimeout})
+	})
+}
+
+func (s *service) GetAuthor(ctx context.Context, id uint) (*models.Author, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
 	var author models.Author
-	if err := s.db.Preload("Books").First(&author, id).Error; err != nil {
+	if err := db.Preload("Books").First(&author, id).Error; err != nil {
 		return nil, err
 	}
 	return &author, nil
 }
 
-func (s *service) ListBooks(limit int, offset int) ([]models.Book, error) {
+func (s *service) ListBooks(ctx context.Context, q query.ListQuery) ([]models.Book, query.Page, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
 	var books []models.Book
-	if err := s.db.Preload("Cover").Preload("Author").Limit(limit).Offset(offset).Find(&books).Error; err != nil {
-		return nil, err
+	page, err := findPage(db.Preload("Cover").Preload("Cover.Variants").Preload("Author").Preload("Genres"), &books, q)
+	if err != nil {
+		return nil, page, err
 	}
-	return books, nil
+	return books, page, nil
 }
 
-func (s *service) GetBook(id uint) (*models.Book, error) {
+func (s *service) GetBook(ctx context.Context, id uint) (*models.Book, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
 	var book models.Book
-	if err := s.db.Preload("Cover.Artists").Preload("Cover").Preload("Author").First(&book, id).Error; err != nil {
+	if err := db.Preload("Cover.Artists").Preload("Cover.Variants").Preload("Cover").Preload("Author").Preload("Genres").First(&book, id).Error; err != nil {
 		return nil, err
 	}
 	return &book, nil
 }
 
-func (s *service) GetUser(username string) (*models.User, error) {
+func (s *service) GetUser(ctx context.Context, username string) (*models.User, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var user models.User
+	if err := db.Preload("Role.Permissions").Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *service) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
 	var user models.User
-	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+	if err := db.Preload("Role.Permissions").First(&user, id).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (s *service) ListCovers(limit int, offset int) ([]models.Cover, error) {
+func (s *service) ListUsers(ctx context.Context, q query.ListQuery) ([]models.User, query.Page, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var users []models.User
+	page, err := findPage(db.Preload("Role"), &users, q)
+	if err != nil {
+		return nil, page, err
+	}
+	return users, page, nil
+}
+
+func (s *service) ListCovers(ctx context.Context, q query.ListQuery) ([]models.Cover, query.Page, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
 	var covers []models.Cover
-	if err := s.db.Preload("Artists").Limit(limit).Offset(offset).Find(&covers).Error; err != nil {
+	page, err := findPage(db.Preload("Artists").Preload("Variants"), &covers, q)
+	if err != nil {
+		return nil, page, err
+	}
+	return covers, page, nil
+}
+
+func (s *service) GetCover(ctx context.Context, id uint) (*models.Cover, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var cover models.Cover
+	if err := db.Preload("Variants").First(&cover, id).Error; err != nil {
 		return nil, err
 	}
-	return covers, nil
+	return &cover, nil
+}
+
+func (s *service) SaveCoverImage(ctx context.Context, cover *models.Cover, variants []models.CoverVariant) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Variants are derived from the image, the old ones are of no use once it is replaced
+		if err := tx.Unscoped().Where("cover_id = ?", cover.ID).Delete(&models.CoverVariant{}).Error; err != nil {
+			return err
+		}
+
+		if err := save(tx, cover); err != nil {
+			return err
+		}
+
+		for i := range variants {
+			variants[i].CoverID = cover.ID
+		}
+		cover.Variants = variants
+		if len(variants) == 0 {
+			return nil
+		}
+		return tx.Create(&cover.Variants).Error
+	})
 }
 
-func (s *service) GetArtist(id uint) (*models.Artist, error) {
+func (s *service) GetArtist(ctx context.Context, id uint) (*models.Artist, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
 	var artist models.Artist
-	if err := s.db.Preload("Covers").Preload("Covers.Book").First(&artist, id).Error; err != nil {
+	if err := db.Preload("Covers").Preload("Covers.Book").First(&artist, id).Error; err != nil {
 		return nil, err
 	}
 	return &artist, nil
 }
 
-func (s *service) GetUserByRefreshToken(token string) (*models.User, error) {
-	var user models.User
-	if err := s.db.Where("refresh_token = ?", token).First(&user).Error; err != nil {
+func (s *service) GetGenre(ctx context.Context, id uint) (*models.Genre, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var genre models.Genre
+	if err := db.Preload("Parent").Preload("Children").First(&genre, id).Error; err != nil {
 		return nil, err
 	}
-	return &user, nil
+	return &genre, nil
 }
 
-func (s *service) ClearRefreshToken(token string) error {
-	var user models.User
-	if err := s.db.Where("refresh_token = ?", token).First(&user).Error; err != nil {
-		return err
+func (s *service) GetGenreBySlug(ctx context.Context, slug string) (*models.Genre, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var genre models.Genre
+	if err := db.Preload("Parent").Preload("Children").Where("slug = ?", slug).First(&genre).Error; err != nil {
+		return nil, err
 	}
+	return &genre, nil
+}
 
-	user.RefreshToken = ""
-	if err := s.db.Save(&user).Error; err != nil {
-		return err
+// GenreDescendantIDs returns the IDs of the genre and every genre below it in the taxonomy.
+// The tree is walked one level at a time so it works on every supported dialect.
+func (s *service) GenreDescendantIDs(ctx context.Context, id uint) ([]uint, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	ids := []uint{id}
+	seen := map[uint]bool{id: true}
+	level := []uint{id}
+
+	for len(level) > 0 {
+		var children []uint
+		if err := db.Model(&models.Genre{}).Where("parent_id IN ?", level).Pluck("id", &children).Error; err != nil {
+			return nil, err
+		}
+
+		level = level[:0]
+		for _, child := range children {
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+			ids = append(ids, child)
+			level = append(level, child)
+		}
 	}
-	return nil
+
+	return ids, nil
+}
+
+func (s *service) ListBooksByGenre(ctx context.Context, genreIDs []uint, q query.ListQuery) ([]models.Book, query.Page, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var books []models.Book
+	subQuery := db.Table("book_genres").Select("book_id").Where("genre_id IN ?", genreIDs)
+	page, err := findPage(db.Preload("Cover").Preload("Cover.Variants").Preload("Author").Preload("Genres").Where("id IN (?)", subQuery), &books, q)
+	if err != nil {
+		return nil, page, err
+	}
+	return books, page, nil
+}
+
+// FindAssociation loads the records linked to entity through the named association into values
+func (s *service) FindAssociation(ctx context.Context, entity any, association string, values any) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	return db.Model(entity).Association(association).Find(values)
+}
+
+// ReplaceAssociation replaces the named association of entity with values,
+// removing any existing links that are not part of values.
+func (s *service) ReplaceAssociation(ctx context.Context, entity any, association string, values any) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	return db.Model(entity).Association(association).Replace(values)
 }
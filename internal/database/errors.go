@@ -0,0 +1,144 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// Kinds of failures the Service reports, check them with errors.Is
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflicts with an existing record")
+	ErrConstraint = errors.New("violates a constraint")
+	ErrValidation = errors.New("is invalid")
+	ErrStale      = errors.New("was changed by another request, reload it and try again")
+)
+
+// Error is a failure of a query that callers can act on, such as a missing record
+// or a duplicate key. It matches its Kind with errors.Is and wraps the driver error.
+type Error struct {
+	Kind    error
+	Entity  string // model the query ran against, e.g. Book
+	Field   string // column at fault, when the database names it
+	Message string // replaces the text of Kind
+	Err     error
+}
+
+func (e *Error) Error() string {
+	var parts []string
+	if e.Entity != "" {
+		parts = append(parts, e.Entity)
+	}
+	if e.Field != "" {
+		parts = append(parts, e.Field)
+	}
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	} else {
+		parts = append(parts, e.Kind.Error())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// registerErrorTranslation turns the errors of every query into an Error once it ran,
+// so callers never have to know the error codes of the dialect.
+func registerErrorTranslation(db *gorm.DB) error {
+	translate := func(tx *gorm.DB) {
+		if tx.Error == nil {
+			return
+		}
+		entity := ""
+		if tx.Statement.Schema != nil {
+			entity = tx.Statement.Schema.Name
+		}
+		tx.Error = translateError(tx.Error, entity)
+	}
+
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().After("*").Register("app:translate_error", translate),
+		callbacks.Query().After("*").Register("app:translate_error", translate),
+		callbacks.Update().After("*").Register("app:translate_error", translate),
+		callbacks.Delete().After("*").Register("app:translate_error", translate),
+		callbacks.Row().After("*").Register("app:translate_error", translate),
+		callbacks.Raw().After("*").Register("app:translate_error", translate),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translateError maps a driver error to an Error, other errors are returned as they are
+func translateError(err error, entity string) error {
+	var translated *Error
+	if errors.As(err, &translated) {
+		return err
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Error{Kind: ErrNotFound, Entity: entity, Err: err}
+	}
+
+	var (
+		sqliteErr sqlite3.Error
+		pgErr     *pgconn.PgError
+		mysqlErr  *mysqldriver.MySQLError
+	)
+	switch {
+	case errors.As(err, &sqliteErr):
+		// The message names the columns, e.g. "UNIQUE constraint failed: users.username"
+		field := ""
+		if _, columns, ok := strings.Cut(sqliteErr.Error(), ": "); ok {
+			column, _, _ := strings.Cut(columns, ", ")
+			field = column[strings.LastIndex(column, ".")+1:]
+		}
+
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return &Error{Kind: ErrConflict, Entity: entity, Field: field, Err: err}
+		case sqlite3.ErrConstraintNotNull:
+			return &Error{Kind: ErrValidation, Entity: entity, Field: field, Message: "is required", Err: err}
+		case sqlite3.ErrConstraintForeignKey, sqlite3.ErrConstraintCheck:
+			return &Error{Kind: ErrConstraint, Entity: entity, Err: err}
+		}
+	case errors.As(err, &pgErr):
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			return &Error{Kind: ErrConflict, Entity: entity, Err: err}
+		case "23502": // not_null_violation
+			return &Error{Kind: ErrValidation, Entity: entity, Field: pgErr.ColumnName, Message: "is required", Err: err}
+		case "23503", "23514": // foreign_key_violation, check_violation
+			return &Error{Kind: ErrConstraint, Entity: entity, Err: err}
+		}
+	case errors.As(err, &mysqlErr):
+		switch mysqlErr.Number {
+		case 1062: // ER_DUP_ENTRY
+			return &Error{Kind: ErrConflict, Entity: entity, Err: err}
+		case 1048: // ER_BAD_NULL_ERROR, "Column 'title' cannot be null"
+			field := ""
+			if parts := strings.Split(mysqlErr.Message, "'"); len(parts) > 1 {
+				field = parts[1]
+			}
+			return &Error{Kind: ErrValidation, Entity: entity, Field: field, Message: "is required", Err: err}
+		case 1451, 1452, 3819: // ER_ROW_IS_REFERENCED_2, ER_NO_REFERENCED_ROW_2, ER_CHECK_CONSTRAINT_VIOLATED
+			return &Error{Kind: ErrConstraint, Entity: entity, Err: err}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,248 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"go-playground/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+// The exports read the whole table through one cursor, passing every record to fn as soon as
+// it is read instead of loading the table first. They are ordered by ID and stop at the first
+// error of fn. The to-many relations are joined in, so a record spans several rows in a row.
+// They are not bound by the query timeout, an export takes as long as the client reads.
+
+// bookExportRow is a book joined with its author, cover and one of its genres
+type bookExportRow struct {
+	ID               uint
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Version          uint
+	Title            string
+	PublishedDate    time.Time
+	DigitalOnly      bool
+	Pages            uint
+	Description      string
+	ISBN             string `gorm:"column:isbn"`
+	Price            float32
+	AuthorID         uint
+	AuthorFirstName  sql.NullString
+	AuthorLastName   sql.NullString
+	CoverID          sql.NullInt64
+	CoverDesignIdeas sql.NullString
+	CoverImageURL    sql.NullString
+	GenreID          sql.NullInt64
+	GenreName        sql.NullString
+	GenreSlug        sql.NullString
+}
+
+// ExportBooks streams every book with its author, cover and genres to fn, or only the books
+// with the IDs unless they are nil
+func (s *service) ExportBooks(ctx context.Context, ids []uint, fn func(book *models.Book) error) error {
+	if ids != nil && len(ids) == 0 {
+		return nil
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.Book{})
+	if ids != nil {
+		query = query.Where("books.id IN ?", ids)
+	}
+	rows, err := query.
+		Select(`books.id, books.created_at, books.updated_at, books.version, books.title, books.published_date,
+			books.digital_only, books.pages, books.description, books.isbn, books.price, books.author_id,
+			authors.first_name AS author_first_name, authors.last_name AS author_last_name,
+			covers.id AS cover_id, covers.design_ideas AS cover_design_ideas, covers.image_url AS cover_image_url,
+			genres.id AS genre_id, genres.name AS genre_name, genres.slug AS genre_slug`).
+		Joins("LEFT JOIN authors ON authors.id = books.author_id AND authors.deleted_at IS NULL").
+		Joins("LEFT JOIN covers ON covers.book_id = books.id AND covers.deleted_at IS NULL").
+		Joins("LEFT JOIN book_genres ON book_genres.book_id = books.id").
+		Joins("LEFT JOIN genres ON genres.id = book_genres.genre_id AND genres.deleted_at IS NULL").
+		Order("books.id, covers.id, genres.id").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var book *models.Book
+	for rows.Next() {
+		var row bookExportRow
+		if err := s.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+
+		if book == nil || book.ID != row.ID {
+			if book != nil {
+				if err := fn(book); err != nil {
+					return err
+				}
+			}
+
+			book = &models.Book{
+				Model:         gorm.Model{ID: row.ID, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt},
+				Versioned:     models.Versioned{Version: row.Version},
+				Title:         row.Title,
+				PublishedDate: row.PublishedDate,
+				DigitalOnly:   row.DigitalOnly,
+				Pages:         row.Pages,
+				Description:   row.Description,
+				ISBN:          row.ISBN,
+				Price:         row.Price,
+				AuthorID:      row.AuthorID,
+				Author: models.Author{
+					Model:     gorm.Model{ID: row.AuthorID},
+					FirstName: row.AuthorFirstName.String,
+					LastName:  row.AuthorLastName.String,
+				},
+				Genres: []*models.Genre{},
+			}
+			// A book has one cover, should there be more the first one is exported
+			if row.CoverID.Valid {
+				book.Cover = models.Cover{
+					Model:       gorm.Model{ID: uint(row.CoverID.Int64)},
+					DesignIdeas: row.CoverDesignIdeas,
+					ImageURL:    row.CoverImageURL,
+					BookID:      row.ID,
+				}
+			}
+		}
+
+		if row.GenreID.Valid && !slices.ContainsFunc(book.Genres, func(genre *models.Genre) bool { return genre.ID == uint(row.GenreID.Int64) }) {
+			book.Genres = append(book.Genres, &models.Genre{
+				Model: gorm.Model{ID: uint(row.GenreID.Int64)},
+				Name:  row.GenreName.String,
+				Slug:  row.GenreSlug.String,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if book != nil {
+		return fn(book)
+	}
+	return nil
+}
+
+// coverExportRow is a cover joined with its book and one of its artists
+type coverExportRow struct {
+	ID              uint
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Version         uint
+	DesignIdeas     sql.NullString
+	ImageURL        sql.NullString
+	ImageWidth      int
+	ImageHeight     int
+	DominantColor   string
+	BookID          uint
+	BookTitle       sql.NullString
+	BookISBN        sql.NullString `gorm:"column:book_isbn"`
+	ArtistID        sql.NullInt64
+	ArtistFirstName sql.NullString
+	ArtistLastName  sql.NullString
+}
+
+// ExportCovers streams every cover with its book and artists to fn
+func (s *service) ExportCovers(ctx context.Context, fn func(cover *models.Cover) error) error {
+	rows, err := s.db.WithContext(ctx).Model(&models.Cover{}).
+		Select(`covers.id, covers.created_at, covers.updated_at, covers.version, covers.design_ideas, covers.image_url,
+			covers.image_width, covers.image_height, covers.dominant_color, covers.book_id,
+			books.title AS book_title, books.isbn AS book_isbn,
+			artists.id AS artist_id, artists.first_name AS artist_first_name, artists.last_name AS artist_last_name`).
+		Joins("LEFT JOIN books ON books.id = covers.book_id AND books.deleted_at IS NULL").
+		Joins("LEFT JOIN artist_covers ON artist_covers.cover_id = covers.id").
+		Joins("LEFT JOIN artists ON artists.id = artist_covers.artist_id AND artists.deleted_at IS NULL").
+		Order("covers.id, artists.id").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var cover *models.Cover
+	for rows.Next() {
+		var row coverExportRow
+		if err := s.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+
+		if cover == nil || cover.ID != row.ID {
+			if cover != nil {
+				if err := fn(cover); err != nil {
+					return err
+				}
+			}
+
+			cover = &models.Cover{
+				Model:         gorm.Model{ID: row.ID, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt},
+				Versioned:     models.Versioned{Version: row.Version},
+				DesignIdeas:   row.DesignIdeas,
+				ImageURL:      row.ImageURL,
+				ImageWidth:    row.ImageWidth,
+				ImageHeight:   row.ImageHeight,
+				DominantColor: row.DominantColor,
+				BookID:        row.BookID,
+				Artists:       []*models.Artist{},
+			}
+			if row.BookTitle.Valid {
+				cover.Book = &models.Book{
+					Model: gorm.Model{ID: row.BookID},
+					Title: row.BookTitle.String,
+					ISBN:  row.BookISBN.String,
+				}
+			}
+		}
+
+		if row.ArtistID.Valid {
+			cover.Artists = append(cover.Artists, &models.Artist{
+				Model:     gorm.Model{ID: uint(row.ArtistID.Int64)},
+				FirstName: row.ArtistFirstName.String,
+				LastName:  row.ArtistLastName.String,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if cover != nil {
+		return fn(cover)
+	}
+	return nil
+}
+
+// ExportAuthors streams every author to fn
+func (s *service) ExportAuthors(ctx context.Context, fn func(author *models.Author) error) error {
+	return exportTable(s.db.WithContext(ctx), fn)
+}
+
+// ExportArtists streams every artist to fn
+func (s *service) ExportArtists(ctx context.Context, fn func(artist *models.Artist) error) error {
+	return exportTable(s.db.WithContext(ctx), fn)
+}
+
+// exportTable streams the rows of the table of T without any relations
+func exportTable[T any](db *gorm.DB, fn func(entity *T) error) error {
+	rows, err := db.Model(new(T)).Order("id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entity := new(T)
+		if err := db.ScanRows(rows, entity); err != nil {
+			return err
+		}
+		if err := fn(entity); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
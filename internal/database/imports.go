@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go-playground/internal/database/models"
+)
+
+// FindBookByISBN looks up a book by its ISBN, an ISBN-10 finds the book stored with the ISBN-13
+// and the other way around. Hyphens and spaces are ignored.
+func (s *service) FindBookByISBN(ctx context.Context, isbn string) (*models.Book, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	isbn = models.ISBN13(isbn)
+	if isbn == "" {
+		return nil, &Error{Kind: ErrNotFound, Entity: "Book"}
+	}
+
+	var book models.Book
+	err := db.Preload("Genres").
+		Where("isbn13 = ?", isbn).
+		Order("id").
+		First(&book).Error
+	if err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+// FindAuthorByName looks up an author by first and last name, ignoring case
+func (s *service) FindAuthorByName(ctx context.Context, firstName string, lastName string) (*models.Author, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var author models.Author
+	err := db.Where("LOWER(first_name) = LOWER(?) AND LOWER(last_name) = LOWER(?)", firstName, lastName).
+		Order("id").
+		First(&author).Error
+	if err != nil {
+		return nil, err
+	}
+	return &author, nil
+}
+
+// FindArtistByName looks up an artist by first and last name, ignoring case
+func (s *service) FindArtistByName(ctx context.Context, firstName string, lastName string) (*models.Artist, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var artist models.Artist
+	err := db.Where("LOWER(first_name) = LOWER(?) AND LOWER(last_name) = LOWER(?)", firstName, lastName).
+		Order("id").
+		First(&artist).Error
+	if err != nil {
+		return nil, err
+	}
+	return &artist, nil
+}
+
+// FailUnfinishedImportJobs marks the import jobs that are still pending or running as failed
+// with the given message. Jobs run in the process that accepted them, so after a restart
+// nothing is working on them any more.
+func (s *service) FailUnfinishedImportJobs(ctx context.Context, message string) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	return db.Model(&models.ImportJob{}).
+		Where("status IN ?", []string{models.ImportStatusPending, models.ImportStatusRunning}).
+		Updates(map[string]any{"status": models.ImportStatusFailed, "message": message, "finished_at": time.Now()}).Error
+}
@@ -0,0 +1,609 @@
+//go:build integration
+
+// The integration suite runs the migrations and the Service against the database configured by
+// BLUEPRINT_DB_DRIVER and BLUEPRINT_DB_URL, SQLite uses a temporary file when no URL is set.
+// Every test starts from an empty schema, so never point it at a database you want to keep.
+// `make itest` starts throwaway PostgreSQL and MySQL servers and runs it against every dialect.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-playground/internal/database/models"
+	"go-playground/internal/database/query"
+
+	"gorm.io/gorm"
+)
+
+// integrationDB opens the configured database and resets its schema to the latest migration
+func integrationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	if dburl == "" {
+		if dbdriver != "" && dbdriver != "sqlite" {
+			t.Fatalf("BLUEPRINT_DB_URL is required for the %s driver", dbdriver)
+		}
+		dburl = filepath.Join(t.TempDir(), "integration.db")
+		t.Cleanup(func() { dburl = "" })
+	}
+
+	db, err := open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Start from an empty schema whatever an earlier run left behind
+	if _, err := migrator.Down(len(migrator.migrations)); err != nil {
+		t.Fatal(err)
+	}
+	// The SQLite search index is created on start instead of by a migration
+	if err := db.Migrator().DropTable("search_index"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// integrationService sets up a service on a fresh schema the way New does
+func integrationService(t *testing.T) *service {
+	t.Helper()
+	return newIntegrationService(t, integrationDB(t))
+}
+
+func newIntegrationService(t *testing.T, db *gorm.DB) *service {
+	t.Helper()
+
+	if err := seedRoles(db); err != nil {
+		t.Fatal(err)
+	}
+
+	search := newSearchBackend(db.Dialector.Name())
+	if err := search.setup(db); err != nil {
+		t.Logf("Full-text search disabled: %v", err)
+		search = nil
+	}
+
+	if err := registerErrorTranslation(db); err != nil {
+		t.Fatal(err)
+	}
+
+	return &service{db: db, search: search, queryTimeout: defaultQueryTimeout}
+}
+
+func TestIntegrationMigrations(t *testing.T) {
+	db := integrationDB(t)
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reverting has to cope with deleted genres sharing the slug of another genre
+	deleted := models.Genre{Name: "Poetry", Slug: "poetry", Model: gorm.Model{DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}}
+	if err := db.Create(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Genre{Name: "Poems", Slug: "poetry"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// and with deleted users sharing the username of another user
+	deletedUser := models.User{Username: "editor", Model: gorm.Model{DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}}
+	if err := db.Create(&deletedUser).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.User{Username: "editor"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	reverted, err := migrator.Down(len(migrator.migrations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(migrator.migrations) {
+		t.Fatalf("reverted %d migrations, want %d", len(reverted), len(migrator.migrations))
+	}
+	for _, table := range []string{"books", "authors", "genres", "users", "revisions"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("table %s is left after reverting every migration", table)
+		}
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(migrator.migrations))
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) > 0 {
+		t.Fatalf("%d migrations pending after applying all of them", len(pending))
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range status {
+		if migration.AppliedAt == nil {
+			t.Errorf("migration %04d_%s is not applied", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestIntegrationUpgradeFromAutoMigrate(t *testing.T) {
+	db := integrationDB(t)
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Down(len(migrator.migrations)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The models of the last release that created its tables with AutoMigrate
+	type Artist struct {
+		gorm.Model
+		FirstName string
+		LastName  string
+	}
+	type Cover struct {
+		gorm.Model
+		DesignIdeas sql.NullString
+		ImageURL    sql.NullString
+		BookID      uint
+		Artists     []*Artist `gorm:"many2many:artist_covers;"`
+	}
+	type Book struct {
+		gorm.Model
+		Title         string
+		PublishedDate time.Time
+		DigitalOnly   bool `gorm:"default:false"`
+		Pages         uint
+		Description   string
+		ISBN          string
+		Price         float32
+		Cover         Cover
+		AuthorID      uint
+	}
+	type Author struct {
+		gorm.Model
+		FirstName string
+		LastName  string
+		Books     []Book
+	}
+	type User struct {
+		gorm.Model
+		Username     string `gorm:"unique"`
+		Password     string
+		RefreshToken string `gorm:"unique"`
+		ExpriesAt    time.Time
+	}
+	if err := db.AutoMigrate(&Author{}, &Artist{}, &Book{}, &Cover{}, &User{}); err != nil {
+		t.Fatal(err)
+	}
+
+	author := &Author{FirstName: "Edward", LastName: "Gorey"}
+	if err := db.Create(author).Error; err != nil {
+		t.Fatal(err)
+	}
+	book := &Book{Title: "The Doubtful Guest", PublishedDate: time.Date(1957, 1, 1, 0, 0, 0, 0, time.UTC), Pages: 64, ISBN: "9780151003877", AuthorID: author.ID}
+	if err := db.Omit("Cover").Create(book).Error; err != nil {
+		t.Fatal(err)
+	}
+	cover := &Cover{DesignIdeas: sql.NullString{String: "Pen and ink", Valid: true}, BookID: book.ID, Artists: []*Artist{{FirstName: "Edward", LastName: "Gorey"}}}
+	if err := db.Create(cover).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&User{Username: "admin", Password: "hash", RefreshToken: "token", ExpriesAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(migrator.migrations))
+	}
+	if pending, err := migrator.Pending(); err != nil || len(pending) > 0 {
+		t.Fatalf("%d migrations pending after upgrading: %v", len(pending), err)
+	}
+
+	// The data of the old release is served by the current models
+	s := newIntegrationService(t, db)
+	ctx := context.Background()
+
+	upgraded, err := s.GetBook(ctx, book.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgraded.Title != book.Title || upgraded.Author.LastName != "Gorey" || upgraded.Version != 1 {
+		t.Errorf("upgraded book is %q by %q at version %d", upgraded.Title, upgraded.Author.LastName, upgraded.Version)
+	}
+	upgraded.Pages = 72
+	if err := s.Update(ctx, upgraded); err != nil {
+		t.Fatal(err)
+	}
+
+	upgradedCover, err := s.GetCover(ctx, cover.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var artists []*models.Artist
+	if err := s.FindAssociation(ctx, upgradedCover, "Artists", &artists); err != nil {
+		t.Fatal(err)
+	}
+	if upgradedCover.BookID != book.ID || len(artists) != 1 {
+		t.Errorf("upgraded cover belongs to book %d and %d artists", upgradedCover.BookID, len(artists))
+	}
+
+	user, err := s.GetUser(ctx, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, err := s.GetRole(ctx, models.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.RoleID = &role.ID
+	if err := s.Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIntegrationCRUD(t *testing.T) {
+	s := integrationService(t)
+	ctx := context.Background()
+
+	author := &models.Author{FirstName: "Ada", LastName: "Palmer"}
+	if err := s.Create(ctx, author); err != nil {
+		t.Fatal(err)
+	}
+	if author.ID == 0 || author.Version != 1 {
+		t.Fatalf("created author has ID %d and version %d", author.ID, author.Version)
+	}
+
+	var stale models.Author
+	if err := s.Read(ctx, &stale, author.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	author.LastName = "Lovelace"
+	if err := s.Update(ctx, author); err != nil {
+		t.Fatal(err)
+	}
+	if author.Version != 2 {
+		t.Fatalf("updated author has version %d, want 2", author.Version)
+	}
+
+	stale.FirstName = "Augusta"
+	if err := s.Update(ctx, &stale); !errors.Is(err, ErrStale) {
+		t.Fatalf("updating a stale author returned %v, want ErrStale", err)
+	}
+
+	var read models.Author
+	if err := s.Read(ctx, &read, author.ID); err != nil {
+		t.Fatal(err)
+	}
+	if read.FirstName != "Ada" || read.LastName != "Lovelace" {
+		t.Fatalf("read author %s %s, want Ada Lovelace", read.FirstName, read.LastName)
+	}
+
+	if err := s.Delete(ctx, &models.Author{}, author.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Read(ctx, &models.Author{}, author.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("reading a deleted author returned %v, want ErrNotFound", err)
+	}
+
+	var restored models.Author
+	if err := s.Restore(ctx, &restored, author.ID); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version != 3 {
+		t.Fatalf("restored author has version %d, want 3", restored.Version)
+	}
+	if err := s.Read(ctx, &models.Author{}, author.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Slugs are unique among genres that are not deleted
+	genre := &models.Genre{Name: "Poetry", Slug: "poetry"}
+	if err := s.Create(ctx, genre); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Create(ctx, &models.Genre{Name: "Poems", Slug: "poetry"}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("creating a genre with a taken slug returned %v, want ErrConflict", err)
+	}
+	if err := s.Delete(ctx, &models.Genre{}, genre.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Create(ctx, &models.Genre{Name: "Poems", Slug: "poetry"}); err != nil {
+		t.Fatalf("creating a genre with the slug of a deleted one: %v", err)
+	}
+
+	// and usernames among users that are not deleted
+	user := &models.User{Username: "editor", Password: "hash"}
+	if err := s.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Create(ctx, &models.User{Username: "editor", Password: "hash"}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("creating a user with a taken username returned %v, want ErrConflict", err)
+	}
+	if err := s.Delete(ctx, &models.User{}, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetUser(ctx, "editor"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("getting a deleted user returned %v, want ErrNotFound", err)
+	}
+	if err := s.Create(ctx, &models.User{Username: "editor", Password: "hash"}); err != nil {
+		t.Fatalf("creating a user with the username of a deleted one: %v", err)
+	}
+}
+
+func TestIntegrationFindBookByISBN(t *testing.T) {
+	db := integrationDB(t)
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Books stored before 0011_book_isbn13 get the ISBN-13 models.ISBN13 gives them
+	if _, err := migrator.Down(1); err != nil {
+		t.Fatal(err)
+	}
+	isbns := []string{
+		"0-306-40615-2", "978-0-306-40615-7", "080442957x", "0 8044 2957 X", "979-10-90636-07-1",
+		"", "12345", "97803064061570", "030640615Y", "X306406152",
+	}
+	for _, isbn := range isbns {
+		if err := db.Table("books").Create(map[string]any{"title": isbn, "isbn": isbn}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []struct {
+		ISBN   string
+		ISBN13 string `gorm:"column:isbn13"`
+	}
+	if err := db.Table("books").Select("isbn", "isbn13").Order("id").Scan(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if want := models.ISBN13(row.ISBN); row.ISBN13 != want {
+			t.Errorf("migrating ISBN %q stored %q, want %q", row.ISBN, row.ISBN13, want)
+		}
+	}
+
+	// An ISBN-10 finds the book stored with its ISBN-13 and the other way around
+	s := newIntegrationService(t, db)
+	ctx := context.Background()
+
+	author := &models.Author{FirstName: "Peter", LastName: "Watts"}
+	if err := s.Create(ctx, author); err != nil {
+		t.Fatal(err)
+	}
+	book := &models.Book{Title: "Blindsight", PublishedDate: time.Date(2006, 10, 3, 0, 0, 0, 0, time.UTC), ISBN: "0-7653-1218-5", AuthorID: author.ID}
+	if err := s.Create(ctx, book); err != nil {
+		t.Fatal(err)
+	}
+	for _, isbn := range []string{"0765312185", "9780765312181", "978-0-7653-1218-1"} {
+		found, err := s.FindBookByISBN(ctx, isbn)
+		if err != nil {
+			t.Fatalf("looking up %s: %v", isbn, err)
+		}
+		if found.ID != book.ID {
+			t.Errorf("looking up %s found book %d, want %d", isbn, found.ID, book.ID)
+		}
+	}
+
+	// The lookup follows changes of the ISBN
+	book.ISBN = "978-0-306-40615-7"
+	if err := s.Update(ctx, book); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FindBookByISBN(ctx, "0765312185"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("looking up the old ISBN returned %v, want ErrNotFound", err)
+	}
+	if _, err := s.FindBookByISBN(ctx, "not an isbn"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("looking up an invalid ISBN returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestIntegrationCursorList(t *testing.T) {
+	s := integrationService(t)
+	ctx := context.Background()
+
+	root := &models.Genre{Name: "Fiction", Slug: "fiction"}
+	if err := s.Create(ctx, root); err != nil {
+		t.Fatal(err)
+	}
+	// Every other genre has no parent, so paging has to cross NULL sort keys
+	names := []string{"Mystery", "Essays", "Horror", "Biography", "Fantasy", "History", "Romance", "Travel"}
+	for i, name := range names {
+		genre := &models.Genre{Name: name, Slug: strconv.Itoa(i)}
+		if i%2 == 0 {
+			genre.ParentID = &root.ID
+		}
+		if err := s.Create(ctx, genre); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, sort := range []string{"name", "-name", "parent_id", "-parent_id", "parent_id,-name", "-parent_id,name"} {
+		t.Run(sort, func(t *testing.T) {
+			q, err := query.Parse(url.Values{"sort": {sort}, "limit": {"100"}}, GenreFields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var all []models.Genre
+			page, err := s.List(ctx, &all, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total == nil || *page.Total != int64(len(names)+1) {
+				t.Fatalf("first page counts %v genres, want %d", page.Total, len(names)+1)
+			}
+
+			var paged []uint
+			after := ""
+			for {
+				values := url.Values{"sort": {sort}, "limit": {"3"}}
+				if after != "" {
+					values.Set("after", after)
+				}
+				q, err := query.Parse(values, GenreFields)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var genres []models.Genre
+				page, err := s.List(ctx, &genres, q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if after != "" && page.Total != nil {
+					t.Fatal("a page after a cursor is counted")
+				}
+				for _, genre := range genres {
+					paged = append(paged, genre.ID)
+				}
+
+				if page.NextCursor == "" {
+					break
+				}
+				if len(paged) > len(all) {
+					t.Fatalf("cursors returned %d genres of %d", len(paged), len(all))
+				}
+				after = page.NextCursor
+			}
+
+			var want []uint
+			for _, genre := range all {
+				want = append(want, genre.ID)
+			}
+			if !slices.Equal(paged, want) {
+				t.Fatalf("cursor pages returned %v, want %v", paged, want)
+			}
+		})
+	}
+}
+
+func TestIntegrationSearch(t *testing.T) {
+	s := integrationService(t)
+	ctx := context.Background()
+
+	if s.search == nil {
+		t.Skip("full-text search is not available, build SQLite with -tags sqlite_fts5")
+	}
+
+	author := &models.Author{FirstName: "Octavia", LastName: "Quillfeather"}
+	if err := s.Create(ctx, author); err != nil {
+		t.Fatal(err)
+	}
+	book := &models.Book{
+		Title:         "The Lighthouse Keeper",
+		PublishedDate: time.Date(2001, 4, 1, 0, 0, 0, 0, time.UTC),
+		Pages:         320,
+		Description:   "A keeper logs the ships passing a remote island",
+		ISBN:          "9780000000001",
+		Price:         12.5,
+		AuthorID:      author.ID,
+	}
+	if err := s.Create(ctx, book); err != nil {
+		t.Fatal(err)
+	}
+
+	hits := func(q string) []SearchResult {
+		t.Helper()
+		results, err := s.Search(ctx, q, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+	contains := func(results []SearchResult, entityType string, id uint) bool {
+		return slices.ContainsFunc(results, func(hit SearchResult) bool {
+			return hit.EntityType == entityType && hit.EntityID == id
+		})
+	}
+
+	if results := hits("lighthou"); !contains(results, SearchEntityBook, book.ID) {
+		t.Fatalf("searching a title prefix returned %v", results)
+	}
+	if results := hits("remote island"); !contains(results, SearchEntityBook, book.ID) {
+		t.Fatalf("searching the description returned %v", results)
+	}
+	if results := hits("quillfeath"); !contains(results, SearchEntityAuthor, author.ID) {
+		t.Fatalf("searching an author name prefix returned %v", results)
+	}
+
+	// Titles and descriptions are escaped, only the highlights are markup
+	markup := &models.Book{
+		Title:         `<img src=x onerror=alert(1)> Lantern`,
+		PublishedDate: time.Date(2002, 4, 1, 0, 0, 0, 0, time.UTC),
+		Description:   `The lantern <script>alert("snippet")</script> of the keeper`,
+		ISBN:          "9780000000002",
+		AuthorID:      author.ID,
+	}
+	if err := s.Create(ctx, markup); err != nil {
+		t.Fatal(err)
+	}
+	results := hits("lantern")
+	if !contains(results, SearchEntityBook, markup.ID) {
+		t.Fatalf("searching a title with markup returned %v", results)
+	}
+	for _, hit := range results {
+		if hit.EntityID != markup.ID {
+			continue
+		}
+		if !strings.Contains(hit.Title, "&lt;img") || strings.Contains(hit.Title, "<img") {
+			t.Errorf("title %q is not escaped", hit.Title)
+		}
+		if !strings.Contains(hit.Snippet, "&lt;script&gt;") || strings.Contains(hit.Snippet, "<script") {
+			t.Errorf("snippet %q is not escaped", hit.Snippet)
+		}
+		if !strings.Contains(strings.ToLower(hit.Title), "<mark>lantern</mark>") {
+			t.Errorf("title %q does not highlight the match", hit.Title)
+		}
+	}
+
+	if err := s.Delete(ctx, &models.Book{}, book.ID); err != nil {
+		t.Fatal(err)
+	}
+	if results := hits("lighthou"); contains(results, SearchEntityBook, book.ID) {
+		t.Fatalf("a deleted book is still found: %v", results)
+	}
+}
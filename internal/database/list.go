@@ -0,0 +1,261 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go-playground/internal/database/query"
+
+	"gorm.io/gorm"
+)
+
+// Whitelists of the fields each list endpoint can filter and sort on
+var (
+	BookFields = query.Fields{
+		"id":             {Column: "id", Type: query.Integer},
+		"title":          {Column: "title", Type: query.String},
+		"isbn":           {Column: "isbn", Type: query.String},
+		"price":          {Column: "price", Type: query.Number},
+		"pages":          {Column: "pages", Type: query.Integer},
+		"published_date": {Column: "published_date", Type: query.Date},
+		"digital_only":   {Column: "digital_only", Type: query.Bool},
+		"author_id":      {Column: "author_id", Type: query.Integer},
+		"created_at":     {Column: "created_at", Type: query.Date},
+	}
+
+	AuthorFields = query.Fields{
+		"id":         {Column: "id", Type: query.Integer},
+		"first_name": {Column: "first_name", Type: query.String},
+		"last_name":  {Column: "last_name", Type: query.String},
+		"created_at": {Column: "created_at", Type: query.Date},
+	}
+
+	ArtistFields = query.Fields{
+		"id":         {Column: "id", Type: query.Integer},
+		"first_name": {Column: "first_name", Type: query.String},
+		"last_name":  {Column: "last_name", Type: query.String},
+		"created_at": {Column: "created_at", Type: query.Date},
+	}
+
+	CoverFields = query.Fields{
+		"id":         {Column: "id", Type: query.Integer},
+		"book_id":    {Column: "book_id", Type: query.Integer},
+		"created_at": {Column: "created_at", Type: query.Date},
+	}
+
+	UserFields = query.Fields{
+		"id":         {Column: "id", Type: query.Integer},
+		"username":   {Column: "username", Type: query.String},
+		"role_id":    {Column: "role_id", Type: query.Integer, Nullable: true},
+		"disabled":   {Column: "disabled", Type: query.Bool},
+		"created_at": {Column: "created_at", Type: query.Date},
+	}
+
+	GenreFields = query.Fields{
+		"id":        {Column: "id", Type: query.Integer},
+		"name":      {Column: "name", Type: query.String},
+		"slug":      {Column: "slug", Type: query.String},
+		"parent_id": {Column: "parent_id", Type: query.Integer, Nullable: true},
+	}
+
+	AuditFields = query.Fields{
+		"id":          {Column: "id", Type: query.Integer},
+		"actor_id":    {Column: "actor_id", Type: query.Integer},
+		"action":      {Column: "action", Type: query.String},
+		"entity_type": {Column: "entity_type", Type: query.String},
+		"entity_id":   {Column: "entity_id", Type: query.Integer},
+		"request_id":  {Column: "request_id", Type: query.String},
+		"created_at":  {Column: "created_at", Type: query.Date},
+	}
+
+	ImportJobFields = query.Fields{
+		"id":         {Column: "id", Type: query.Integer},
+		"actor_id":   {Column: "actor_id", Type: query.Integer},
+		"entity":     {Column: "entity", Type: query.String},
+		"status":     {Column: "status", Type: query.String},
+		"dry_run":    {Column: "dry_run", Type: query.Bool},
+		"created_at": {Column: "created_at", Type: query.Date},
+	}
+
+	RevisionFields = query.Fields{
+		"id":         {Column: "id", Type: query.Integer},
+		"version":    {Column: "version", Type: query.Integer},
+		"actor_id":   {Column: "actor_id", Type: query.Integer},
+		"created_at": {Column: "created_at", Type: query.Date},
+	}
+)
+
+var operatorSQL = map[query.Operator]string{
+	query.OpEq:   "=",
+	query.OpNe:   "<>",
+	query.OpLt:   "<",
+	query.OpLte:  "<=",
+	query.OpGt:   ">",
+	query.OpGte:  ">=",
+	query.OpLike: "LIKE",
+	query.OpIn:   "IN",
+}
+
+// filterScope translates the filters of a list query into WHERE clauses.
+// Column names come from the whitelists above, never from the client.
+func filterScope(q query.ListQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, filter := range q.Filters {
+			// LIKE is case sensitive on PostgreSQL but not on SQLite and MySQL, lowering both sides behaves the same everywhere
+			if filter.Operator == query.OpLike {
+				db = db.Where(fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", filter.Column), filter.Value)
+				continue
+			}
+			db = db.Where(fmt.Sprintf("%s %s ?", filter.Column, operatorSQL[filter.Operator]), filter.Value)
+		}
+		return db
+	}
+}
+
+// keysetScope continues the list after the cursor row by comparing the sort
+// keys as a tuple, e.g. (price > ?) OR (price = ? AND id > ?).
+func keysetScope(q query.ListQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if q.After == nil {
+			return db
+		}
+
+		sorts := append(append([]query.Sort{}, q.Sort...), query.Sort{Column: "id"})
+		values := append(append([]any{}, q.After.Values...), q.After.ID)
+
+		var clauses []string
+		var args []any
+		for i := range sorts {
+			after, afterArgs := keysetAfter(sorts[i], values[i])
+			if after == "" {
+				continue
+			}
+
+			var parts []string
+			for j := 0; j < i; j++ {
+				equal, equalArgs := keysetEqual(sorts[j], values[j])
+				parts = append(parts, equal)
+				args = append(args, equalArgs...)
+			}
+			parts = append(parts, after)
+			args = append(args, afterArgs...)
+
+			clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+		}
+
+		return db.Where("("+strings.Join(clauses, " OR ")+")", args...)
+	}
+}
+
+// keysetAfter is the condition of the rows sorting after value, empty when none do.
+// NULL sorts after every value, as pageScope orders nullable columns.
+func keysetAfter(sort query.Sort, value any) (string, []any) {
+	switch {
+	case value == nil && sort.Desc:
+		return sort.Column + " IS NOT NULL", nil
+	case value == nil:
+		return "", nil
+	case sort.Desc:
+		return sort.Column + " < ?", []any{value}
+	case sort.Nullable:
+		return "(" + sort.Column + " > ? OR " + sort.Column + " IS NULL)", []any{value}
+	default:
+		return sort.Column + " > ?", []any{value}
+	}
+}
+
+// keysetEqual is the condition of the rows sorting with value
+func keysetEqual(sort query.Sort, value any) (string, []any) {
+	if value == nil {
+		return sort.Column + " IS NULL", nil
+	}
+	return sort.Column + " = ?", []any{value}
+}
+
+// pageScope applies the sort order and pagination of a list query.
+// The primary key is always the final sort key so pages are stable. The dialects
+// disagree on where NULLs sort, nullable columns put them last, or first when descending.
+func pageScope(q query.ListQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, sort := range q.Sort {
+			if sort.Desc {
+				if sort.Nullable {
+					db = db.Order(sort.Column + " IS NULL DESC")
+				}
+				db = db.Order(sort.Column + " DESC")
+			} else {
+				if sort.Nullable {
+					db = db.Order(sort.Column + " IS NULL")
+				}
+				db = db.Order(sort.Column)
+			}
+		}
+		return db.Order("id").Limit(q.Limit).Offset(q.Offset)
+	}
+}
+
+// findPage counts every row matching the filters, then loads the requested page into dest.
+// Pages continued after a cursor are not counted.
+func findPage(db *gorm.DB, dest any, q query.ListQuery) (query.Page, error) {
+	// A new session lets the same base statement be used for both queries
+	db = db.Session(&gorm.Session{})
+
+	var page query.Page
+	if q.After == nil {
+		var total int64
+		if err := db.Model(dest).Scopes(filterScope(q)).Count(&total).Error; err != nil {
+			return page, err
+		}
+		page.Total = &total
+	}
+
+	if err := db.Scopes(filterScope(q), keysetScope(q), pageScope(q)).Find(dest).Error; err != nil {
+		return page, err
+	}
+
+	cursor, err := nextCursor(db, dest, q)
+	if err != nil {
+		return page, err
+	}
+	page.NextCursor = cursor
+
+	return page, nil
+}
+
+// nextCursor builds the cursor pointing after the last row in dest.
+// It is empty when dest holds less than a full page, as there is nothing after it.
+func nextCursor(db *gorm.DB, dest any, q query.ListQuery) (string, error) {
+	rows := reflect.Indirect(reflect.ValueOf(dest))
+	if q.Limit == 0 || rows.Len() < q.Limit {
+		return "", nil
+	}
+	last := reflect.Indirect(rows.Index(rows.Len() - 1))
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(dest); err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	var values []any
+	for _, sort := range q.Sort {
+		field := stmt.Schema.LookUpField(sort.Column)
+		if field == nil {
+			return "", fmt.Errorf("unknown sort column %s", sort.Column)
+		}
+
+		value, _ := field.ValueOf(ctx, last)
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer {
+			value = nil
+			if !v.IsNil() {
+				value = v.Elem().Interface()
+			}
+		}
+		values = append(values, value)
+	}
+
+	id, _ := stmt.Schema.PrioritizedPrimaryField.ValueOf(ctx, last)
+	return query.EncodeCursor(q, values, id.(uint)), nil
+}
@@ -0,0 +1,287 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go-playground/internal/database/migrations"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned change of the database schema
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of the up script
+
+	// Adopt replaces Up on a database created by AutoMigrate before migrations existed,
+	// only the first migration has one
+	Adopt string
+}
+
+// MigrationStatus is a migration together with the time it was applied, if it was
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator applies and reverts the migrations embedded in the binary
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down|adopt)\.sql$`)
+
+// NewMigrator opens the database configured by BLUEPRINT_DB_URL for running migrations
+func NewMigrator() (*Migrator, error) {
+	db, err := open()
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(db)
+}
+
+func newMigrator(db *gorm.DB) (*Migrator, error) {
+	list, err := loadMigrations(migrations.FS, db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	timeType := "datetime"
+	if db.Dialector.Name() == "postgres" {
+		timeType = "timestamptz"
+	}
+	if err := db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version integer PRIMARY KEY, name varchar(255) NOT NULL, checksum varchar(64) NOT NULL, applied_at " + timeType + " NOT NULL)").Error; err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: list}, nil
+}
+
+// loadMigrations reads the up and down scripts of a dialect, ordered by version
+func loadMigrations(fsys fs.FS, dialect string) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s: %w", dialect, err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		matches := migrationFile.FindStringSubmatch(file.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", file.Name())
+		}
+
+		version, _ := strconv.Atoi(matches[1])
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, matches[2])
+		}
+
+		script, err := fs.ReadFile(fsys, path.Join(dialect, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		switch matches[3] {
+		case "up":
+			migration.Up = string(script)
+			sum := sha256.Sum256(script)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		case "down":
+			migration.Down = string(script)
+		case "adopt":
+			migration.Adopt = string(script)
+		}
+	}
+
+	var list []Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down script", migration.Version, migration.Name)
+		}
+		list = append(list, *migration)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	return list, nil
+}
+
+// applied returns the applied migrations by version, after checking that none of
+// them was edited since and that the binary knows every one of them.
+func (m *Migrator) applied() (map[int]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := m.db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	known := map[int]Migration{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	applied := map[int]schemaMigration{}
+	for _, row := range rows {
+		migration, ok := known[row.Version]
+		if !ok {
+			return nil, fmt.Errorf("migration %04d_%s is applied but unknown to this build, the database is newer than the application", row.Version, row.Name)
+		}
+		if migration.Checksum != row.Checksum {
+			return nil, fmt.Errorf("migration %04d_%s was changed after it was applied", row.Version, row.Name)
+		}
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+// Status lists every migration and when it was applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var status []MigrationStatus
+	for _, migration := range m.migrations {
+		entry := MigrationStatus{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			entry.AppliedAt = &appliedAt
+		}
+		status = append(status, entry)
+	}
+	return status, nil
+}
+
+// Pending returns the migrations that still have to be applied, in order
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration, each in its own transaction.
+// MySQL commits schema changes implicitly, so a failed migration can be left half applied there.
+// A database created by AutoMigrate is adopted by running the adopt script of a migration in
+// place of its up script, it is recorded with the checksum of the up script all the same.
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	adopt, err := m.createdByAutoMigrate()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		script := migration.Up
+		if adopt && migration.Adopt != "" {
+			script = migration.Adopt
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(script).Error; err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// createdByAutoMigrate reports whether the database was created by a release that still used
+// AutoMigrate, it has the tables of that release but no migration was ever applied to it.
+func (m *Migrator) createdByAutoMigrate() (bool, error) {
+	var applied int64
+	if err := m.db.Model(&schemaMigration{}).Count(&applied).Error; err != nil {
+		return false, err
+	}
+	return applied == 0 && m.db.Migrator().HasTable("users"), nil
+}
+
+// Down reverts the last steps applied migrations, newest first
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Close closes the connection of the migrator
+func (m *Migrator) Close() error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
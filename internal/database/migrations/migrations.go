@@ -0,0 +1,17 @@
+// Package migrations embeds the versioned SQL migrations of the database schema.
+//
+// Every migration is a pair of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. Versions are applied in ascending order and an
+// applied migration must never be edited, add a new one instead.
+//
+// The first migration also has a <version>_<name>.adopt.sql script. It runs instead
+// of the up script on databases created by AutoMigrate before migrations existed.
+package migrations
+
+import "embed"
+
+// FS holds the migrations, one directory per SQL dialect. Every dialect has the
+// same versions, so a change that is not needed on one of them is an empty migration.
+//
+//go:embed sqlite/*.sql postgres/*.sql mysql/*.sql
+var FS embed.FS
@@ -0,0 +1,22 @@
+-- Replaces the up script on databases created by AutoMigrate before migrations existed,
+-- which already have the authors, artists, books, covers, artist_covers and users tables
+-- of that release. They are brought to the schema of the up script, the rest is created.
+
+ALTER TABLE covers ADD COLUMN image_key longtext, ADD COLUMN image_width bigint, ADD COLUMN image_height bigint, ADD COLUMN dominant_color longtext, ADD COLUMN blur_hash longtext;
+
+CREATE TABLE cover_variants (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), cover_id bigint unsigned, name longtext, format longtext, width bigint, height bigint, url longtext, `key` longtext, INDEX idx_cover_variants_cover_id (cover_id), INDEX idx_cover_variants_deleted_at (deleted_at), CONSTRAINT fk_covers_variants FOREIGN KEY (cover_id) REFERENCES covers (id));
+
+CREATE TABLE genres (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), name longtext, slug varchar(191), parent_id bigint unsigned, INDEX idx_genres_deleted_at (deleted_at), UNIQUE INDEX idx_genres_slug (slug), CONSTRAINT fk_genres_children FOREIGN KEY (parent_id) REFERENCES genres (id));
+
+CREATE TABLE book_genres (genre_id bigint unsigned, book_id bigint unsigned, PRIMARY KEY (genre_id, book_id), CONSTRAINT fk_book_genres_genre FOREIGN KEY (genre_id) REFERENCES genres (id), CONSTRAINT fk_book_genres_book FOREIGN KEY (book_id) REFERENCES books (id));
+
+CREATE TABLE roles (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), name varchar(191), INDEX idx_roles_deleted_at (deleted_at), CONSTRAINT uni_roles_name UNIQUE (name));
+
+CREATE TABLE permissions (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), name varchar(191), INDEX idx_permissions_deleted_at (deleted_at), CONSTRAINT uni_permissions_name UNIQUE (name));
+
+CREATE TABLE role_permissions (role_id bigint unsigned, permission_id bigint unsigned, PRIMARY KEY (role_id, permission_id), CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles (id), CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission_id) REFERENCES permissions (id));
+
+-- Refresh tokens are stored as sessions
+ALTER TABLE users DROP COLUMN refresh_token, DROP COLUMN expries_at, ADD COLUMN role_id bigint unsigned, ADD COLUMN disabled boolean DEFAULT false, ADD COLUMN must_change_password boolean DEFAULT false, ADD CONSTRAINT fk_users_role FOREIGN KEY (role_id) REFERENCES roles (id);
+
+CREATE TABLE sessions (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), user_id bigint unsigned, token_hash varchar(64), family_id varchar(191), user_agent longtext, ip longtext, last_used_at datetime(3), expires_at datetime(3), revoked_at datetime(3), INDEX idx_sessions_deleted_at (deleted_at), INDEX idx_sessions_family_id (family_id), UNIQUE INDEX idx_sessions_token_hash (token_hash), INDEX idx_sessions_user_id (user_id), CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id));
@@ -0,0 +1,27 @@
+-- Columns that are indexed use varchar(191), the longest that fits an index with utf8mb4.
+
+CREATE TABLE authors (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), first_name longtext, last_name longtext, INDEX idx_authors_deleted_at (deleted_at));
+
+CREATE TABLE artists (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), first_name longtext, last_name longtext, INDEX idx_artists_deleted_at (deleted_at));
+
+CREATE TABLE books (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), title longtext, published_date datetime(3), digital_only boolean DEFAULT false, pages bigint unsigned, description longtext, isbn longtext, price float, author_id bigint unsigned, INDEX idx_books_deleted_at (deleted_at), CONSTRAINT fk_authors_books FOREIGN KEY (author_id) REFERENCES authors (id));
+
+CREATE TABLE covers (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), design_ideas longtext, image_url longtext, image_key longtext, image_width bigint, image_height bigint, dominant_color longtext, blur_hash longtext, book_id bigint unsigned, INDEX idx_covers_deleted_at (deleted_at), CONSTRAINT fk_books_cover FOREIGN KEY (book_id) REFERENCES books (id));
+
+CREATE TABLE artist_covers (cover_id bigint unsigned, artist_id bigint unsigned, PRIMARY KEY (cover_id, artist_id), CONSTRAINT fk_artist_covers_cover FOREIGN KEY (cover_id) REFERENCES covers (id), CONSTRAINT fk_artist_covers_artist FOREIGN KEY (artist_id) REFERENCES artists (id));
+
+CREATE TABLE cover_variants (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), cover_id bigint unsigned, name longtext, format longtext, width bigint, height bigint, url longtext, `key` longtext, INDEX idx_cover_variants_cover_id (cover_id), INDEX idx_cover_variants_deleted_at (deleted_at), CONSTRAINT fk_covers_variants FOREIGN KEY (cover_id) REFERENCES covers (id));
+
+CREATE TABLE genres (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), name longtext, slug varchar(191), parent_id bigint unsigned, INDEX idx_genres_deleted_at (deleted_at), UNIQUE INDEX idx_genres_slug (slug), CONSTRAINT fk_genres_children FOREIGN KEY (parent_id) REFERENCES genres (id));
+
+CREATE TABLE book_genres (genre_id bigint unsigned, book_id bigint unsigned, PRIMARY KEY (genre_id, book_id), CONSTRAINT fk_book_genres_genre FOREIGN KEY (genre_id) REFERENCES genres (id), CONSTRAINT fk_book_genres_book FOREIGN KEY (book_id) REFERENCES books (id));
+
+CREATE TABLE roles (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), name varchar(191), INDEX idx_roles_deleted_at (deleted_at), CONSTRAINT uni_roles_name UNIQUE (name));
+
+CREATE TABLE permissions (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), name varchar(191), INDEX idx_permissions_deleted_at (deleted_at), CONSTRAINT uni_permissions_name UNIQUE (name));
+
+CREATE TABLE role_permissions (role_id bigint unsigned, permission_id bigint unsigned, PRIMARY KEY (role_id, permission_id), CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles (id), CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission_id) REFERENCES permissions (id));
+
+CREATE TABLE users (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), username varchar(191), password longtext, role_id bigint unsigned, disabled boolean DEFAULT false, must_change_password boolean DEFAULT false, INDEX idx_users_deleted_at (deleted_at), CONSTRAINT fk_users_role FOREIGN KEY (role_id) REFERENCES roles (id), CONSTRAINT uni_users_username UNIQUE (username));
+
+CREATE TABLE sessions (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), deleted_at datetime(3), user_id bigint unsigned, token_hash varchar(64), family_id varchar(191), user_agent longtext, ip longtext, last_used_at datetime(3), expires_at datetime(3), revoked_at datetime(3), INDEX idx_sessions_deleted_at (deleted_at), INDEX idx_sessions_family_id (family_id), UNIQUE INDEX idx_sessions_token_hash (token_hash), INDEX idx_sessions_user_id (user_id), CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id));
@@ -0,0 +1,3 @@
+-- Nothing to drop, MySQL databases were never created with the refresh token columns.
+-- The migration exists so versions line up across dialects.
+SELECT 1;
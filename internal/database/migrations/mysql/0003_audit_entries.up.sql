@@ -0,0 +1 @@
+CREATE TABLE audit_entries (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), actor_id bigint unsigned, action varchar(32), entity_type varchar(64), entity_id bigint unsigned, changes longtext, ip varchar(64), request_id varchar(64), INDEX idx_audit_entries_created_at (created_at), INDEX idx_audit_entries_actor_id (actor_id), INDEX idx_audit_entries_entity (entity_type, entity_id));
@@ -0,0 +1 @@
+CREATE TABLE revisions (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), entity_type varchar(64), entity_id bigint unsigned, version bigint unsigned, actor_id bigint unsigned, data longtext, UNIQUE INDEX idx_revisions_entity_version (entity_type, entity_id, version));
@@ -0,0 +1 @@
+CREATE TABLE import_jobs (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, created_at datetime(3), updated_at datetime(3), finished_at datetime(3), actor_id bigint unsigned, entity varchar(32), format varchar(16), file_name longtext, dry_run boolean, status varchar(16), message longtext, total bigint, processed bigint, created bigint, updated bigint, unchanged bigint, failed bigint, errors longtext, INDEX idx_import_jobs_created_at (created_at), INDEX idx_import_jobs_actor_id (actor_id), INDEX idx_import_jobs_status (status));
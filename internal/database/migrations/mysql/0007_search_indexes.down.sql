@@ -0,0 +1,3 @@
+ALTER TABLE books DROP INDEX idx_books_search;
+ALTER TABLE authors DROP INDEX idx_authors_search;
+ALTER TABLE artists DROP INDEX idx_artists_search;
@@ -0,0 +1,13 @@
+-- Databases that ran an older build got these indexes at startup, MySQL has no CREATE INDEX IF NOT EXISTS
+SET @statement = IF((SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'books' AND index_name = 'idx_books_search') = 0, 'ALTER TABLE books ADD FULLTEXT INDEX idx_books_search (title, description, isbn)', 'DO 0');
+PREPARE statement FROM @statement;
+EXECUTE statement;
+DEALLOCATE PREPARE statement;
+SET @statement = IF((SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'authors' AND index_name = 'idx_authors_search') = 0, 'ALTER TABLE authors ADD FULLTEXT INDEX idx_authors_search (first_name, last_name)', 'DO 0');
+PREPARE statement FROM @statement;
+EXECUTE statement;
+DEALLOCATE PREPARE statement;
+SET @statement = IF((SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'artists' AND index_name = 'idx_artists_search') = 0, 'ALTER TABLE artists ADD FULLTEXT INDEX idx_artists_search (first_name, last_name)', 'DO 0');
+PREPARE statement FROM @statement;
+EXECUTE statement;
+DEALLOCATE PREPARE statement;
@@ -0,0 +1,2 @@
+ALTER TABLE audit_entries MODIFY changes longtext;
+ALTER TABLE revisions MODIFY data longtext;
@@ -0,0 +1,2 @@
+ALTER TABLE audit_entries MODIFY changes json;
+ALTER TABLE revisions MODIFY data json;
@@ -0,0 +1,8 @@
+-- Deleted genres sharing a slug with another genre get their ID appended, the unique index covers them again
+UPDATE genres JOIN (SELECT slug FROM genres GROUP BY slug HAVING COUNT(*) > 1) AS duplicates ON genres.slug = duplicates.slug
+SET genres.slug = CONCAT(genres.slug, '-', genres.id)
+WHERE genres.deleted_at IS NOT NULL;
+ALTER TABLE genres DROP INDEX idx_genres_slug_lookup;
+ALTER TABLE genres DROP INDEX idx_genres_slug;
+ALTER TABLE genres DROP COLUMN active_slug;
+ALTER TABLE genres ADD UNIQUE INDEX idx_genres_slug (slug);
@@ -0,0 +1,6 @@
+-- Slugs of soft deleted genres can be taken again. MySQL has no partial indexes, the unique
+-- index is on a generated column holding the slug of live genres and NULL for deleted ones.
+ALTER TABLE genres DROP INDEX idx_genres_slug;
+ALTER TABLE genres ADD COLUMN active_slug varchar(191) AS (IF(deleted_at IS NULL, slug, NULL)) STORED;
+ALTER TABLE genres ADD UNIQUE INDEX idx_genres_slug (active_slug);
+ALTER TABLE genres ADD INDEX idx_genres_slug_lookup (slug);
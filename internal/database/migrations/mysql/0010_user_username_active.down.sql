@@ -0,0 +1,8 @@
+-- Deleted users sharing a username with another user get their ID appended, the constraint covers them again
+UPDATE users JOIN (SELECT username FROM users GROUP BY username HAVING COUNT(*) > 1) AS duplicates ON users.username = duplicates.username
+SET users.username = CONCAT(users.username, '-', users.id)
+WHERE users.deleted_at IS NOT NULL;
+ALTER TABLE users DROP INDEX idx_users_username_lookup;
+ALTER TABLE users DROP INDEX idx_users_username;
+ALTER TABLE users DROP COLUMN active_username;
+ALTER TABLE users ADD CONSTRAINT uni_users_username UNIQUE (username);
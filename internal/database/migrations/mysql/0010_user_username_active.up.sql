@@ -0,0 +1,6 @@
+-- Usernames of soft deleted users can be taken again. MySQL has no partial indexes, the unique
+-- index is on a generated column holding the username of live users and NULL for deleted ones.
+ALTER TABLE users DROP INDEX uni_users_username;
+ALTER TABLE users ADD COLUMN active_username varchar(191) AS (IF(deleted_at IS NULL, username, NULL)) STORED;
+ALTER TABLE users ADD UNIQUE INDEX idx_users_username (active_username);
+ALTER TABLE users ADD INDEX idx_users_username_lookup (username);
@@ -0,0 +1,2 @@
+ALTER TABLE books DROP INDEX idx_books_isbn13;
+ALTER TABLE books DROP COLUMN isbn13;
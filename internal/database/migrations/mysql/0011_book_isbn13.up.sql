@@ -0,0 +1,15 @@
+-- Books are looked up by their ISBN as ISBN-13 without hyphens, so an ISBN-10 finds the book
+-- stored with its ISBN-13. The conversion is the one of models.ISBN13.
+ALTER TABLE books ADD COLUMN isbn13 varchar(13);
+
+UPDATE books JOIN (SELECT id, UPPER(REPLACE(REPLACE(COALESCE(isbn, ''), '-', ''), ' ', '')) AS isbn FROM books) AS normalized ON books.id = normalized.id
+SET books.isbn13 = CASE
+	WHEN normalized.isbn REGEXP '^[0-9]{13}$' THEN normalized.isbn
+	WHEN normalized.isbn REGEXP '^[0-9]{9}[0-9X]$' THEN CONCAT('978', SUBSTRING(normalized.isbn, 1, 9), (10 - (38
+		+ 3 * SUBSTRING(normalized.isbn, 1, 1) + SUBSTRING(normalized.isbn, 2, 1) + 3 * SUBSTRING(normalized.isbn, 3, 1)
+		+ SUBSTRING(normalized.isbn, 4, 1) + 3 * SUBSTRING(normalized.isbn, 5, 1) + SUBSTRING(normalized.isbn, 6, 1)
+		+ 3 * SUBSTRING(normalized.isbn, 7, 1) + SUBSTRING(normalized.isbn, 8, 1) + 3 * SUBSTRING(normalized.isbn, 9, 1)) % 10) % 10)
+	ELSE ''
+END;
+
+ALTER TABLE books ADD INDEX idx_books_isbn13 (isbn13);
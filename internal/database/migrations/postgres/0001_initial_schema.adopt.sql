@@ -0,0 +1,32 @@
+-- Replaces the up script on databases created by AutoMigrate before migrations existed,
+-- which already have the authors, artists, books, covers, artist_covers and users tables
+-- of that release. They are brought to the schema of the up script, the rest is created.
+
+ALTER TABLE covers ADD COLUMN image_key text, ADD COLUMN image_width bigint, ADD COLUMN image_height bigint, ADD COLUMN dominant_color text, ADD COLUMN blur_hash text;
+
+CREATE TABLE cover_variants (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, cover_id bigint, name text, format text, width bigint, height bigint, url text, key text, CONSTRAINT fk_covers_variants FOREIGN KEY (cover_id) REFERENCES covers (id));
+CREATE INDEX idx_cover_variants_cover_id ON cover_variants (cover_id);
+CREATE INDEX idx_cover_variants_deleted_at ON cover_variants (deleted_at);
+
+CREATE TABLE genres (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, name text, slug text, parent_id bigint, CONSTRAINT fk_genres_children FOREIGN KEY (parent_id) REFERENCES genres (id));
+CREATE INDEX idx_genres_deleted_at ON genres (deleted_at);
+CREATE UNIQUE INDEX idx_genres_slug ON genres (slug);
+
+CREATE TABLE book_genres (genre_id bigint, book_id bigint, PRIMARY KEY (genre_id, book_id), CONSTRAINT fk_book_genres_genre FOREIGN KEY (genre_id) REFERENCES genres (id), CONSTRAINT fk_book_genres_book FOREIGN KEY (book_id) REFERENCES books (id));
+
+CREATE TABLE roles (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, name text, CONSTRAINT uni_roles_name UNIQUE (name));
+CREATE INDEX idx_roles_deleted_at ON roles (deleted_at);
+
+CREATE TABLE permissions (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, name text, CONSTRAINT uni_permissions_name UNIQUE (name));
+CREATE INDEX idx_permissions_deleted_at ON permissions (deleted_at);
+
+CREATE TABLE role_permissions (role_id bigint, permission_id bigint, PRIMARY KEY (role_id, permission_id), CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles (id), CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission_id) REFERENCES permissions (id));
+
+-- Refresh tokens are stored as sessions
+ALTER TABLE users DROP COLUMN refresh_token, DROP COLUMN expries_at, ADD COLUMN role_id bigint, ADD COLUMN disabled boolean DEFAULT false, ADD COLUMN must_change_password boolean DEFAULT false, ADD CONSTRAINT fk_users_role FOREIGN KEY (role_id) REFERENCES roles (id);
+
+CREATE TABLE sessions (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, user_id bigint, token_hash text, family_id text, user_agent text, ip text, last_used_at timestamptz, expires_at timestamptz, revoked_at timestamptz, CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id));
+CREATE INDEX idx_sessions_deleted_at ON sessions (deleted_at);
+CREATE INDEX idx_sessions_family_id ON sessions (family_id);
+CREATE UNIQUE INDEX idx_sessions_token_hash ON sessions (token_hash);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
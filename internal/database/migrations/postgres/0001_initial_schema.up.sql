@@ -0,0 +1,40 @@
+CREATE TABLE authors (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, first_name text, last_name text);
+CREATE INDEX idx_authors_deleted_at ON authors (deleted_at);
+
+CREATE TABLE artists (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, first_name text, last_name text);
+CREATE INDEX idx_artists_deleted_at ON artists (deleted_at);
+
+CREATE TABLE books (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, title text, published_date timestamptz, digital_only boolean DEFAULT false, pages bigint, description text, isbn text, price real, author_id bigint, CONSTRAINT fk_authors_books FOREIGN KEY (author_id) REFERENCES authors (id));
+CREATE INDEX idx_books_deleted_at ON books (deleted_at);
+
+CREATE TABLE covers (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, design_ideas text, image_url text, image_key text, image_width bigint, image_height bigint, dominant_color text, blur_hash text, book_id bigint, CONSTRAINT fk_books_cover FOREIGN KEY (book_id) REFERENCES books (id));
+CREATE INDEX idx_covers_deleted_at ON covers (deleted_at);
+
+CREATE TABLE artist_covers (cover_id bigint, artist_id bigint, PRIMARY KEY (cover_id, artist_id), CONSTRAINT fk_artist_covers_cover FOREIGN KEY (cover_id) REFERENCES covers (id), CONSTRAINT fk_artist_covers_artist FOREIGN KEY (artist_id) REFERENCES artists (id));
+
+CREATE TABLE cover_variants (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, cover_id bigint, name text, format text, width bigint, height bigint, url text, key text, CONSTRAINT fk_covers_variants FOREIGN KEY (cover_id) REFERENCES covers (id));
+CREATE INDEX idx_cover_variants_cover_id ON cover_variants (cover_id);
+CREATE INDEX idx_cover_variants_deleted_at ON cover_variants (deleted_at);
+
+CREATE TABLE genres (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, name text, slug text, parent_id bigint, CONSTRAINT fk_genres_children FOREIGN KEY (parent_id) REFERENCES genres (id));
+CREATE INDEX idx_genres_deleted_at ON genres (deleted_at);
+CREATE UNIQUE INDEX idx_genres_slug ON genres (slug);
+
+CREATE TABLE book_genres (genre_id bigint, book_id bigint, PRIMARY KEY (genre_id, book_id), CONSTRAINT fk_book_genres_genre FOREIGN KEY (genre_id) REFERENCES genres (id), CONSTRAINT fk_book_genres_book FOREIGN KEY (book_id) REFERENCES books (id));
+
+CREATE TABLE roles (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, name text, CONSTRAINT uni_roles_name UNIQUE (name));
+CREATE INDEX idx_roles_deleted_at ON roles (deleted_at);
+
+CREATE TABLE permissions (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, name text, CONSTRAINT uni_permissions_name UNIQUE (name));
+CREATE INDEX idx_permissions_deleted_at ON permissions (deleted_at);
+
+CREATE TABLE role_permissions (role_id bigint, permission_id bigint, PRIMARY KEY (role_id, permission_id), CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles (id), CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission_id) REFERENCES permissions (id));
+
+CREATE TABLE users (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, username text, password text, role_id bigint, disabled boolean DEFAULT false, must_change_password boolean DEFAULT false, CONSTRAINT fk_users_role FOREIGN KEY (role_id) REFERENCES roles (id), CONSTRAINT uni_users_username UNIQUE (username));
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE sessions (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, deleted_at timestamptz, user_id bigint, token_hash text, family_id text, user_agent text, ip text, last_used_at timestamptz, expires_at timestamptz, revoked_at timestamptz, CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id));
+CREATE INDEX idx_sessions_deleted_at ON sessions (deleted_at);
+CREATE INDEX idx_sessions_family_id ON sessions (family_id);
+CREATE UNIQUE INDEX idx_sessions_token_hash ON sessions (token_hash);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
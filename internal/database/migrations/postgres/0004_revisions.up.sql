@@ -0,0 +1,2 @@
+CREATE TABLE revisions (id bigserial PRIMARY KEY, created_at timestamptz, entity_type text, entity_id bigint, version bigint, actor_id bigint, data text);
+CREATE UNIQUE INDEX idx_revisions_entity_version ON revisions (entity_type, entity_id, version);
@@ -0,0 +1,6 @@
+ALTER TABLE books ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE authors ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE artists ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE covers ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE genres ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN version bigint NOT NULL DEFAULT 1;
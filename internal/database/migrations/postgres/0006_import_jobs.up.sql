@@ -0,0 +1,4 @@
+CREATE TABLE import_jobs (id bigserial PRIMARY KEY, created_at timestamptz, updated_at timestamptz, finished_at timestamptz, actor_id bigint, entity text, format text, file_name text, dry_run boolean, status text, message text, total bigint, processed bigint, created bigint, updated bigint, unchanged bigint, failed bigint, errors text);
+CREATE INDEX idx_import_jobs_created_at ON import_jobs (created_at);
+CREATE INDEX idx_import_jobs_actor_id ON import_jobs (actor_id);
+CREATE INDEX idx_import_jobs_status ON import_jobs (status);
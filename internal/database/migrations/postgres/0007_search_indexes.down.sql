@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_books_search;
+DROP INDEX IF EXISTS idx_authors_search;
+DROP INDEX IF EXISTS idx_artists_search;
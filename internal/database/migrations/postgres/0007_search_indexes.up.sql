@@ -0,0 +1,4 @@
+-- The expressions must stay the same as the documents of search_postgres.go for the indexes to be used
+CREATE INDEX IF NOT EXISTS idx_books_search ON books USING gin (to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(description, '') || ' ' || coalesce(isbn, '')));
+CREATE INDEX IF NOT EXISTS idx_authors_search ON authors USING gin (to_tsvector('simple', coalesce(first_name, '') || ' ' || coalesce(last_name, '')));
+CREATE INDEX IF NOT EXISTS idx_artists_search ON artists USING gin (to_tsvector('simple', coalesce(first_name, '') || ' ' || coalesce(last_name, '')));
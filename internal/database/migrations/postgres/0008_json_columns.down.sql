@@ -0,0 +1,2 @@
+ALTER TABLE audit_entries ALTER COLUMN changes TYPE text;
+ALTER TABLE revisions ALTER COLUMN data TYPE text;
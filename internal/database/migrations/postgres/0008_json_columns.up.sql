@@ -0,0 +1,2 @@
+ALTER TABLE audit_entries ALTER COLUMN changes TYPE jsonb USING changes::jsonb;
+ALTER TABLE revisions ALTER COLUMN data TYPE jsonb USING data::jsonb;
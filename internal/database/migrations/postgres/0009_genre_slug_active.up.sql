@@ -0,0 +1,3 @@
+-- Slugs of soft deleted genres can be taken again
+DROP INDEX IF EXISTS idx_genres_slug;
+CREATE UNIQUE INDEX idx_genres_slug ON genres (slug) WHERE deleted_at IS NULL;
@@ -0,0 +1,5 @@
+-- Deleted users sharing a username with another user get their ID appended, the constraint covers them again
+UPDATE users SET username = username || '-' || id
+WHERE deleted_at IS NOT NULL AND username IN (SELECT username FROM users GROUP BY username HAVING COUNT(*) > 1);
+DROP INDEX IF EXISTS idx_users_username;
+ALTER TABLE users ADD CONSTRAINT uni_users_username UNIQUE (username);
@@ -0,0 +1,3 @@
+-- Usernames of soft deleted users can be taken again
+ALTER TABLE users DROP CONSTRAINT uni_users_username;
+CREATE UNIQUE INDEX idx_users_username ON users (username) WHERE deleted_at IS NULL;
@@ -0,0 +1,37 @@
+-- Replaces the up script on databases created by AutoMigrate before migrations existed,
+-- which already have the authors, artists, books, covers, artist_covers and users tables
+-- of that release. They are brought to the schema of the up script, the rest is created.
+
+ALTER TABLE `covers` ADD `image_key` text;
+ALTER TABLE `covers` ADD `image_width` integer;
+ALTER TABLE `covers` ADD `image_height` integer;
+ALTER TABLE `covers` ADD `dominant_color` text;
+ALTER TABLE `covers` ADD `blur_hash` text;
+
+CREATE TABLE IF NOT EXISTS `cover_variants` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`cover_id` integer,`name` text,`format` text,`width` integer,`height` integer,`url` text,`key` text,CONSTRAINT `fk_covers_variants` FOREIGN KEY (`cover_id`) REFERENCES `covers`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_cover_variants_cover_id` ON `cover_variants`(`cover_id`);
+CREATE INDEX IF NOT EXISTS `idx_cover_variants_deleted_at` ON `cover_variants`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `genres` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`name` text,`slug` text,`parent_id` integer,CONSTRAINT `fk_genres_children` FOREIGN KEY (`parent_id`) REFERENCES `genres`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_genres_deleted_at` ON `genres`(`deleted_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_genres_slug` ON `genres`(`slug`);
+
+CREATE TABLE IF NOT EXISTS `book_genres` (`genre_id` integer,`book_id` integer,PRIMARY KEY (`genre_id`,`book_id`),CONSTRAINT `fk_book_genres_genre` FOREIGN KEY (`genre_id`) REFERENCES `genres`(`id`),CONSTRAINT `fk_book_genres_book` FOREIGN KEY (`book_id`) REFERENCES `books`(`id`));
+
+CREATE TABLE IF NOT EXISTS `roles` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`name` text,CONSTRAINT `uni_roles_name` UNIQUE (`name`));
+CREATE INDEX IF NOT EXISTS `idx_roles_deleted_at` ON `roles`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `permissions` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`name` text,CONSTRAINT `uni_permissions_name` UNIQUE (`name`));
+CREATE INDEX IF NOT EXISTS `idx_permissions_deleted_at` ON `permissions`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `role_permissions` (`role_id` integer,`permission_id` integer,PRIMARY KEY (`role_id`,`permission_id`),CONSTRAINT `fk_role_permissions_role` FOREIGN KEY (`role_id`) REFERENCES `roles`(`id`),CONSTRAINT `fk_role_permissions_permission` FOREIGN KEY (`permission_id`) REFERENCES `permissions`(`id`));
+
+ALTER TABLE `users` ADD `role_id` integer REFERENCES `roles`(`id`);
+ALTER TABLE `users` ADD `disabled` numeric DEFAULT false;
+ALTER TABLE `users` ADD `must_change_password` numeric DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS `sessions` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`user_id` integer,`token_hash` text,`family_id` text,`user_agent` text,`ip` text,`last_used_at` datetime,`expires_at` datetime,`revoked_at` datetime,CONSTRAINT `fk_sessions_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_sessions_deleted_at` ON `sessions`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_sessions_family_id` ON `sessions`(`family_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_sessions_token_hash` ON `sessions`(`token_hash`);
+CREATE INDEX IF NOT EXISTS `idx_sessions_user_id` ON `sessions`(`user_id`);
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS `sessions`;
+DROP TABLE IF EXISTS `users`;
+DROP TABLE IF EXISTS `role_permissions`;
+DROP TABLE IF EXISTS `permissions`;
+DROP TABLE IF EXISTS `roles`;
+DROP TABLE IF EXISTS `book_genres`;
+DROP TABLE IF EXISTS `genres`;
+DROP TABLE IF EXISTS `cover_variants`;
+DROP TABLE IF EXISTS `artist_covers`;
+DROP TABLE IF EXISTS `covers`;
+DROP TABLE IF EXISTS `books`;
+DROP TABLE IF EXISTS `artists`;
+DROP TABLE IF EXISTS `authors`;
@@ -0,0 +1,45 @@
+-- Schema as last created by AutoMigrate. Every statement is idempotent so databases
+-- created by AutoMigrate are adopted as they are, as long as they were started with
+-- the last release that still used it.
+
+CREATE TABLE IF NOT EXISTS `authors` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`first_name` text,`last_name` text);
+CREATE INDEX IF NOT EXISTS `idx_authors_deleted_at` ON `authors`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `artists` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`first_name` text,`last_name` text);
+CREATE INDEX IF NOT EXISTS `idx_artists_deleted_at` ON `artists`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `books` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`title` text,`published_date` datetime,`digital_only` numeric DEFAULT false,`pages` integer,`description` text,`isbn` text,`price` real,`author_id` integer,CONSTRAINT `fk_authors_books` FOREIGN KEY (`author_id`) REFERENCES `authors`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_books_deleted_at` ON `books`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `covers` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`design_ideas` text,`image_url` text,`image_key` text,`image_width` integer,`image_height` integer,`dominant_color` text,`blur_hash` text,`book_id` integer,CONSTRAINT `fk_books_cover` FOREIGN KEY (`book_id`) REFERENCES `books`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_covers_deleted_at` ON `covers`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `artist_covers` (`cover_id` integer,`artist_id` integer,PRIMARY KEY (`cover_id`,`artist_id`),CONSTRAINT `fk_artist_covers_cover` FOREIGN KEY (`cover_id`) REFERENCES `covers`(`id`),CONSTRAINT `fk_artist_covers_artist` FOREIGN KEY (`artist_id`) REFERENCES `artists`(`id`));
+
+CREATE TABLE IF NOT EXISTS `cover_variants` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`cover_id` integer,`name` text,`format` text,`width` integer,`height` integer,`url` text,`key` text,CONSTRAINT `fk_covers_variants` FOREIGN KEY (`cover_id`) REFERENCES `covers`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_cover_variants_cover_id` ON `cover_variants`(`cover_id`);
+CREATE INDEX IF NOT EXISTS `idx_cover_variants_deleted_at` ON `cover_variants`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `genres` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`name` text,`slug` text,`parent_id` integer,CONSTRAINT `fk_genres_children` FOREIGN KEY (`parent_id`) REFERENCES `genres`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_genres_deleted_at` ON `genres`(`deleted_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_genres_slug` ON `genres`(`slug`);
+
+CREATE TABLE IF NOT EXISTS `book_genres` (`genre_id` integer,`book_id` integer,PRIMARY KEY (`genre_id`,`book_id`),CONSTRAINT `fk_book_genres_genre` FOREIGN KEY (`genre_id`) REFERENCES `genres`(`id`),CONSTRAINT `fk_book_genres_book` FOREIGN KEY (`book_id`) REFERENCES `books`(`id`));
+
+CREATE TABLE IF NOT EXISTS `roles` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`name` text,CONSTRAINT `uni_roles_name` UNIQUE (`name`));
+CREATE INDEX IF NOT EXISTS `idx_roles_deleted_at` ON `roles`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `permissions` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`name` text,CONSTRAINT `uni_permissions_name` UNIQUE (`name`));
+CREATE INDEX IF NOT EXISTS `idx_permissions_deleted_at` ON `permissions`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `role_permissions` (`role_id` integer,`permission_id` integer,PRIMARY KEY (`role_id`,`permission_id`),CONSTRAINT `fk_role_permissions_role` FOREIGN KEY (`role_id`) REFERENCES `roles`(`id`),CONSTRAINT `fk_role_permissions_permission` FOREIGN KEY (`permission_id`) REFERENCES `permissions`(`id`));
+
+-- refresh_token and expries_at are no longer used since sessions were introduced
+CREATE TABLE IF NOT EXISTS `users` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`username` text,`password` text,`refresh_token` text,`expries_at` datetime,`role_id` integer,`disabled` numeric DEFAULT false,`must_change_password` numeric DEFAULT false,CONSTRAINT `fk_users_role` FOREIGN KEY (`role_id`) REFERENCES `roles`(`id`),CONSTRAINT `uni_users_username` UNIQUE (`username`),CONSTRAINT `uni_users_refresh_token` UNIQUE (`refresh_token`));
+CREATE INDEX IF NOT EXISTS `idx_users_deleted_at` ON `users`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `sessions` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`user_id` integer,`token_hash` text,`family_id` text,`user_agent` text,`ip` text,`last_used_at` datetime,`expires_at` datetime,`revoked_at` datetime,CONSTRAINT `fk_sessions_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`));
+CREATE INDEX IF NOT EXISTS `idx_sessions_deleted_at` ON `sessions`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_sessions_family_id` ON `sessions`(`family_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_sessions_token_hash` ON `sessions`(`token_hash`);
+CREATE INDEX IF NOT EXISTS `idx_sessions_user_id` ON `sessions`(`user_id`);
@@ -0,0 +1,8 @@
+CREATE TABLE `users_old` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`username` text,`password` text,`refresh_token` text,`expries_at` datetime,`role_id` integer,`disabled` numeric DEFAULT false,`must_change_password` numeric DEFAULT false,CONSTRAINT `fk_users_role` FOREIGN KEY (`role_id`) REFERENCES `roles`(`id`),CONSTRAINT `uni_users_username` UNIQUE (`username`),CONSTRAINT `uni_users_refresh_token` UNIQUE (`refresh_token`));
+
+INSERT INTO `users_old` (`id`, `created_at`, `updated_at`, `deleted_at`, `username`, `password`, `role_id`, `disabled`, `must_change_password`)
+SELECT `id`, `created_at`, `updated_at`, `deleted_at`, `username`, `password`, `role_id`, `disabled`, `must_change_password` FROM `users`;
+
+DROP TABLE `users`;
+ALTER TABLE `users_old` RENAME TO `users`;
+CREATE INDEX `idx_users_deleted_at` ON `users`(`deleted_at`);
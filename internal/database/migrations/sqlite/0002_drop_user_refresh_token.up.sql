@@ -0,0 +1,11 @@
+-- SQLite cannot drop a column with a UNIQUE constraint, so the table is rebuilt without
+-- the columns left over from storing a single refresh token per user.
+
+CREATE TABLE `users_new` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`username` text,`password` text,`role_id` integer,`disabled` numeric DEFAULT false,`must_change_password` numeric DEFAULT false,CONSTRAINT `fk_users_role` FOREIGN KEY (`role_id`) REFERENCES `roles`(`id`),CONSTRAINT `uni_users_username` UNIQUE (`username`));
+
+INSERT INTO `users_new` (`id`, `created_at`, `updated_at`, `deleted_at`, `username`, `password`, `role_id`, `disabled`, `must_change_password`)
+SELECT `id`, `created_at`, `updated_at`, `deleted_at`, `username`, `password`, `role_id`, `disabled`, `must_change_password` FROM `users`;
+
+DROP TABLE `users`;
+ALTER TABLE `users_new` RENAME TO `users`;
+CREATE INDEX `idx_users_deleted_at` ON `users`(`deleted_at`);
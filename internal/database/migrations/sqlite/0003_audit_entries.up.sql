@@ -0,0 +1,4 @@
+CREATE TABLE `audit_entries` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`actor_id` integer,`action` text,`entity_type` text,`entity_id` integer,`changes` text,`ip` text,`request_id` text);
+CREATE INDEX `idx_audit_entries_created_at` ON `audit_entries`(`created_at`);
+CREATE INDEX `idx_audit_entries_actor_id` ON `audit_entries`(`actor_id`);
+CREATE INDEX `idx_audit_entries_entity` ON `audit_entries`(`entity_type`,`entity_id`);
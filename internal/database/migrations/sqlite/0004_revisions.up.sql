@@ -0,0 +1,2 @@
+CREATE TABLE `revisions` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`entity_type` text,`entity_id` integer,`version` integer,`actor_id` integer,`data` text);
+CREATE UNIQUE INDEX `idx_revisions_entity_version` ON `revisions`(`entity_type`,`entity_id`,`version`);
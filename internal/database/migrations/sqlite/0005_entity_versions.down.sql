@@ -0,0 +1,6 @@
+ALTER TABLE `books` DROP COLUMN `version`;
+ALTER TABLE `authors` DROP COLUMN `version`;
+ALTER TABLE `artists` DROP COLUMN `version`;
+ALTER TABLE `covers` DROP COLUMN `version`;
+ALTER TABLE `genres` DROP COLUMN `version`;
+ALTER TABLE `users` DROP COLUMN `version`;
@@ -0,0 +1,6 @@
+ALTER TABLE `books` ADD `version` integer NOT NULL DEFAULT 1;
+ALTER TABLE `authors` ADD `version` integer NOT NULL DEFAULT 1;
+ALTER TABLE `artists` ADD `version` integer NOT NULL DEFAULT 1;
+ALTER TABLE `covers` ADD `version` integer NOT NULL DEFAULT 1;
+ALTER TABLE `genres` ADD `version` integer NOT NULL DEFAULT 1;
+ALTER TABLE `users` ADD `version` integer NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+CREATE TABLE `import_jobs` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`finished_at` datetime,`actor_id` integer,`entity` text,`format` text,`file_name` text,`dry_run` numeric,`status` text,`message` text,`total` integer,`processed` integer,`created` integer,`updated` integer,`unchanged` integer,`failed` integer,`errors` text);
+CREATE INDEX `idx_import_jobs_created_at` ON `import_jobs`(`created_at`);
+CREATE INDEX `idx_import_jobs_actor_id` ON `import_jobs`(`actor_id`);
+CREATE INDEX `idx_import_jobs_status` ON `import_jobs`(`status`);
@@ -0,0 +1 @@
+-- The FTS5 search index of SQLite is created at startup, only builds with -tags sqlite_fts5 can create it
@@ -0,0 +1 @@
+-- SQLite has no JSON column type, its JSON functions read the text columns as they are
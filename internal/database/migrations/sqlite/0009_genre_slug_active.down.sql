@@ -0,0 +1,5 @@
+-- Deleted genres sharing a slug with another genre get their ID appended, the unique index covers them again
+UPDATE `genres` SET `slug` = `slug` || '-' || `id`
+WHERE `deleted_at` IS NOT NULL AND `slug` IN (SELECT `slug` FROM `genres` GROUP BY `slug` HAVING COUNT(*) > 1);
+DROP INDEX IF EXISTS `idx_genres_slug`;
+CREATE UNIQUE INDEX `idx_genres_slug` ON `genres`(`slug`);
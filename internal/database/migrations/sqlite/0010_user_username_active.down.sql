@@ -0,0 +1,12 @@
+-- Deleted users sharing a username with another user get their ID appended, the constraint covers them again
+UPDATE `users` SET `username` = `username` || '-' || `id`
+WHERE `deleted_at` IS NOT NULL AND `username` IN (SELECT `username` FROM `users` GROUP BY `username` HAVING COUNT(*) > 1);
+
+CREATE TABLE `users_old` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`username` text,`password` text,`role_id` integer,`disabled` numeric DEFAULT false,`must_change_password` numeric DEFAULT false,`version` integer NOT NULL DEFAULT 1,CONSTRAINT `fk_users_role` FOREIGN KEY (`role_id`) REFERENCES `roles`(`id`),CONSTRAINT `uni_users_username` UNIQUE (`username`));
+
+INSERT INTO `users_old` (`id`, `created_at`, `updated_at`, `deleted_at`, `username`, `password`, `role_id`, `disabled`, `must_change_password`, `version`)
+SELECT `id`, `created_at`, `updated_at`, `deleted_at`, `username`, `password`, `role_id`, `disabled`, `must_change_password`, `version` FROM `users`;
+
+DROP TABLE `users`;
+ALTER TABLE `users_old` RENAME TO `users`;
+CREATE INDEX `idx_users_deleted_at` ON `users`(`deleted_at`);
@@ -0,0 +1,12 @@
+-- Usernames of soft deleted users can be taken again. SQLite cannot drop the UNIQUE
+-- constraint of the table, so the table is rebuilt with a partial index in its place.
+
+CREATE TABLE `users_new` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`username` text,`password` text,`role_id` integer,`disabled` numeric DEFAULT false,`must_change_password` numeric DEFAULT false,`version` integer NOT NULL DEFAULT 1,CONSTRAINT `fk_users_role` FOREIGN KEY (`role_id`) REFERENCES `roles`(`id`));
+
+INSERT INTO `users_new` (`id`, `created_at`, `updated_at`, `deleted_at`, `username`, `password`, `role_id`, `disabled`, `must_change_password`, `version`)
+SELECT `id`, `created_at`, `updated_at`, `deleted_at`, `username`, `password`, `role_id`, `disabled`, `must_change_password`, `version` FROM `users`;
+
+DROP TABLE `users`;
+ALTER TABLE `users_new` RENAME TO `users`;
+CREATE INDEX `idx_users_deleted_at` ON `users`(`deleted_at`);
+CREATE UNIQUE INDEX `idx_users_username` ON `users`(`username`) WHERE `deleted_at` IS NULL;
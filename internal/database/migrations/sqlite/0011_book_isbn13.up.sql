@@ -0,0 +1,16 @@
+-- Books are looked up by their ISBN as ISBN-13 without hyphens, so an ISBN-10 finds the book
+-- stored with its ISBN-13. The conversion is the one of models.ISBN13.
+ALTER TABLE `books` ADD `isbn13` text;
+
+UPDATE `books` SET `isbn13` = CASE
+	WHEN `normalized`.`isbn` GLOB '[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]' THEN `normalized`.`isbn`
+	WHEN `normalized`.`isbn` GLOB '[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9X]' THEN '978' || substr(`normalized`.`isbn`, 1, 9) || ((10 - (38
+		+ 3 * substr(`normalized`.`isbn`, 1, 1) + substr(`normalized`.`isbn`, 2, 1) + 3 * substr(`normalized`.`isbn`, 3, 1)
+		+ substr(`normalized`.`isbn`, 4, 1) + 3 * substr(`normalized`.`isbn`, 5, 1) + substr(`normalized`.`isbn`, 6, 1)
+		+ 3 * substr(`normalized`.`isbn`, 7, 1) + substr(`normalized`.`isbn`, 8, 1) + 3 * substr(`normalized`.`isbn`, 9, 1)) % 10) % 10)
+	ELSE ''
+END
+FROM (SELECT `id`, UPPER(REPLACE(REPLACE(COALESCE(`isbn`, ''), '-', ''), ' ', '')) AS `isbn` FROM `books`) AS `normalized`
+WHERE `books`.`id` = `normalized`.`id`;
+
+CREATE INDEX `idx_books_isbn13` ON `books`(`isbn13`);
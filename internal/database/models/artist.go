@@ -6,6 +6,7 @@ import (
 
 type Artist struct {
 	gorm.Model
+	Versioned
 	FirstName string   `json:"first_name" binding:"required"`
 	LastName  string   `json:"last_name" binding:"required"`
 	Covers    []*Cover `gorm:"many2many:artist_covers;"`
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditActionCreate   = "create"
+	AuditActionUpdate   = "update"
+	AuditActionDelete   = "delete"
+	AuditActionRestore  = "restore"
+	AuditActionPurge    = "purge"
+	AuditActionRollback = "rollback"
+)
+
+// AuditEntry records a change made through the admin API. Entries are only ever added.
+type AuditEntry struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+	ActorID    uint      `json:"actor_id" gorm:"index"` // User who made the change
+	Action     string    `json:"action"`
+	EntityType string    `json:"entity_type" gorm:"index:idx_audit_entries_entity"` // e.g. book
+	EntityID   uint      `json:"entity_id" gorm:"index:idx_audit_entries_entity"`
+	Changes    Changes   `json:"changes"`
+	IP         string    `json:"ip"`
+	RequestID  string    `json:"request_id"`
+}
+
+// FieldChange is the value of a field before and after a change, null when it had none
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Changes maps the JSON names of the changed fields to their change, it is stored as JSON text
+type Changes map[string]FieldChange
+
+func (changes Changes) Value() (driver.Value, error) {
+	return jsonValue(changes)
+}
+
+func (changes *Changes) Scan(value any) error {
+	return scanJSON(value, changes)
+}
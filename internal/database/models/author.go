@@ -6,6 +6,7 @@ import (
 
 type Author struct {
 	gorm.Model
+	Versioned
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
 	Books     []Book
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,14 +9,53 @@ import (
 
 type Book struct {
 	gorm.Model
+	Versioned
 	Title         string    `json:"title" binding:"required"`
 	PublishedDate time.Time `json:"published_date" binding:"required"`
 	DigitalOnly   bool      `json:"digital_only" binding:"required" gorm:"default:false"`
 	Pages         uint      `json:"pages" binding:"required"`
 	Description   string    `json:"description" binding:"required"`
 	ISBN          string    `json:"isbn" binding:"required"`
+	ISBN13        string    `json:"-" gorm:"column:isbn13;size:13;index"` // ISBN as ISBN-13 without hyphens, set on save
 	Price         float32   `json:"price" binding:"required"`
 	Cover         Cover
-	AuthorID      uint   `json:"author_id"`
-	Author        Author `json:"author" gorm:"foreignKey:AuthorID"`
+	AuthorID      uint     `json:"author_id"`
+	Author        Author   `json:"author" gorm:"foreignKey:AuthorID"`
+	Genres        []*Genre `json:"genres" gorm:"many2many:book_genres;"`
+}
+
+// BeforeSave keeps ISBN13 in step with ISBN, books are looked up by it
+func (book *Book) BeforeSave(tx *gorm.DB) error {
+	book.ISBN13 = ISBN13(book.ISBN)
+	return nil
+}
+
+// ISBN13 returns an ISBN-10 or ISBN-13 as ISBN-13 without hyphens and spaces, so both forms
+// of a book compare equal. The check digit is not verified, anything that does not have the
+// digits of an ISBN returns an empty string. Migration 0011_book_isbn13 does the same in SQL.
+func ISBN13(isbn string) string {
+	isbn = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+	for i, char := range isbn {
+		if (char < '0' || char > '9') && !(char == 'X' && i == 9 && len(isbn) == 10) {
+			return ""
+		}
+	}
+
+	switch len(isbn) {
+	case 13:
+		return isbn
+	case 10:
+		// 978 prefixed to the first nine digits, with the check digit of an ISBN-13
+		isbn = "978" + isbn[:9]
+		sum := 0
+		for i, char := range isbn {
+			digit := int(char - '0')
+			if i%2 == 1 {
+				digit *= 3
+			}
+			sum += digit
+		}
+		return isbn + string(rune('0'+(10-sum%10)%10))
+	}
+	return ""
 }
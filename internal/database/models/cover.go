@@ -8,9 +8,28 @@ import (
 
 type Cover struct {
 	gorm.Model
-	DesignIdeas sql.NullString `json:"design_ideas" binding:"required"`
-	ImageURL    sql.NullString `json:"image_url"`
-	BookID      uint           `json:"book_id" binding:"required"`
-	Book        *Book          `json:"book" gorm:"foreignKey:BookID"`
-	Artists     []*Artist      `gorm:"many2many:artist_covers;"`
+	Versioned
+	DesignIdeas   sql.NullString `json:"design_ideas" binding:"required"`
+	ImageURL      sql.NullString `json:"image_url"`
+	ImageKey      string         `json:"-"` // Storage key of an uploaded image
+	ImageWidth    int            `json:"image_width"`
+	ImageHeight   int            `json:"image_height"`
+	DominantColor string         `json:"dominant_color"` // Placeholder color shown while the image loads
+	BlurHash      string         `json:"blurhash"`       // Blurred placeholder, see https://blurha.sh
+	Variants      []CoverVariant `json:"variants"`
+	BookID        uint           `json:"book_id" binding:"required"`
+	Book          *Book          `json:"book" gorm:"foreignKey:BookID"`
+	Artists       []*Artist      `gorm:"many2many:artist_covers;"`
+}
+
+// ImageKeys returns the storage keys of the uploaded image and its variants
+func (cover *Cover) ImageKeys() []string {
+	var keys []string
+	if cover.ImageKey != "" {
+		keys = append(keys, cover.ImageKey)
+	}
+	for _, variant := range cover.Variants {
+		keys = append(keys, variant.Key)
+	}
+	return keys
 }
@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// CoverVariant is a resized copy of an uploaded cover image, generated in the
+// format of the upload and as WebP so clients can build responsive images.
+type CoverVariant struct {
+	gorm.Model
+	CoverID uint   `json:"cover_id" gorm:"index"`
+	Name    string `json:"name"`   // thumbnail, medium or large
+	Format  string `json:"format"` // jpeg, png or webp
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	URL     string `json:"url"`
+	Key     string `json:"-"` // Storage key of the variant
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+type Genre struct {
+	gorm.Model
+	Versioned
+	Name     string  `json:"name" binding:"required"`
+	Slug     string  `json:"slug" gorm:"index"` // Unique among genres that are not deleted
+	ParentID *uint   `json:"parent_id"`
+	Parent   *Genre  `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
+	Children []Genre `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+	Books    []*Book `json:"books,omitempty" gorm:"many2many:book_genres;"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// Statuses of an import job. A job that ran to the end succeeded, even when some of its
+// rows were rejected, those are listed in its errors. A job fails when it could not finish.
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusSucceeded = "succeeded"
+	ImportStatusFailed    = "failed"
+)
+
+// ImportJob tracks a bulk import of a file. Dry runs validate every row and count what
+// would change without saving anything.
+type ImportJob struct {
+	ID         uint         `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time    `json:"created_at" gorm:"index"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+	FinishedAt *time.Time   `json:"finished_at"`
+	ActorID    uint         `json:"actor_id" gorm:"index"` // User who started the import
+	Entity     string       `json:"entity"`                // books, authors or artists
+	Format     string       `json:"format"`                // csv or ndjson
+	FileName   string       `json:"file_name"`
+	DryRun     bool         `json:"dry_run"`
+	Status     string       `json:"status" gorm:"index"`
+	Message    string       `json:"message"` // Why a failed job stopped
+	Total      int          `json:"total"`   // Rows in the file
+	Processed  int          `json:"processed"`
+	Created    int          `json:"created"`
+	Updated    int          `json:"updated"`
+	Unchanged  int          `json:"unchanged"`
+	Failed     int          `json:"failed"`
+	Errors     ImportErrors `json:"errors"`
+}
+
+// ImportRowError explains why a row of an import was rejected
+type ImportRowError struct {
+	Row     int    `json:"row"`             // Line of the row in the file, starting at 1
+	Field   string `json:"field,omitempty"` // Field the error is about, empty for the whole row
+	Message string `json:"message"`
+}
+
+// ImportErrors lists the rejected rows of an import, it is stored as JSON text
+type ImportErrors []ImportRowError
+
+func (errors ImportErrors) Value() (driver.Value, error) {
+	return jsonValue(errors)
+}
+
+func (errors *ImportErrors) Scan(value any) error {
+	return scanJSON(value, errors)
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonValue stores a value in a text column as JSON
+func jsonValue(value any) (driver.Value, error) {
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// scanJSON decodes a JSON text column into dest, NULL leaves dest untouched
+func scanJSON(value any, dest any) error {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(value), dest)
+	case []byte:
+		return json.Unmarshal(value, dest)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", value, dest)
+	}
+}
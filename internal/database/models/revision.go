@@ -0,0 +1,37 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// Entity types that keep revisions
+const (
+	RevisionEntityBook   = "book"
+	RevisionEntityAuthor = "author"
+	RevisionEntityArtist = "artist"
+	RevisionEntityCover  = "cover"
+)
+
+// Revision is a numbered snapshot of the editable fields of an entity, taken after every
+// change so an older state can be compared with the current one or brought back.
+type Revision struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time `json:"created_at"`
+	EntityType string    `json:"entity_type" gorm:"uniqueIndex:idx_revisions_entity_version"`
+	EntityID   uint      `json:"entity_id" gorm:"uniqueIndex:idx_revisions_entity_version"`
+	Version    uint      `json:"version" gorm:"uniqueIndex:idx_revisions_entity_version"` // Counts up from 1 per entity
+	ActorID    uint      `json:"actor_id"`                                                // User who made the change
+	Data       Snapshot  `json:"data"`
+}
+
+// Snapshot holds the fields of an entity by their JSON name, it is stored as JSON text
+type Snapshot map[string]any
+
+func (snapshot Snapshot) Value() (driver.Value, error) {
+	return jsonValue(snapshot)
+}
+
+func (snapshot *Snapshot) Scan(value any) error {
+	return scanJSON(value, snapshot)
+}
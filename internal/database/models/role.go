@@ -0,0 +1,82 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Permissions checked by the admin routes
+const (
+	PermissionBooksRead    = "books:read"
+	PermissionBooksWrite   = "books:write"
+	PermissionAuthorsRead  = "authors:read"
+	PermissionAuthorsWrite = "authors:write"
+	PermissionArtistsRead  = "artists:read"
+	PermissionArtistsWrite = "artists:write"
+	PermissionCoversRead   = "covers:read"
+	PermissionCoversWrite  = "covers:write"
+	PermissionGenresRead   = "genres:read"
+	PermissionGenresWrite  = "genres:write"
+	PermissionSearchAdmin  = "search:admin"
+	PermissionUsersRead    = "users:read"
+	PermissionUsersWrite   = "users:write"
+	PermissionAuditRead    = "audit:read"
+	PermissionImportsRead  = "imports:read"
+	PermissionImportsWrite = "imports:write"
+)
+
+// Built-in roles
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// DefaultRolePermissions lists the permissions granted to each built-in role
+var DefaultRolePermissions = map[string][]string{
+	RoleAdmin: {
+		PermissionBooksRead, PermissionBooksWrite,
+		PermissionAuthorsRead, PermissionAuthorsWrite,
+		PermissionArtistsRead, PermissionArtistsWrite,
+		PermissionCoversRead, PermissionCoversWrite,
+		PermissionGenresRead, PermissionGenresWrite,
+		PermissionSearchAdmin,
+		PermissionUsersRead, PermissionUsersWrite,
+		PermissionAuditRead,
+		PermissionImportsRead, PermissionImportsWrite,
+	},
+	RoleEditor: {
+		PermissionBooksRead, PermissionBooksWrite,
+		PermissionAuthorsRead, PermissionAuthorsWrite,
+		PermissionArtistsRead, PermissionArtistsWrite,
+		PermissionCoversRead, PermissionCoversWrite,
+		PermissionGenresRead, PermissionGenresWrite,
+		PermissionImportsRead, PermissionImportsWrite,
+	},
+	RoleViewer: {
+		PermissionBooksRead,
+		PermissionAuthorsRead,
+		PermissionArtistsRead,
+		PermissionCoversRead,
+		PermissionGenresRead,
+	},
+}
+
+type Role struct {
+	gorm.Model
+	Name        string        `json:"name" binding:"required" gorm:"unique"`
+	Permissions []*Permission `json:"permissions" gorm:"many2many:role_permissions;"`
+}
+
+type Permission struct {
+	gorm.Model
+	Name string `json:"name" binding:"required" gorm:"unique"`
+}
+
+// PermissionNames returns the names of all permissions granted by the role
+func (r *Role) PermissionNames() []string {
+	names := []string{}
+	for _, permission := range r.Permissions {
+		names = append(names, permission.Name)
+	}
+	return names
+}
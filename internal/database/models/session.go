@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is a refresh token issued to a device. Every refresh rotates the token,
+// revoking the current session and starting a new one in the same family.
+type Session struct {
+	gorm.Model
+	UserID     uint       `json:"user_id" gorm:"index"`
+	User       *User      `json:"-" gorm:"foreignKey:UserID"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex"`
+	FamilyID   string     `json:"family_id" gorm:"index"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// Active reports whether the session can still be used to refresh
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(time.Now())
+}
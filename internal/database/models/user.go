@@ -1,15 +1,16 @@
 package models
 
 import (
-	"time"
-
 	"gorm.io/gorm"
 )
 
 type User struct {
 	gorm.Model
-	Username     string `json:"username" binding:"required" gorm:"unique"`
-	Password     string `json:"password" binding:"required"`
-	RefreshToken string `gorm:"unique"`
-	ExpriesAt    time.Time
+	Versioned
+	Username           string `json:"username" binding:"required" gorm:"index"` // Unique among users that are not deleted
+	Password           string `json:"password" binding:"required"`
+	RoleID             *uint  `json:"role_id"`
+	Role               *Role  `json:"role,omitempty" gorm:"foreignKey:RoleID"`
+	Disabled           bool   `json:"disabled" gorm:"default:false"`
+	MustChangePassword bool   `json:"must_change_password" gorm:"default:false"`
 }
@@ -0,0 +1,22 @@
+package models
+
+// Versioned counts the saves of a model. Embedded in a model it makes the Service refuse
+// to save a copy that was read before the last save, instead of overwriting that save.
+type Versioned struct {
+	Version uint `json:"version" gorm:"not null;default:1"`
+}
+
+// CurrentVersion returns the version the model was read or last saved with
+func (versioned *Versioned) CurrentVersion() uint {
+	return versioned.Version
+}
+
+func (versioned *Versioned) SetVersion(version uint) {
+	versioned.Version = version
+}
+
+// VersionedModel is implemented by pointers to the models embedding Versioned
+type VersionedModel interface {
+	CurrentVersion() uint
+	SetVersion(version uint)
+}
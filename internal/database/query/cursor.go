@@ -0,0 +1,147 @@
+package query
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cursor marks the last row of a page for keyset pagination.
+// Values holds the sort key values of that row in the order of the sort, nil for NULL,
+// the ID breaks ties between rows with equal sort keys.
+type Cursor struct {
+	Sort   string
+	Values []any
+	ID     uint
+}
+
+// cursorPayload is the signed JSON form of a Cursor, values are kept as text so
+// they can be parsed back with the type of their field, NULL is kept as null.
+type cursorPayload struct {
+	Sort   string    `json:"s"`
+	Values []*string `json:"v"`
+	ID     uint      `json:"id"`
+}
+
+// Page describes the position of a page of results within the full list.
+// Total is nil on pages continued after a cursor, counting every matching row
+// again on each of them would undo the point of keyset pagination.
+type Page struct {
+	Total      *int64
+	NextCursor string
+}
+
+var (
+	cursorSecret     []byte
+	cursorSecretOnce sync.Once
+)
+
+// secret returns the key used to sign cursors, read from CURSOR_SECRET.
+// Without it a random key is generated, so cursors do not survive a restart.
+func secret() []byte {
+	cursorSecretOnce.Do(func() {
+		if s := os.Getenv("CURSOR_SECRET"); s != "" {
+			cursorSecret = []byte(s)
+			return
+		}
+
+		log.Println("CURSOR_SECRET is not set, using a random key for pagination cursors")
+		cursorSecret = make([]byte, 32)
+		if _, err := rand.Read(cursorSecret); err != nil {
+			log.Fatal(err)
+		}
+	})
+	return cursorSecret
+}
+
+// SortKey returns the canonical form of the sort order, as used in the sort parameter
+func (q ListQuery) SortKey() string {
+	keys := make([]string, len(q.Sort))
+	for i, sort := range q.Sort {
+		if sort.Desc {
+			keys[i] = "-" + sort.Field
+		} else {
+			keys[i] = sort.Field
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// EncodeCursor returns an opaque, signed token pointing after the row with the given sort values and ID
+func EncodeCursor(q ListQuery, values []any, id uint) string {
+	payload := cursorPayload{Sort: q.SortKey(), ID: id}
+	for _, value := range values {
+		var text string
+		switch value := value.(type) {
+		case nil:
+			payload.Values = append(payload.Values, nil)
+			continue
+		case time.Time:
+			text = value.Format(time.RFC3339Nano)
+		default:
+			text = fmt.Sprint(value)
+		}
+		payload.Values = append(payload.Values, &text)
+	}
+
+	data, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(sign(data))
+}
+
+// decodeCursor verifies the signature of a token and parses its values against the sort of the query
+func decodeCursor(token string, q ListQuery, fields Fields) (*Cursor, error) {
+	encodedData, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errorf("Invalid cursor")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encodedData)
+	if err != nil {
+		return nil, errorf("Invalid cursor")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, sign(data)) {
+		return nil, errorf("Invalid cursor")
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, errorf("Invalid cursor")
+	}
+
+	if payload.Sort != q.SortKey() || len(payload.Values) != len(q.Sort) {
+		return nil, errorf("Cursor does not match the requested sort order")
+	}
+
+	cursor := &Cursor{Sort: payload.Sort, ID: payload.ID}
+	for i, sort := range q.Sort {
+		if payload.Values[i] == nil {
+			if !sort.Nullable {
+				return nil, errorf("Invalid cursor")
+			}
+			cursor.Values = append(cursor.Values, nil)
+			continue
+		}
+		value, err := parseValue(fields[sort.Field].Type, *payload.Values[i])
+		if err != nil {
+			return nil, errorf("Invalid cursor")
+		}
+		cursor.Values = append(cursor.Values, value)
+	}
+
+	return cursor, nil
+}
+
+func sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, secret())
+	mac.Write(data)
+	return mac.Sum(nil)
+}
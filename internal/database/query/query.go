@@ -0,0 +1,205 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 10
+	MaxLimit     = 100
+)
+
+// Operator is a comparison used in a filter, e.g. filter[price][lt]=20
+type Operator string
+
+const (
+	OpEq   Operator = "eq"
+	OpNe   Operator = "ne"
+	OpLt   Operator = "lt"
+	OpLte  Operator = "lte"
+	OpGt   Operator = "gt"
+	OpGte  Operator = "gte"
+	OpLike Operator = "like"
+	OpIn   Operator = "in"
+)
+
+// FieldType decides how filter values are parsed before they reach the database
+type FieldType int
+
+const (
+	String FieldType = iota
+	Number
+	Integer
+	Bool
+	Date
+)
+
+// Field describes a column that clients are allowed to filter and sort on.
+// Nullable columns sort their NULLs after every value, pages continue past them.
+type Field struct {
+	Column   string
+	Type     FieldType
+	Nullable bool
+}
+
+// Fields is the whitelist of queryable fields for a model, keyed by API name
+type Fields map[string]Field
+
+// Filter is a single parsed filter condition
+type Filter struct {
+	Field    string
+	Column   string
+	Operator Operator
+	Value    any
+}
+
+// Sort is a single parsed sort key
+type Sort struct {
+	Field    string
+	Column   string
+	Desc     bool
+	Nullable bool
+}
+
+// ListQuery holds the pagination, filtering and sorting options of a list request.
+// When After is set the list continues after that row and Offset is ignored.
+type ListQuery struct {
+	Limit   int
+	Offset  int
+	After   *Cursor
+	Filters []Filter
+	Sort    []Sort
+}
+
+// Error is returned for query strings that reference unknown fields or carry invalid values
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func errorf(format string, args ...any) error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// filterKey matches filter[field] and filter[field][op]
+var filterKey = regexp.MustCompile(`^filter\[([a-z0-9_]+)\](?:\[([a-z]+)\])?$`)
+
+// Parse reads limit, offset, after, filter[...] and sort parameters from a query string.
+// Only fields present in the whitelist are accepted.
+func Parse(values url.Values, fields Fields) (ListQuery, error) {
+	q := ListQuery{Limit: DefaultLimit}
+
+	if limitStr := values.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return q, errorf("Invalid limit format")
+		}
+		q.Limit = min(limit, MaxLimit)
+	}
+
+	if offsetStr := values.Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return q, errorf("Invalid offset format")
+		}
+		q.Offset = offset
+	}
+
+	for key, raw := range values {
+		matches := filterKey.FindStringSubmatch(key)
+		if matches == nil {
+			continue
+		}
+
+		name, op := matches[1], Operator(matches[2])
+		if op == "" {
+			op = OpEq
+		}
+
+		field, ok := fields[name]
+		if !ok {
+			return q, errorf("Filtering on %q is not supported", name)
+		}
+
+		value, err := parseFilterValue(field, op, raw[len(raw)-1])
+		if err != nil {
+			return q, errorf("Invalid value for filter %q: %v", name, err)
+		}
+
+		q.Filters = append(q.Filters, Filter{Field: name, Column: field.Column, Operator: op, Value: value})
+	}
+
+	if sortStr := values.Get("sort"); sortStr != "" {
+		for _, key := range strings.Split(sortStr, ",") {
+			desc := strings.HasPrefix(key, "-")
+			name := strings.TrimPrefix(key, "-")
+
+			field, ok := fields[name]
+			if !ok {
+				return q, errorf("Sorting on %q is not supported", name)
+			}
+			q.Sort = append(q.Sort, Sort{Field: name, Column: field.Column, Desc: desc, Nullable: field.Nullable})
+		}
+	}
+
+	if after := values.Get("after"); after != "" {
+		cursor, err := decodeCursor(after, q, fields)
+		if err != nil {
+			return q, err
+		}
+		q.After = cursor
+		q.Offset = 0
+	}
+
+	return q, nil
+}
+
+func parseFilterValue(field Field, op Operator, raw string) (any, error) {
+	switch op {
+	case OpEq, OpNe, OpLt, OpLte, OpGt, OpGte:
+		return parseValue(field.Type, raw)
+	case OpLike:
+		if field.Type != String {
+			return nil, fmt.Errorf("operator %q only applies to text fields", op)
+		}
+		return "%" + raw + "%", nil
+	case OpIn:
+		var list []any
+		for _, part := range strings.Split(raw, ",") {
+			value, err := parseValue(field.Type, part)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+}
+
+func parseValue(fieldType FieldType, raw string) (any, error) {
+	switch fieldType {
+	case Number:
+		return strconv.ParseFloat(raw, 64)
+	case Integer:
+		return strconv.ParseInt(raw, 10, 64)
+	case Bool:
+		return strconv.ParseBool(raw)
+	case Date:
+		if t, err := time.Parse("2006-01-02", raw); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.RFC3339, raw)
+	default:
+		return raw, nil
+	}
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+
+	"go-playground/internal/database/models"
+	"go-playground/internal/database/query"
+
+	"gorm.io/gorm"
+)
+
+// ListRevisions pages through the revisions of an entity
+func (s *service) ListRevisions(ctx context.Context, entityType string, entityID uint, q query.ListQuery) ([]models.Revision, query.Page, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var revisions []models.Revision
+	page, err := findPage(db.Where("entity_type = ? AND entity_id = ?", entityType, entityID), &revisions, q)
+	if err != nil {
+		return nil, page, err
+	}
+	return revisions, page, nil
+}
+
+func (s *service) GetRevision(ctx context.Context, entityType string, entityID uint, version uint) (*models.Revision, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var revision models.Revision
+	if err := db.Where("entity_type = ? AND entity_id = ? AND version = ?", entityType, entityID, version).First(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// AddRevision stores revision as the next version of its entity. An entity without revisions
+// first gets baseline stored as version 1, when given, so the state it had before revisions
+// were kept can be rolled back to. Two concurrent changes to an entity end in ErrConflict.
+func (s *service) AddRevision(ctx context.Context, revision *models.Revision, baseline models.Snapshot) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var latest uint
+		err := tx.Model(&models.Revision{}).
+			Where("entity_type = ? AND entity_id = ?", revision.EntityType, revision.EntityID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+
+		if latest == 0 && baseline != nil {
+			latest++
+			initial := models.Revision{
+				EntityType: revision.EntityType,
+				EntityID:   revision.EntityID,
+				Version:    latest,
+				Data:       baseline,
+			}
+			if err := tx.Create(&initial).Error; err != nil {
+				return err
+			}
+		}
+
+		revision.Version = latest + 1
+		return tx.Create(revision).Error
+	})
+}
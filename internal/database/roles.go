@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"go-playground/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+// seedRoles makes sure the built-in roles exist and grant at least their default permissions
+func seedRoles(db *gorm.DB) error {
+	for roleName, permissionNames := range models.DefaultRolePermissions {
+		var permissions []*models.Permission
+		for _, name := range permissionNames {
+			permission := models.Permission{Name: name}
+			if err := db.Where(models.Permission{Name: name}).FirstOrCreate(&permission).Error; err != nil {
+				return err
+			}
+			permissions = append(permissions, &permission)
+		}
+
+		role := models.Role{Name: roleName}
+		if err := db.Where(models.Role{Name: roleName}).FirstOrCreate(&role).Error; err != nil {
+			return err
+		}
+
+		if err := db.Model(&role).Association("Permissions").Append(permissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *service) GetRole(ctx context.Context, name string) (*models.Role, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var role models.Role
+	if err := db.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
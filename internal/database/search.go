@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Entity types stored in the search index
+const (
+	SearchEntityBook   = "book"
+	SearchEntityAuthor = "author"
+	SearchEntityArtist = "artist"
+)
+
+// ErrSearchUnavailable is returned when the database does not support full-text search,
+// e.g. SQLite built without FTS5. Build with `-tags sqlite_fts5` to enable it.
+var ErrSearchUnavailable = errors.New("full-text search is not available")
+
+// SearchResult is a single ranked hit from the search index.
+// Title and Snippet are HTML escaped with the matched terms wrapped in <mark> tags,
+// a lower Rank is a better match.
+type SearchResult struct {
+	EntityType string  `json:"entity_type"`
+	EntityID   uint    `json:"entity_id"`
+	Title      string  `json:"title"`
+	Snippet    string  `json:"snippet"`
+	Rank       float64 `json:"rank"`
+}
+
+// Backends wrap matched terms in these control characters instead of <mark> tags, so the
+// text around them can be escaped before the tags are put in place by markHighlights.
+const (
+	highlightStart = "\x02"
+	highlightEnd   = "\x03"
+)
+
+// searchBackend implements full-text search on top of the features of one SQL dialect
+type searchBackend interface {
+	// setup creates the indexes search depends on, it runs on every start
+	setup(db *gorm.DB) error
+	// rebuild reindexes every book, author and artist
+	rebuild(db *gorm.DB) error
+	// search returns up to limit hits of one entity type, best matches first,
+	// with the matched terms between highlightStart and highlightEnd
+	search(db *gorm.DB, query string, entityType string, limit int) ([]SearchResult, error)
+}
+
+// newSearchBackend returns the search implementation for a gorm dialect name
+func newSearchBackend(dialect string) searchBackend {
+	switch dialect {
+	case "postgres":
+		return postgresSearch{}
+	case "mysql":
+		return mysqlSearch{}
+	default:
+		return sqliteSearch{}
+	}
+}
+
+// RebuildSearchIndex drops every entry in the search index and reindexes all
+// books, authors and artists.
+func (s *service) RebuildSearchIndex(ctx context.Context) error {
+	if s.search == nil {
+		return ErrSearchUnavailable
+	}
+	// Reindexing everything takes longer than a single query, it is only bound to the caller
+	return s.search.rebuild(s.db.WithContext(ctx))
+}
+
+// Search runs a full-text query against the search index and returns up to
+// limit hits per entity type, best matches first.
+func (s *service) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	if s.search == nil {
+		return nil, ErrSearchUnavailable
+	}
+
+	if len(searchTerms(query)) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	results := []SearchResult{}
+	for _, entityType := range []string{SearchEntityBook, SearchEntityAuthor, SearchEntityArtist} {
+		hits, err := s.search.search(db, query, entityType, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range hits {
+			hits[i].Title = markHighlights(hits[i].Title)
+			hits[i].Snippet = markHighlights(hits[i].Snippet)
+		}
+		results = append(results, hits...)
+	}
+
+	return results, nil
+}
+
+// markHighlights escapes the stored text of a hit for HTML, titles and descriptions are
+// user input, and only then turns the highlighted terms into <mark> tags.
+func markHighlights(text string) string {
+	return strings.NewReplacer(highlightStart, "<mark>", highlightEnd, "</mark>").Replace(html.EscapeString(text))
+}
+
+// searchTerms splits a query into the words that have to match, dropping
+// punctuation so user input can never be parsed as query syntax of a dialect.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// mysqlSearch uses InnoDB FULLTEXT indexes in boolean mode. MySQL cannot highlight
+// matches, so titles and snippets are marked up after the query.
+type mysqlSearch struct{}
+
+// mysqlSearchTable describes the FULLTEXT index of an entity type, its columns must be those
+// of the index in the migrations for MATCH to use it
+type mysqlSearchTable struct {
+	table   string
+	columns string
+	title   string
+	body    string
+}
+
+var mysqlSearchTables = map[string]mysqlSearchTable{
+	SearchEntityBook: {
+		table:   "books",
+		columns: "title, description, isbn",
+		title:   "title",
+		body:    "description",
+	},
+	SearchEntityAuthor: {
+		table:   "authors",
+		columns: "first_name, last_name",
+		title:   "CONCAT_WS(' ', first_name, last_name)",
+		body:    "''",
+	},
+	SearchEntityArtist: {
+		table:   "artists",
+		columns: "first_name, last_name",
+		title:   "CONCAT_WS(' ', first_name, last_name)",
+		body:    "''",
+	},
+}
+
+// setup has nothing to do, the FULLTEXT indexes are created by migration 0007_search_indexes
+func (mysqlSearch) setup(db *gorm.DB) error {
+	return nil
+}
+
+// rebuild optimizes the FULLTEXT indexes, InnoDB keeps their content up to date itself
+func (mysqlSearch) rebuild(db *gorm.DB) error {
+	for _, table := range mysqlSearchTables {
+		if err := db.Exec("OPTIMIZE TABLE " + table.table).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mysqlSearch) search(db *gorm.DB, query string, entityType string, limit int) ([]SearchResult, error) {
+	table := mysqlSearchTables[entityType]
+	terms := searchTerms(query)
+
+	statement := fmt.Sprintf(`SELECT
+			'%[5]s' AS entity_type,
+			id AS entity_id,
+			%[1]s AS title,
+			%[2]s AS snippet,
+			-MATCH(%[3]s) AGAINST (? IN BOOLEAN MODE) AS `+"`rank`"+`
+		FROM %[4]s
+		WHERE deleted_at IS NULL AND MATCH(%[3]s) AGAINST (? IN BOOLEAN MODE)
+		ORDER BY `+"`rank`"+`
+		LIMIT ?`, table.title, table.body, table.columns, table.table, entityType)
+
+	match := booleanModeExpression(terms)
+	var hits []SearchResult
+	if err := db.Raw(statement, match, match, limit).Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range hits {
+		hits[i].Title = highlightTerms(hits[i].Title, terms)
+		hits[i].Snippet = highlightTerms(snippetAround(hits[i].Snippet, terms, 16), terms)
+	}
+	return hits, nil
+}
+
+// booleanModeExpression requires every word to match as a prefix, e.g. +tolk* +ring*
+func booleanModeExpression(terms []string) string {
+	var parts []string
+	for _, term := range terms {
+		parts = append(parts, "+"+term+"*")
+	}
+	return strings.Join(parts, " ")
+}
+
+// highlightTerms marks every word starting with one of the terms as highlighted
+func highlightTerms(text string, terms []string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		if matchesTerm(word, terms) {
+			words[i] = highlightStart + word + highlightEnd
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// snippetAround returns up to size words of text, starting a little before the first match
+func snippetAround(text string, terms []string, size int) string {
+	words := strings.Fields(text)
+	if len(words) <= size {
+		return strings.Join(words, " ")
+	}
+
+	start := 0
+	for i, word := range words {
+		if matchesTerm(word, terms) {
+			start = max(i-size/4, 0)
+			break
+		}
+	}
+	end := min(start+size, len(words))
+
+	snippet := strings.Join(words[start:end], " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(words) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func matchesTerm(word string, terms []string) bool {
+	for _, part := range searchTerms(word) {
+		for _, term := range terms {
+			if strings.HasPrefix(part, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// postgresSearch matches text search vectors computed from the tables themselves,
+// kept fast by GIN expression indexes. There is no separate index to keep in sync.
+type postgresSearch struct{}
+
+// postgresSearchTable describes how the documents of an entity type are built.
+// The document expression must be the same as in the indexes of the migrations for them to be used.
+type postgresSearchTable struct {
+	table    string
+	title    string
+	body     string
+	document string
+}
+
+var postgresSearchTables = map[string]postgresSearchTable{
+	SearchEntityBook: {
+		table:    "books",
+		title:    "coalesce(title, '')",
+		body:     "coalesce(description, '')",
+		document: "coalesce(title, '') || ' ' || coalesce(description, '') || ' ' || coalesce(isbn, '')",
+	},
+	SearchEntityAuthor: {
+		table:    "authors",
+		title:    "coalesce(first_name, '') || ' ' || coalesce(last_name, '')",
+		body:     "''",
+		document: "coalesce(first_name, '') || ' ' || coalesce(last_name, '')",
+	},
+	SearchEntityArtist: {
+		table:    "artists",
+		title:    "coalesce(first_name, '') || ' ' || coalesce(last_name, '')",
+		body:     "''",
+		document: "coalesce(first_name, '') || ' ' || coalesce(last_name, '')",
+	},
+}
+
+// setup has nothing to do, the GIN indexes are created by migration 0007_search_indexes
+func (postgresSearch) setup(db *gorm.DB) error {
+	return nil
+}
+
+// rebuild has nothing to do, PostgreSQL maintains the indexes itself
+func (postgresSearch) rebuild(db *gorm.DB) error {
+	return nil
+}
+
+func (postgresSearch) search(db *gorm.DB, query string, entityType string, limit int) ([]SearchResult, error) {
+	table := postgresSearchTables[entityType]
+
+	// Titles weigh more than the rest of the document, like the bm25 weights used with SQLite
+	statement := fmt.Sprintf(`SELECT
+			'%[5]s' AS entity_type,
+			id AS entity_id,
+			ts_headline('simple', %[1]s, q, 'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', HighlightAll=true') AS title,
+			ts_headline('simple', %[2]s, q, 'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxWords=16, MinWords=8') AS snippet,
+			-ts_rank(setweight(to_tsvector('simple', %[1]s), 'A') || to_tsvector('simple', %[3]s), q) AS rank
+		FROM %[4]s, to_tsquery('simple', ?) AS q
+		WHERE deleted_at IS NULL AND to_tsvector('simple', %[3]s) @@ q
+		ORDER BY rank
+		LIMIT ?`, table.title, table.body, table.document, table.table, entityType)
+
+	var hits []SearchResult
+	err := db.Raw(statement, tsQueryExpression(query), limit).Scan(&hits).Error
+	return hits, err
+}
+
+// tsQueryExpression turns free text into a tsquery where every word has to match as a prefix
+func tsQueryExpression(query string) string {
+	var terms []string
+	for _, word := range searchTerms(query) {
+		terms = append(terms, word+":*")
+	}
+	return strings.Join(terms, " & ")
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// sqliteSearch uses an FTS5 table, which needs SQLite built with -tags sqlite_fts5
+type sqliteSearch struct{}
+
+// searchIndexSchema creates the FTS5 table and the triggers keeping it in sync
+// with the books, authors and artists tables. Soft deleted rows are left out.
+var searchIndexSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+		entity_type UNINDEXED,
+		entity_id UNINDEXED,
+		title,
+		body,
+		isbn,
+		tokenize = 'unicode61 remove_diacritics 2'
+	)`,
+
+	`CREATE TRIGGER IF NOT EXISTS books_search_insert AFTER INSERT ON books WHEN new.deleted_at IS NULL BEGIN
+		INSERT INTO search_index (entity_type, entity_id, title, body, isbn) VALUES ('book', new.id, new.title, new.description, new.isbn);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS books_search_update AFTER UPDATE ON books BEGIN
+		DELETE FROM search_index WHERE entity_type = 'book' AND entity_id = old.id;
+		INSERT INTO search_index (entity_type, entity_id, title, body, isbn) SELECT 'book', new.id, new.title, new.description, new.isbn WHERE new.deleted_at IS NULL;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS books_search_delete AFTER DELETE ON books BEGIN
+		DELETE FROM search_index WHERE entity_type = 'book' AND entity_id = old.id;
+	END`,
+
+	`CREATE TRIGGER IF NOT EXISTS authors_search_insert AFTER INSERT ON authors WHEN new.deleted_at IS NULL BEGIN
+		INSERT INTO search_index (entity_type, entity_id, title, body, isbn) VALUES ('author', new.id, new.first_name || ' ' || new.last_name, '', '');
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS authors_search_update AFTER UPDATE ON authors BEGIN
+		DELETE FROM search_index WHERE entity_type = 'author' AND entity_id = old.id;
+		INSERT INTO search_index (entity_type, entity_id, title, body, isbn) SELECT 'author', new.id, new.first_name || ' ' || new.last_name, '', '' WHERE new.deleted_at IS NULL;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS authors_search_delete AFTER DELETE ON authors BEGIN
+		DELETE FROM search_index WHERE entity_type = 'author' AND entity_id = old.id;
+	END`,
+
+	`CREATE TRIGGER IF NOT EXISTS artists_search_insert AFTER INSERT ON artists WHEN new.deleted_at IS NULL BEGIN
+		INSERT INTO search_index (entity_type, entity_id, title, body, isbn) VALUES ('artist', new.id, new.first_name || ' ' || new.last_name, '', '');
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS artists_search_update AFTER UPDATE ON artists BEGIN
+		DELETE FROM search_index WHERE entity_type = 'artist' AND entity_id = old.id;
+		INSERT INTO search_index (entity_type, entity_id, title, body, isbn) SELECT 'artist', new.id, new.first_name || ' ' || new.last_name, '', '' WHERE new.deleted_at IS NULL;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS artists_search_delete AFTER DELETE ON artists BEGIN
+		DELETE FROM search_index WHERE entity_type = 'artist' AND entity_id = old.id;
+	END`,
+}
+
+// setup creates the search index if needed and fills it when it is created
+// against an existing database.
+func (search sqliteSearch) setup(db *gorm.DB) error {
+	created := !db.Migrator().HasTable("search_index")
+
+	for _, statement := range searchIndexSchema {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	if created {
+		return search.rebuild(db)
+	}
+	return nil
+}
+
+func (sqliteSearch) rebuild(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			`DELETE FROM search_index`,
+			`INSERT INTO search_index (entity_type, entity_id, title, body, isbn)
+				SELECT 'book', id, title, description, isbn FROM books WHERE deleted_at IS NULL`,
+			`INSERT INTO search_index (entity_type, entity_id, title, body, isbn)
+				SELECT 'author', id, first_name || ' ' || last_name, '', '' FROM authors WHERE deleted_at IS NULL`,
+			`INSERT INTO search_index (entity_type, entity_id, title, body, isbn)
+				SELECT 'artist', id, first_name || ' ' || last_name, '', '' FROM artists WHERE deleted_at IS NULL`,
+		}
+
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (sqliteSearch) search(db *gorm.DB, query string, entityType string, limit int) ([]SearchResult, error) {
+	var hits []SearchResult
+	err := db.Raw(`SELECT
+			entity_type,
+			entity_id,
+			highlight(search_index, 2, char(2), char(3)) AS title,
+			snippet(search_index, 3, char(2), char(3), '…', 16) AS snippet,
+			bm25(search_index, 0, 0, 10.0, 1.0, 5.0) AS rank
+		FROM search_index
+		WHERE search_index MATCH ? AND entity_type = ?
+		ORDER BY rank
+		LIMIT ?`, ftsMatchExpression(query), entityType, limit).Scan(&hits).Error
+	return hits, err
+}
+
+// ftsMatchExpression turns free text into an FTS5 query where every word has
+// to match as a prefix. Words are quoted so keywords like OR or NEAR are matched
+// as text instead of being parsed as FTS5 query syntax.
+func ftsMatchExpression(query string) string {
+	var terms []string
+	for _, word := range searchTerms(query) {
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-playground/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrSessionReused is returned when a refresh token that was already rotated is
+// presented again. The whole token family is revoked when this happens.
+var ErrSessionReused = errors.New("refresh token reuse detected")
+
+func (s *service) GetSessionByTokenHash(ctx context.Context, hash string) (*models.Session, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var session models.Session
+	if err := db.Preload("User.Role.Permissions").Where("token_hash = ?", hash).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RotateSession revokes the current session and stores next as its successor in
+// the same family. Rotating a session that was already revoked revokes the
+// whole family and returns ErrSessionReused.
+func (s *service) RotateSession(ctx context.Context, current *models.Session, next *models.Session) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Only one request may rotate a session, a concurrent replay updates nothing
+		result := tx.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Updates(map[string]any{"revoked_at": now, "last_used_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSessionReused
+		}
+
+		next.UserID = current.UserID
+		next.FamilyID = current.FamilyID
+		next.LastUsedAt = now
+		return tx.Create(next).Error
+	})
+
+	if errors.Is(err, ErrSessionReused) {
+		// The family has to be revoked even when the client hangs up on the replayed request
+		if revokeErr := s.RevokeSessionFamily(context.WithoutCancel(ctx), current.FamilyID); revokeErr != nil {
+			return revokeErr
+		}
+	}
+	return err
+}
+
+// RevokeSessionFamily revokes every session descending from the same login
+func (s *service) RevokeSessionFamily(ctx context.Context, familyID string) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	return db.Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions revokes every session of the user, logging them out on all devices
+func (s *service) RevokeUserSessions(ctx context.Context, userID uint) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// ListUserSessions returns the sessions of the user that can still be refreshed, most recently used first
+func (s *service) ListUserSessions(ctx context.Context, userID uint) ([]models.Session, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var sessions []models.Session
+	if err := db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
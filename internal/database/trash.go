@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+
+	"go-playground/internal/database/models"
+	"go-playground/internal/database/query"
+
+	"gorm.io/gorm"
+)
+
+// purgedWithOwner lists the has-many relations whose rows only exist for their owner,
+// they are purged together with it instead of keeping it from being purged.
+var purgedWithOwner = map[string]bool{
+	"Cover.Variants": true,
+}
+
+// TrashFields extends the whitelist of a list endpoint with the deletion time for its trash
+func TrashFields(fields query.Fields) query.Fields {
+	trash := maps.Clone(fields)
+	trash["deleted_at"] = query.Field{Column: "deleted_at", Type: query.Date}
+	return trash
+}
+
+// ListTrash lists the soft deleted rows of a table
+func (s *service) ListTrash(ctx context.Context, entities any, q query.ListQuery) (query.Page, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	if err := requireTable(db, entities); err != nil {
+		return query.Page{}, err
+	}
+
+	return findPage(trashed(db), entities, q)
+}
+
+// ListTrashedBefore returns the IDs of the rows of a table deleted before the given time
+func (s *service) ListTrashedBefore(ctx context.Context, entity any, before time.Time) ([]uint, error) {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	var ids []uint
+	err := trashed(db).Model(entity).Where("deleted_at < ?", before).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Restore takes a soft deleted row out of the trash and loads it into entity.
+// Its many-to-many associations are kept while it is in the trash, so they come back with it.
+// A versioned row comes back as a new version, copies read before it was deleted are stale.
+// A row that belongs to another row still in the trash can only be restored after that one.
+func (s *service) Restore(ctx context.Context, entity any, id uint) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(entity); err != nil {
+			return err
+		}
+		model := stmt.Schema
+
+		if err := trashed(tx).First(entity, id).Error; err != nil {
+			return err
+		}
+
+		for _, relation := range model.Relationships.BelongsTo {
+			foreignKey, zero := relation.References[0].ForeignKey.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(entity)))
+			if zero {
+				continue
+			}
+
+			var count int64
+			parent := reflect.New(relation.FieldSchema.ModelType).Interface()
+			if err := trashed(tx).Model(parent).Where("id = ?", foreignKey).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return &Error{
+					Kind:    ErrConstraint,
+					Entity:  model.Name,
+					Message: fmt.Sprintf("belongs to %s %v which is in the trash, restore it first", relation.FieldSchema.Name, foreignKey),
+				}
+			}
+		}
+
+		restored := map[string]any{"deleted_at": nil}
+		if _, ok := entity.(models.VersionedModel); ok {
+			restored["version"] = gorm.Expr("version + 1")
+		}
+		if err := tx.Unscoped().Model(entity).Updates(restored).Error; err != nil {
+			return err
+		}
+		return tx.First(entity, id).Error
+	})
+}
+
+// Purge permanently deletes a soft deleted row and its many-to-many associations.
+// Rows that still refer to it, even ones in the trash, keep it from being purged.
+// Rows owned by it are loaded into entity before they are purged with it.
+func (s *service) Purge(ctx context.Context, entity any, id uint) error {
+	db, cancel := s.withContext(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(entity); err != nil {
+			return err
+		}
+		model := stmt.Schema
+
+		load := trashed(tx)
+		for _, relation := range model.Relationships.HasMany {
+			if purgedWithOwner[model.Name+"."+relation.Name] {
+				load = load.Preload(relation.Name)
+			}
+		}
+		if err := load.First(entity, id).Error; err != nil {
+			return err
+		}
+
+		for _, relation := range model.Relationships.Many2Many {
+			if err := tx.Model(entity).Association(relation.Name).Clear(); err != nil {
+				return err
+			}
+		}
+
+		for _, relation := range slices.Concat(model.Relationships.HasOne, model.Relationships.HasMany) {
+			child := reflect.New(relation.FieldSchema.ModelType).Interface()
+			children := tx.Unscoped().Model(child).Where(relation.References[0].ForeignKey.DBName+" = ?", id)
+
+			if purgedWithOwner[model.Name+"."+relation.Name] {
+				if err := children.Delete(child).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			var count int64
+			if err := children.Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return &Error{
+					Kind:    ErrConstraint,
+					Entity:  model.Name,
+					Message: fmt.Sprintf("is still referenced by %d %s, purge them first", count, relation.FieldSchema.Table),
+				}
+			}
+		}
+
+		return tx.Unscoped().Delete(entity, id).Error
+	})
+}
+
+// trashed scopes a query to soft deleted rows
+func trashed(db *gorm.DB) *gorm.DB {
+	return db.Unscoped().Where("deleted_at IS NOT NULL")
+}
@@ -0,0 +1,224 @@
+// Package exporter writes records as CSV, NDJSON or a JSON array while they are read, so an
+// export never holds more than one record and a buffer in memory.
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of export files
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+)
+
+// MediaTypes of the formats
+var MediaTypes = map[string]string{
+	FormatCSV:    "text/csv",
+	FormatNDJSON: "application/x-ndjson",
+	FormatJSON:   "application/json",
+}
+
+// FormatOf returns the format of a media type, it is empty for any other type
+func FormatOf(mediaType string) string {
+	for format, formatType := range MediaTypes {
+		if formatType == mediaType {
+			return format
+		}
+	}
+	return ""
+}
+
+// bufferSize is how much of an export is collected before it is written out
+const bufferSize = 32 << 10
+
+// Writer writes the records of an export one at a time
+type Writer interface {
+	// Write adds a record, a struct like the record the writer was created for
+	Write(record any) error
+	// Close finishes the file, e.g. closes the JSON array, and writes out what is buffered.
+	// It does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter creates a writer of records like record in the format. The columns of a CSV
+// file are the fields of record, named by their json tag. Lists are separated by |, like
+// the importer reads them.
+func NewWriter(w io.Writer, format string, record any) (Writer, error) {
+	buffer := bufio.NewWriterSize(w, bufferSize)
+
+	switch format {
+	case FormatCSV:
+		return &csvWriter{buffer: buffer, csv: csv.NewWriter(buffer), columns: columnsOf(reflect.TypeOf(record))}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{buffer: buffer}, nil
+	case FormatJSON:
+		return &jsonWriter{buffer: buffer}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+type ndjsonWriter struct {
+	buffer *bufio.Writer
+}
+
+func (w *ndjsonWriter) Write(record any) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := w.buffer.Write(data); err != nil {
+		return err
+	}
+	return w.buffer.WriteByte('\n')
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.buffer.Flush()
+}
+
+type jsonWriter struct {
+	buffer *bufio.Writer
+	count  int
+}
+
+func (w *jsonWriter) Write(record any) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if w.count == 0 {
+		separator = "[\n"
+	}
+	w.count++
+	if _, err := w.buffer.WriteString(separator); err != nil {
+		return err
+	}
+	_, err = w.buffer.Write(data)
+	return err
+}
+
+func (w *jsonWriter) Close() error {
+	end := "\n]\n"
+	if w.count == 0 {
+		end = "[]\n"
+	}
+	if _, err := w.buffer.WriteString(end); err != nil {
+		return err
+	}
+	return w.buffer.Flush()
+}
+
+// column is a field of a record written to a CSV column
+type column struct {
+	name  string
+	index []int
+}
+
+// columnsOf lists the fields of a struct type by their json names, skipping those without one
+func columnsOf(recordType reflect.Type) []column {
+	var columns []column
+	for _, field := range reflect.VisibleFields(recordType) {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || field.Anonymous || name == "" || name == "-" {
+			continue
+		}
+		columns = append(columns, column{name: name, index: field.Index})
+	}
+	return columns
+}
+
+type csvWriter struct {
+	buffer  *bufio.Writer
+	csv     *csv.Writer
+	columns []column
+
+	wroteHeader bool
+}
+
+func (w *csvWriter) writeHeader() error {
+	w.wroteHeader = true
+
+	header := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		header[i] = column.name
+	}
+	return w.csv.Write(header)
+}
+
+func (w *csvWriter) Write(record any) error {
+	if !w.wroteHeader {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(record))
+	fields := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		fields[i] = textOf(value.FieldByIndex(column.index))
+	}
+	return w.csv.Write(fields)
+}
+
+func (w *csvWriter) Close() error {
+	if !w.wroteHeader {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	return w.buffer.Flush()
+}
+
+// textOf formats a field for a CSV column, nil is empty and lists are separated by |
+func textOf(value reflect.Value) string {
+	if t, ok := value.Interface().(time.Time); ok {
+		return t.Format(timeFormat)
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return ""
+		}
+		return textOf(value.Elem())
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, value.Type().Bits())
+	case reflect.Slice, reflect.Array:
+		items := make([]string, value.Len())
+		for i := range items {
+			items[i] = textOf(value.Index(i))
+		}
+		return strings.Join(items, "|")
+	}
+
+	data, _ := json.Marshal(value.Interface())
+	return string(data)
+}
+
+// timeFormat is how times are written to CSV, the way JSON writes them too
+const timeFormat = time.RFC3339Nano
@@ -0,0 +1,203 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	// Register the decoders for the accepted upload formats
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/buckket/go-blurhash"
+	"github.com/chai2010/webp"
+	"golang.org/x/image/draw"
+)
+
+// MaxPixels bounds the decoded size of an image, so a small file cannot expand into gigabytes of memory
+const MaxPixels = 40_000_000
+
+// ErrTooManyPixels is returned for images larger than MaxPixels
+var ErrTooManyPixels = errors.New("image dimensions are too large")
+
+// ErrNoPixels is returned for images without a width or height, there is nothing to resize
+var ErrNoPixels = errors.New("image has no pixels")
+
+// Size is a named target width for a variant
+type Size struct {
+	Name  string
+	Width int
+}
+
+// Sizes are the variants generated for every image, images are never scaled up
+var Sizes = []Size{
+	{Name: "thumbnail", Width: 160},
+	{Name: "medium", Width: 480},
+	{Name: "large", Width: 1024},
+}
+
+// Variant is an encoded, resized copy of an image
+type Variant struct {
+	Name        string
+	Format      string // jpeg, png or webp
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// Result holds everything derived from an uploaded image
+type Result struct {
+	Width         int
+	Height        int
+	DominantColor string // hex color such as #a1b2c3
+	BlurHash      string
+	Variants      []Variant
+}
+
+// Process decodes an image and generates the resized variants as PNG for images
+// with transparency or JPEG otherwise, and as WebP, together with a dominant color
+// and a blurhash placeholder.
+func Process(r io.ReadSeeker) (*Result, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The decoded frame of a GIF can be empty even where its screen is not
+	bounds := src.Bounds()
+	if bounds.Empty() {
+		return nil, ErrNoPixels
+	}
+	result := &Result{Width: bounds.Dx(), Height: bounds.Dy()}
+
+	// PNG keeps transparency, everything else is served as the smaller JPEG
+	encodeFormat := "jpeg"
+	if img, ok := src.(interface{ Opaque() bool }); ok && !img.Opaque() {
+		encodeFormat = "png"
+	}
+
+	for _, size := range Sizes {
+		resized := resize(src, size.Width)
+
+		variant, err := encode(resized, size.Name, encodeFormat)
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, variant)
+
+		webpVariant, err := encode(resized, size.Name, "webp")
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, webpVariant)
+	}
+
+	// The placeholders only need a few pixels, a small copy keeps them fast
+	small := resize(src, 64)
+	result.DominantColor = dominantColor(small)
+	result.BlurHash, err = blurhash.Encode(4, 3, small)
+	if err != nil {
+		return nil, fmt.Errorf("blurhash: %w", err)
+	}
+
+	return result, nil
+}
+
+// resize scales the image down to the given width, keeping its aspect ratio
+func resize(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	if bounds.Dx() <= width {
+		width = bounds.Dx()
+	}
+	height := max(bounds.Dy()*width/bounds.Dx(), 1)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+	return dst
+}
+
+func encode(img image.Image, name string, format string) (Variant, error) {
+	variant := Variant{Name: name, Format: format, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		variant.ContentType, variant.Extension = "image/jpeg", ".jpg"
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	case "png":
+		variant.ContentType, variant.Extension = "image/png", ".png"
+		err = png.Encode(&buf, img)
+	case "webp":
+		variant.ContentType, variant.Extension = "image/webp", ".webp"
+		err = webp.Encode(&buf, img, &webp.Options{Quality: 80})
+	default:
+		err = fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return variant, err
+	}
+
+	variant.Data = buf.Bytes()
+	return variant, nil
+}
+
+// dominantColor groups the opaque pixels into coarse color buckets and
+// returns the average color of the most common bucket.
+func dominantColor(img image.Image) string {
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	buckets := map[uint16]*bucket{}
+	var best *bucket
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < 128 {
+				continue
+			}
+
+			key := uint16(c.R>>4)<<8 | uint16(c.G>>4)<<4 | uint16(c.B>>4)
+			b, ok := buckets[key]
+			if !ok {
+				b = &bucket{}
+				buckets[key] = b
+			}
+			b.count++
+			b.r += int(c.R)
+			b.g += int(c.G)
+			b.b += int(c.B)
+
+			if best == nil || b.count > best.count {
+				best = b
+			}
+		}
+	}
+
+	if best == nil {
+		return "#000000"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", best.r/best.count, best.g/best.count, best.b/best.count)
+}
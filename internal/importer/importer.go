@@ -0,0 +1,484 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/server/utils"
+)
+
+// Actions of the audit log the changes of an import are recorded under
+const (
+	actionCreate = models.AuditActionCreate
+	actionUpdate = models.AuditActionUpdate
+)
+
+// MaxErrors bounds the rejected rows listed in a job, all of them are counted
+const MaxErrors = 1000
+
+// progressInterval is how many rows are imported between two progress reports
+const progressInterval = 100
+
+// Recorder records a change an import made in the transaction of the change, e.g. in the
+// audit log. before is nil for created entities, both are pointers to models.
+type Recorder func(ctx context.Context, tx database.Service, action string, id uint, before any, after any) error
+
+// Importer saves the rows of import files
+type Importer struct {
+	db     database.Service
+	record Recorder
+
+	// Progress is called with the job every few rows while it runs, it may be nil
+	Progress func(job *models.ImportJob)
+
+	genres map[string]*models.Genre
+}
+
+// New creates an importer that records its changes with record, which may be nil
+func New(db database.Service, record Recorder) *Importer {
+	return &Importer{db: db, record: record, genres: map[string]*models.Genre{}}
+}
+
+// rowErrors are the problems found with the fields of a row
+type rowErrors []models.ImportRowError
+
+func (errs rowErrors) Error() string {
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, strings.TrimSpace(err.Field+" "+err.Message))
+	}
+	return strings.Join(messages, ", ")
+}
+
+func (errs *rowErrors) add(field string, message string) {
+	*errs = append(*errs, models.ImportRowError{Field: field, Message: message})
+}
+
+// Run imports the rows into the entity of the job and counts in the job how each went. Every
+// row is saved in a transaction of its own, a rejected row is listed in the errors of the job
+// and leaves the other rows alone. A dry run checks every row the same way without saving.
+// Run stops early only when ctx ends.
+func (im *Importer) Run(ctx context.Context, job *models.ImportJob, rows []Row) error {
+	job.Total = len(rows)
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var action string
+		var err error
+		if len(row.Problems) > 0 {
+			err = rowErrors(row.Problems)
+		} else if job.DryRun {
+			action, err = im.importRow(ctx, im.db, job.Entity, row, true)
+		} else {
+			err = im.db.WithTx(ctx, func(tx database.Service) error {
+				action, err = im.importRow(ctx, tx, job.Entity, row, false)
+				return err
+			})
+		}
+
+		job.Processed++
+		switch {
+		case err != nil:
+			job.Failed++
+			im.addErrors(job, row, err)
+		case action == actionCreate:
+			job.Created++
+		case action == actionUpdate:
+			job.Updated++
+		default:
+			job.Unchanged++
+		}
+
+		if im.Progress != nil && (i+1)%progressInterval == 0 {
+			im.Progress(job)
+		}
+	}
+	return nil
+}
+
+// addErrors lists why a row was rejected in the job
+func (im *Importer) addErrors(job *models.ImportJob, row Row, err error) {
+	var fieldErrs rowErrors
+	if !errors.As(err, &fieldErrs) {
+		fieldErrs = rowErrors{{Message: err.Error()}}
+	}
+	for _, fieldErr := range fieldErrs {
+		if len(job.Errors) >= MaxErrors {
+			return
+		}
+		fieldErr.Row = row.Line
+		job.Errors = append(job.Errors, fieldErr)
+	}
+}
+
+// importRow saves a row and returns the action it took, empty when nothing changed
+func (im *Importer) importRow(ctx context.Context, db database.Service, entity string, row Row, dryRun bool) (string, error) {
+	switch entity {
+	case EntityBooks:
+		return im.importBook(ctx, db, row, dryRun)
+	case EntityAuthors:
+		first, last, err := personName(row.Values, "first_name", "last_name", "name")
+		if err != nil {
+			return "", err
+		}
+		_, action, err := im.findOrCreateAuthor(ctx, db, first, last, dryRun)
+		return action, err
+	case EntityArtists:
+		first, last, err := personName(row.Values, "first_name", "last_name", "name")
+		if err != nil {
+			return "", err
+		}
+		return im.findOrCreateArtist(ctx, db, first, last, dryRun)
+	}
+	return "", errors.New("cannot import " + entity)
+}
+
+// importBook creates the book of a row or updates the book with its ISBN
+func (im *Importer) importBook(ctx context.Context, db database.Service, row Row, dryRun bool) (string, error) {
+	values := row.Values
+	var errs rowErrors
+
+	isbn, ok := NormalizeISBN(values["isbn"])
+	if values["isbn"] == "" {
+		errs.add("isbn", "is required")
+	} else if !ok {
+		errs.add("isbn", "must be a valid ISBN-10 or ISBN-13")
+	}
+
+	var existing *models.Book
+	if isbn != "" {
+		var err error
+		existing, err = db.FindBookByISBN(ctx, isbn)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return "", err
+		}
+	}
+
+	// Books are created with every field, only the given ones change on an update
+	if existing == nil {
+		for _, field := range []string{"title", "published_date", "pages", "description", "price"} {
+			if values[field] == "" {
+				errs.add(field, "is required")
+			}
+		}
+		if values["author"] == "" && values["author_first_name"] == "" && values["author_last_name"] == "" {
+			errs.add("author", "is required")
+		}
+	}
+
+	// An existing book keeps its ISBN as it was written
+	book := models.Book{ISBN: isbn}
+	if existing != nil {
+		book = *existing
+	}
+	if value, ok := values["title"]; ok {
+		book.Title = value
+	}
+	if value, ok := values["description"]; ok {
+		book.Description = value
+	}
+	if value, ok := values["published_date"]; ok {
+		date, err := parseDate(value)
+		if err != nil {
+			errs.add("published_date", "must be a date as YYYY-MM-DD or a year")
+		}
+		// A year alone, as MARC records give it, keeps the day of a book published that year
+		if len(value) == 4 && existing != nil && existing.PublishedDate.Year() == date.Year() {
+			date = existing.PublishedDate
+		}
+		book.PublishedDate = date
+	}
+	if value, ok := values["pages"]; ok {
+		pages, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			errs.add("pages", "must be a whole number")
+		}
+		book.Pages = uint(pages)
+	}
+	if value, ok := values["price"]; ok {
+		price, err := strconv.ParseFloat(value, 32)
+		if err != nil || price < 0 {
+			errs.add("price", "must be a number of at least 0")
+		}
+		book.Price = float32(price)
+	}
+	if value, ok := values["digital_only"]; ok {
+		digitalOnly, err := strconv.ParseBool(strings.ToLower(value))
+		if err != nil {
+			errs.add("digital_only", "must be true or false")
+		}
+		book.DigitalOnly = digitalOnly
+	}
+
+	var authorFirst, authorLast string
+	if values["author"] != "" || values["author_first_name"] != "" || values["author_last_name"] != "" {
+		var nameErrs rowErrors
+		var err error
+		authorFirst, authorLast, err = personName(values, "author_first_name", "author_last_name", "author")
+		if errors.As(err, &nameErrs) {
+			errs = append(errs, nameErrs...)
+		}
+	}
+
+	if value, ok := values["genres"]; ok {
+		genres, err := im.findGenres(ctx, db, value)
+		if err != nil {
+			var fieldErrs rowErrors
+			if !errors.As(err, &fieldErrs) {
+				return "", err
+			}
+			errs = append(errs, fieldErrs...)
+		}
+		book.Genres = genres
+	}
+
+	if value, ok := values["cover_image_url"]; ok {
+		if link, err := url.Parse(value); err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+			errs.add("cover_image_url", "must be an http or https URL")
+		}
+	}
+
+	if len(errs) > 0 {
+		return "", errs
+	}
+
+	if authorFirst != "" {
+		author, _, err := im.findOrCreateAuthor(ctx, db, authorFirst, authorLast, dryRun)
+		if err != nil {
+			return "", err
+		}
+		book.AuthorID = author.ID
+	}
+
+	var action string
+	switch {
+	case existing == nil:
+		action = actionCreate
+		if dryRun {
+			break
+		}
+		if err := db.Create(ctx, &book); err != nil {
+			return "", err
+		}
+		if err := im.recordChange(ctx, db, actionCreate, book.ID, nil, &book); err != nil {
+			return "", err
+		}
+
+	case !sameBook(*existing, book):
+		action = actionUpdate
+		if dryRun {
+			break
+		}
+		if err := db.Update(ctx, &book); err != nil {
+			return "", err
+		}
+		if _, ok := values["genres"]; ok {
+			if err := db.ReplaceAssociation(ctx, &book, "Genres", book.Genres); err != nil {
+				return "", err
+			}
+		}
+		if err := im.recordChange(ctx, db, actionUpdate, book.ID, existing, &book); err != nil {
+			return "", err
+		}
+	}
+
+	if value, ok := values["cover_image_url"]; ok {
+		changed, err := im.importCover(ctx, db, &book, value, dryRun)
+		if err != nil {
+			return "", err
+		}
+		if changed && action == "" {
+			action = actionUpdate
+		}
+	}
+	return action, nil
+}
+
+// importCover links the image at imageURL to the cover of a book, creating the cover when
+// the book has none. An uploaded image is kept, the link only applies to covers without one.
+// It reports whether the cover changed.
+func (im *Importer) importCover(ctx context.Context, db database.Service, book *models.Book, imageURL string, dryRun bool) (bool, error) {
+	var cover models.Cover
+	if book.ID != 0 {
+		if err := db.FindAssociation(ctx, book, "Cover", &cover); err != nil {
+			return false, err
+		}
+	}
+
+	if cover.ID == 0 {
+		if dryRun {
+			return true, nil
+		}
+		cover = models.Cover{BookID: book.ID, ImageURL: sql.NullString{String: imageURL, Valid: true}}
+		if err := db.Create(ctx, &cover); err != nil {
+			return false, err
+		}
+		return true, im.recordChange(ctx, db, actionCreate, cover.ID, nil, &cover)
+	}
+
+	if cover.ImageKey != "" || cover.ImageURL.String == imageURL {
+		return false, nil
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	// The artists are part of the revisions of a cover
+	if err := db.FindAssociation(ctx, &cover, "Artists", &cover.Artists); err != nil {
+		return false, err
+	}
+	before := cover
+	cover.ImageURL = sql.NullString{String: imageURL, Valid: true}
+	if err := db.Update(ctx, &cover); err != nil {
+		return false, err
+	}
+	return true, im.recordChange(ctx, db, actionUpdate, cover.ID, &before, &cover)
+}
+
+// sameBook reports whether an update leaves the imported fields of a book as they were
+func sameBook(a models.Book, b models.Book) bool {
+	genreIDs := func(book models.Book) []uint {
+		ids := []uint{}
+		for _, genre := range book.Genres {
+			ids = append(ids, genre.ID)
+		}
+		return ids
+	}
+
+	return a.Title == b.Title && a.PublishedDate.Equal(b.PublishedDate) && a.Pages == b.Pages &&
+		a.Description == b.Description && a.DigitalOnly == b.DigitalOnly && a.ISBN == b.ISBN &&
+		a.Price == b.Price && a.AuthorID == b.AuthorID && reflect.DeepEqual(genreIDs(a), genreIDs(b))
+}
+
+// findOrCreateAuthor returns the author with the name, creating it unless it is a dry run
+func (im *Importer) findOrCreateAuthor(ctx context.Context, db database.Service, first string, last string, dryRun bool) (*models.Author, string, error) {
+	author, err := db.FindAuthorByName(ctx, first, last)
+	if err == nil {
+		return author, "", nil
+	}
+	if !errors.Is(err, database.ErrNotFound) {
+		return nil, "", err
+	}
+
+	author = &models.Author{FirstName: first, LastName: last}
+	if dryRun {
+		return author, actionCreate, nil
+	}
+	if err := db.Create(ctx, author); err != nil {
+		return nil, "", err
+	}
+	return author, actionCreate, im.recordChange(ctx, db, actionCreate, author.ID, nil, author)
+}
+
+// findOrCreateArtist creates the artist with the name unless there is one
+func (im *Importer) findOrCreateArtist(ctx context.Context, db database.Service, first string, last string, dryRun bool) (string, error) {
+	_, err := db.FindArtistByName(ctx, first, last)
+	if err == nil {
+		return "", nil
+	}
+	if !errors.Is(err, database.ErrNotFound) {
+		return "", err
+	}
+
+	if dryRun {
+		return actionCreate, nil
+	}
+	artist := &models.Artist{FirstName: first, LastName: last}
+	if err := db.Create(ctx, artist); err != nil {
+		return "", err
+	}
+	return actionCreate, im.recordChange(ctx, db, actionCreate, artist.ID, nil, artist)
+}
+
+// findGenres looks up the genres of a list of slugs or names separated by | or ;
+func (im *Importer) findGenres(ctx context.Context, db database.Service, value string) ([]*models.Genre, error) {
+	genres := []*models.Genre{}
+	var missing []string
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == '|' || r == ';' }) {
+		slug := utils.Slugify(name)
+		if slug == "" {
+			continue
+		}
+
+		genre, ok := im.genres[slug]
+		if !ok {
+			var err error
+			genre, err = db.GetGenreBySlug(ctx, slug)
+			if errors.Is(err, database.ErrNotFound) {
+				missing = append(missing, strings.TrimSpace(name))
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			im.genres[slug] = genre
+		}
+		genres = append(genres, genre)
+	}
+
+	if len(missing) > 0 {
+		return nil, rowErrors{{Field: "genres", Message: "has unknown genres: " + strings.Join(missing, ", ")}}
+	}
+	return genres, nil
+}
+
+func (im *Importer) recordChange(ctx context.Context, tx database.Service, action string, id uint, before any, after any) error {
+	if im.record == nil {
+		return nil
+	}
+	return im.record(ctx, tx, action, id, before, after)
+}
+
+// personName reads a first and last name from their fields or from a full name as
+// "First Last" or "Last, First", where everything before the last word is the first name
+func personName(values map[string]string, firstField string, lastField string, nameField string) (string, string, error) {
+	first, last := values[firstField], values[lastField]
+	if first == "" && last == "" {
+		name := values[nameField]
+		if before, after, ok := strings.Cut(name, ","); ok {
+			first, last = strings.TrimSpace(after), strings.TrimSpace(before)
+		} else if i := strings.LastIndex(name, " "); i > 0 {
+			first, last = strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+1:])
+		}
+		if first == "" || last == "" {
+			if name == "" {
+				return "", "", rowErrors{{Field: nameField, Message: "is required"}}
+			}
+			return "", "", rowErrors{{Field: nameField, Message: "must have a first and a last name"}}
+		}
+		return first, last, nil
+	}
+
+	var errs rowErrors
+	if first == "" {
+		errs.add(firstField, "is required")
+	}
+	if last == "" {
+		errs.add(lastField, "is required")
+	}
+	if len(errs) > 0 {
+		return "", "", errs
+	}
+	return first, last, nil
+}
+
+// parseDate reads a date as YYYY-MM-DD, as an RFC 3339 time or a year as its first day
+func parseDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, nil
+	}
+	if len(value) == 4 {
+		return time.Parse("2006", value)
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,39 @@
+package importer
+
+import "strings"
+
+// NormalizeISBN strips the hyphens and spaces of an ISBN-10 or ISBN-13 and checks its check
+// digit. It reports false for anything else.
+func NormalizeISBN(value string) (string, bool) {
+	isbn := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(value))
+
+	switch len(isbn) {
+	case 10:
+		sum := 0
+		for i, char := range isbn {
+			digit := int(char - '0')
+			if char == 'X' && i == 9 {
+				digit = 10
+			} else if char < '0' || char > '9' {
+				return "", false
+			}
+			sum += digit * (10 - i)
+		}
+		return isbn, sum%11 == 0
+
+	case 13:
+		sum := 0
+		for i, char := range isbn {
+			if char < '0' || char > '9' {
+				return "", false
+			}
+			digit := int(char - '0')
+			if i%2 == 1 {
+				digit *= 3
+			}
+			sum += digit
+		}
+		return isbn, sum%10 == 0
+	}
+	return "", false
+}
@@ -0,0 +1,32 @@
+package importer
+
+import "testing"
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		value string
+		isbn  string
+		ok    bool
+	}{
+		{"978-0-441-47812-5", "9780441478125", true},
+		{"978 0 441 47812 5", "9780441478125", true},
+		{"0-441-47812-3", "0441478123", true},
+		{"0-8044-2957-x", "080442957X", true},
+		{"978-0-441-47812-4", "9780441478124", false},
+		{"0-441-47812-4", "0441478124", false},
+		{"X-441-47812-3", "", false},
+		{"978044147812X", "", false},
+		{"97804414781２5", "", false},
+		{"044147812", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			isbn, ok := NormalizeISBN(tt.value)
+			if isbn != tt.isbn || ok != tt.ok {
+				t.Errorf("NormalizeISBN(%q) = %q, %v, want %q, %v", tt.value, isbn, ok, tt.isbn, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go-playground/internal/database/models"
+	"go-playground/internal/marc"
+)
+
+// marcReader is the reader of binary MARC or the decoder of MARCXML
+type marcReader interface {
+	Next() (*marc.Record, int, error)
+}
+
+// readMARC turns the records of a binary MARC or MARCXML file into rows of books
+func readMARC(r io.Reader, format string) ([]Row, error) {
+	var reader marcReader = marc.NewDecoder(r)
+	if format == FormatMARC {
+		reader = marc.NewReader(r)
+	}
+
+	var rows []Row
+	for {
+		record, position, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var marcErr *marc.Error
+			if errors.As(err, &marcErr) {
+				if marcErr.Line > 0 {
+					return nil, &FileError{Line: marcErr.Line, Message: marcErr.Message}
+				}
+				return nil, &FileError{Message: marcErr.Error()}
+			}
+			return nil, err
+		}
+		rows = append(rows, recordRow(record, position, format == FormatMARCXML))
+	}
+
+	if len(rows) == 0 {
+		return nil, &FileError{Message: "the file holds no records"}
+	}
+	return rows, nil
+}
+
+var (
+	// year finds the year of a publication statement such as "[2019]" or "c2019"
+	year = regexp.MustCompile(`\d{4}`)
+	// pageCount finds the pages of a physical description such as "xii, 412 p." or "1 volume (412 pages)"
+	pageCount = regexp.MustCompile(`(\d+)\s*(?:p\b|pages?\b)`)
+	// amount finds the price in the terms of availability such as "USD 12.50" or "£9,99"
+	amount = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+)
+
+// recordRow maps a MARC 21 bibliographic record onto the fields of a book: the ISBN and price
+// of the first 020 with an ISBN, the title and remainder of title of 245, the main entry
+// (100) or else the first added entry (700) as the author, the year of publication of 264,
+// 260 or 008, the pages of 300, the summaries of 520, whether it is an online resource from
+// 338 or 008 and the cover image of the 856 labelled as one. Binary records are numbered
+// instead of given a line. MARCXML is always read as UTF-8, whatever its leader says.
+func recordRow(record *marc.Record, position int, xml bool) Row {
+	row := Row{Line: position, Values: map[string]string{}}
+	set := func(field string, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			row.Values[field] = value
+		}
+	}
+
+	if !xml && !record.UTF8() && !record.ASCII() {
+		row.Problems = append(row.Problems, models.ImportRowError{Field: "Leader/09", Message: "is MARC-8 with characters beyond ASCII, only UTF-8 records are imported"})
+		return row
+	}
+	if len(record.Leader) > 6 {
+		if record.Leader[5] == 'd' {
+			row.Problems = append(row.Problems, models.ImportRowError{Field: "Leader/05", Message: "deletes the record, deletions are not imported"})
+			return row
+		}
+		if record.Leader[6] != 'a' && record.Leader[6] != 't' {
+			row.Problems = append(row.Problems, models.ImportRowError{Field: "Leader/06", Message: "is not a record of language material, only books are imported"})
+			return row
+		}
+	}
+
+	for _, field := range record.Fields("020") {
+		// The ISBN may be followed by a qualifier, as in "9780441013593 (paperback)"
+		if isbn, _, _ := strings.Cut(strings.TrimSpace(field.Subfield("a")), " "); isbn != "" {
+			set("isbn", isbn)
+			set("price", strings.ReplaceAll(amount.FindString(field.Subfield("c")), ",", "."))
+			break
+		}
+	}
+
+	if titles := record.Fields("245"); len(titles) > 0 {
+		title := marc.TrimPunctuation(titles[0].Subfield("a"))
+		if remainder := marc.TrimPunctuation(titles[0].Subfield("b")); remainder != "" {
+			title += ": " + remainder
+		}
+		set("title", title)
+	}
+
+	authors := record.Fields("100")
+	if len(authors) == 0 {
+		authors = record.Fields("700")
+	}
+	if len(authors) > 0 {
+		set("author", marc.TrimPunctuation(authors[0].Subfield("a")))
+	}
+
+	var published string
+	for _, field := range record.Fields("264") {
+		if field.Ind2 == "1" && published == "" {
+			published = year.FindString(field.Subfield("c"))
+		}
+	}
+	for _, field := range record.Fields("260") {
+		if published == "" {
+			published = year.FindString(field.Subfield("c"))
+		}
+	}
+	fixed := record.Control("008")
+	if published == "" && len(fixed) >= 11 {
+		published = year.FindString(fixed[7:11])
+	}
+	set("published_date", published)
+
+	for _, field := range record.Fields("300") {
+		if matches := pageCount.FindAllStringSubmatch(field.Subfield("a"), -1); len(matches) > 0 {
+			set("pages", matches[len(matches)-1][1])
+			break
+		}
+	}
+
+	var summaries []string
+	for _, field := range record.Fields("520") {
+		if summary := strings.TrimSpace(field.Subfield("a")); summary != "" {
+			summaries = append(summaries, summary)
+		}
+	}
+	set("description", strings.Join(summaries, "\n"))
+
+	if carriers := record.Fields("338"); len(carriers) > 0 {
+		online := carriers[0].Subfield("b") == "cr" || strings.EqualFold(carriers[0].Subfield("a"), "online resource")
+		set("digital_only", strconv.FormatBool(online))
+	} else if len(fixed) >= 24 {
+		set("digital_only", strconv.FormatBool(strings.ContainsRune("oqs", rune(fixed[23]))))
+	}
+
+	for _, field := range record.Fields("856") {
+		if strings.Contains(strings.ToLower(field.Subfield("3")), "cover") {
+			set("cover_image_url", field.Subfield("u"))
+			break
+		}
+	}
+
+	return row
+}
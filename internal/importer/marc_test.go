@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"go-playground/internal/database/models"
+	"go-playground/internal/marc"
+)
+
+func TestReadRejectsMalformedMARC(t *testing.T) {
+	testMalformedFiles(t, []malformedFile{
+		{"MARC of artists", FormatMARC, EntityArtists, nil, "", 0, "import them as books"},
+		{"MARC with a mapping", FormatMARCXML, EntityBooks, map[string]string{"245": "title"}, "", 0, "cannot be mapped"},
+		{"empty MARC", FormatMARC, EntityBooks, nil, "", 0, "holds no MARC records"},
+		{"text as MARC", FormatMARC, EntityBooks, nil, "isbn,title\n", 0, "not binary MARC"},
+		{"MARCXML without records", FormatMARCXML, EntityBooks, nil, `<collection xmlns="http://www.loc.gov/MARC21/slim"/>`, 0, "holds no records"},
+		{"malformed MARCXML", FormatMARCXML, EntityBooks, nil, "<collection>\n<record></datafield>", 2, "element <record> closed by </datafield>"},
+	})
+}
+
+// writeMARC exports records in the format the way the catalog does
+func writeMARC(t *testing.T, format string, records ...*marc.Record) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := marc.NewWriter(&buf, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadMARC(t *testing.T) {
+	want := map[string]string{
+		"isbn":            "9780441478125",
+		"title":           "The Left Hand of Darkness",
+		"author":          "Le Guin, Ursula K.",
+		"published_date":  "1969",
+		"pages":           "304",
+		"description":     "A lone envoy is sent to Gethen.",
+		"digital_only":    "false",
+		"price":           "9.99",
+		"cover_image_url": "https://example.com/covers/12.jpg",
+	}
+
+	for _, format := range []string{FormatMARC, FormatMARCXML} {
+		t.Run(format, func(t *testing.T) {
+			marcFormat := marc.FormatBinary
+			if format == FormatMARCXML {
+				marcFormat = marc.FormatXML
+			}
+			rows, err := Read(bytes.NewReader(writeMARC(t, marcFormat, marc.RecordOf(testBook()))), format, EntityBooks, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("read %d rows, want 1", len(rows))
+			}
+			if !reflect.DeepEqual(rows[0].Values, want) || len(rows[0].Problems) > 0 {
+				t.Errorf("read book %v with problems %v, want %v", rows[0].Values, rows[0].Problems, want)
+			}
+		})
+	}
+}
+
+func TestRecordRow(t *testing.T) {
+	withLeader := func(position int, code byte) *marc.Record {
+		record := marc.RecordOf(testBook())
+		leader := []byte(record.Leader)
+		leader[position] = code
+		record.Leader = string(leader)
+		return record
+	}
+
+	// Records of MARC-8 are left alone unless they are plain ASCII
+	translated := testBook()
+	translated.Title = "Die linke Hand der Dunkelheit: Roman über Gethen"
+	marc8 := marc.RecordOf(translated)
+	marc8.Leader = withLeader(9, ' ').Leader
+	ascii := marc.RecordOf(&models.Book{Title: "Plain", ISBN: "0441478123"})
+	ascii.Leader = marc8.Leader
+
+	// A record of the 1990s without the fields of RDA
+	older := &marc.Record{Leader: "00000cam a2200000 a 4500"}
+	older.AddControl("008", "950101s1995    nyu           000 1 eng d")
+	older.AddField("020", " ", " ", "a", "0441478123 (pbk.)", "c", "£5,99")
+	older.AddField("245", "1", "4", "a", "The dispossessed :", "b", "an ambiguous utopia /")
+	older.AddField("260", " ", " ", "c", "c1974.")
+	older.AddField("300", " ", " ", "a", "vi, 341 p. ;", "c", "18 cm.")
+	older.AddField("700", "1", " ", "a", "Le Guin, Ursula K.,")
+	older.AddField("520", " ", " ", "a", "First part.")
+	older.AddField("520", " ", " ", "a", "Second part.")
+
+	online := &marc.Record{Leader: "00000nam a2200000 i 4500"}
+	online.AddControl("008", "950101s1995    nyu     o     000 1 eng d")
+
+	tests := []struct {
+		name    string
+		record  *marc.Record
+		xml     bool
+		values  map[string]string
+		problem string
+	}{
+		{"MARC-8", marc8, false, nil, "Leader/09"},
+		{"MARC-8 in MARCXML", marc8, true, nil, ""},
+		{"MARC-8 of ASCII", ascii, false, nil, ""},
+		{"deleted", withLeader(5, 'd'), false, nil, "Leader/05"},
+		{"map", withLeader(6, 'e'), false, nil, "Leader/06"},
+		{"manuscript", withLeader(6, 't'), false, nil, ""},
+		{"older record", older, false, map[string]string{
+			"isbn":           "0441478123",
+			"price":          "5.99",
+			"title":          "The dispossessed: an ambiguous utopia",
+			"author":         "Le Guin, Ursula K.",
+			"published_date": "1974",
+			"pages":          "341",
+			"description":    "First part.\nSecond part.",
+			"digital_only":   "false",
+		}, ""},
+		{"online", online, false, map[string]string{"published_date": "1995", "digital_only": "true"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := recordRow(tt.record, 7, tt.xml)
+			if row.Line != 7 {
+				t.Errorf("row is on line %d, want 7", row.Line)
+			}
+			if tt.problem != "" {
+				if len(row.Problems) != 1 || row.Problems[0].Field != tt.problem {
+					t.Errorf("read problems %v, want one with %s", row.Problems, tt.problem)
+				}
+				return
+			}
+			if len(row.Problems) > 0 {
+				t.Fatalf("read problems %v", row.Problems)
+			}
+			if tt.values != nil && !reflect.DeepEqual(row.Values, tt.values) {
+				t.Errorf("read book %v, want %v", row.Values, tt.values)
+			}
+		})
+	}
+}
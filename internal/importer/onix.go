@@ -0,0 +1,224 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"go-playground/internal/database/models"
+	"go-playground/internal/onix"
+)
+
+// readONIX turns the products of an ONIX 3.0 message into rows of books
+func readONIX(r io.Reader) ([]Row, error) {
+	decoder := onix.NewDecoder(r)
+
+	var rows []Row
+	for {
+		product, line, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var onixErr *onix.Error
+			if errors.As(err, &onixErr) {
+				return nil, &FileError{Line: onixErr.Line, Message: onixErr.Message}
+			}
+			return nil, err
+		}
+		rows = append(rows, productRow(product, line))
+	}
+
+	if len(rows) == 0 {
+		return nil, &FileError{Message: "the message holds no products"}
+	}
+	return rows, nil
+}
+
+// productRow maps an ONIX product onto the fields of a book. The first author by sequence
+// number becomes the author of the book, the front cover image its cover and the
+// recommended retail price in the currency of the catalog its price.
+func productRow(product *onix.Product, line int) Row {
+	row := Row{Line: line, Values: map[string]string{}}
+	set := func(field string, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			row.Values[field] = value
+		}
+	}
+
+	if product.NotificationType == onix.NotificationDelete {
+		row.Problems = append(row.Problems, models.ImportRowError{Field: "NotificationType", Message: "deletes the product, deletions are not imported"})
+		return row
+	}
+
+	for _, idType := range []string{onix.ProductIDISBN13, onix.ProductIDISBN10} {
+		if id := productID(product, idType); id != "" {
+			set("isbn", id)
+			break
+		}
+	}
+
+	detail := product.DescriptiveDetail
+	if detail.ProductForm != "" {
+		// Forms starting with E are digital and online products
+		set("digital_only", strconv.FormatBool(strings.HasPrefix(detail.ProductForm, "E")))
+	}
+
+	for _, title := range detail.TitleDetails {
+		if title.TitleType != onix.TitleDistinctive {
+			continue
+		}
+		for _, element := range title.TitleElements {
+			if element.TitleElementLevel == onix.TitleLevelProduct {
+				set("title", element.Text())
+			}
+		}
+	}
+
+	var author *onix.Contributor
+	for i, contributor := range detail.Contributors {
+		if contributor.HasRole(onix.RoleAuthor) && (author == nil || contributor.SequenceNumber < author.SequenceNumber) {
+			author = &detail.Contributors[i]
+		}
+	}
+	if author != nil {
+		switch {
+		case author.KeyNames != "":
+			set("author_first_name", author.NamesBeforeKey)
+			set("author_last_name", author.KeyNames)
+		case author.PersonNameInverted != "":
+			set("author", author.PersonNameInverted)
+		default:
+			set("author", author.PersonName)
+		}
+	}
+
+	for _, extentType := range []string{onix.ExtentMainContentPages, onix.ExtentTotalPages} {
+		if pages := extent(product, extentType); pages != "" {
+			set("pages", pages)
+			break
+		}
+	}
+
+	var genres []string
+	for _, subject := range detail.Subjects {
+		if subject.SubjectSchemeIdentifier == onix.SubjectSchemeProprietary && subject.SubjectSchemeName == onix.GenreScheme {
+			genres = append(genres, subject.SubjectCode)
+		}
+	}
+	set("genres", strings.Join(genres, "|"))
+
+	if collateral := product.CollateralDetail; collateral != nil {
+		for _, textType := range []string{onix.TextDescription, onix.TextShortDescription} {
+			if text := textContent(collateral, textType); text != "" {
+				set("description", text)
+				break
+			}
+		}
+		set("cover_image_url", frontCover(collateral))
+	}
+
+	if publishing := product.PublishingDetail; publishing != nil {
+		for _, date := range publishing.PublishingDates {
+			if date.PublishingDateRole == onix.DatePublication {
+				set("published_date", onixDate(date.Date))
+			}
+		}
+	}
+
+	price, ok := retailPrice(product, onix.Currency())
+	if !ok {
+		row.Problems = append(row.Problems, models.ImportRowError{Field: "Price", Message: "has no price in " + onix.Currency()})
+	}
+	set("price", price)
+
+	return row
+}
+
+func productID(product *onix.Product, idType string) string {
+	for _, id := range product.ProductIdentifiers {
+		if id.ProductIDType == idType {
+			return id.IDValue
+		}
+	}
+	return ""
+}
+
+func extent(product *onix.Product, extentType string) string {
+	for _, extent := range product.DescriptiveDetail.Extents {
+		if extent.ExtentType == extentType && extent.ExtentUnit == onix.ExtentUnitPages {
+			return extent.ExtentValue
+		}
+	}
+	return ""
+}
+
+func textContent(collateral *onix.CollateralDetail, textType string) string {
+	for _, text := range collateral.TextContents {
+		if text.TextType == textType {
+			return text.Text.Value
+		}
+	}
+	return ""
+}
+
+// frontCover finds the link of the front cover image, a file to download rather than a page
+// showing it when there are both
+func frontCover(collateral *onix.CollateralDetail) string {
+	link := ""
+	for _, resource := range collateral.SupportingResources {
+		if resource.ResourceContentType != onix.ResourceFrontCover || resource.ResourceMode != onix.ResourceModeImage {
+			continue
+		}
+		for _, version := range resource.ResourceVersions {
+			switch version.ResourceForm {
+			case onix.ResourceFormFile:
+				return version.ResourceLink
+			case onix.ResourceFormLinkable:
+				if link == "" {
+					link = version.ResourceLink
+				}
+			}
+		}
+	}
+	return link
+}
+
+// onixDate writes a date of ONIX as YYYY-MM-DD, months and years as their first day. Dates it
+// cannot read are kept as they are, so the book reports them.
+func onixDate(date onix.Date) string {
+	value := strings.TrimSpace(date.Value)
+	switch {
+	case (date.Format == "" || date.Format == onix.DateFormatYYYYMMDD) && len(value) == 8:
+		return value[:4] + "-" + value[4:6] + "-" + value[6:]
+	case date.Format == onix.DateFormatYYYYMM && len(value) == 6:
+		return value[:4] + "-" + value[4:] + "-01"
+	case date.Format == onix.DateFormatYYYY && len(value) == 4:
+		return value + "-01-01"
+	}
+	return value
+}
+
+// retailPrice picks the recommended retail price in the currency, with tax if there is one. It
+// reports false when the product has prices but none in the currency.
+func retailPrice(product *onix.Product, currency string) (string, bool) {
+	var prices []onix.Price
+	for _, supply := range product.ProductSupplies {
+		for _, detail := range supply.SupplyDetails {
+			prices = append(prices, detail.Prices...)
+		}
+	}
+	if len(prices) == 0 {
+		return "", true
+	}
+
+	for _, priceType := range []string{onix.PriceRRPIncludingTax, onix.PriceRRPExcludingTax, ""} {
+		for _, price := range prices {
+			if strings.EqualFold(price.CurrencyCode, currency) && (priceType == "" || price.PriceType == priceType) {
+				return price.PriceAmount, true
+			}
+		}
+	}
+	return "", false
+}
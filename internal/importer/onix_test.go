@@ -0,0 +1,181 @@
+package importer
+
+import (
+	"bytes"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go-playground/internal/database/models"
+	"go-playground/internal/onix"
+
+	"gorm.io/gorm"
+)
+
+func testBook() *models.Book {
+	return &models.Book{
+		Model:         gorm.Model{ID: 12},
+		Title:         "The Left Hand of Darkness",
+		PublishedDate: time.Date(1969, 3, 1, 0, 0, 0, 0, time.UTC),
+		Pages:         304,
+		Description:   "A lone envoy is sent to Gethen.",
+		ISBN:          "978-0-441-47812-5",
+		Price:         9.99,
+		Author:        models.Author{FirstName: "Ursula K.", LastName: "Le Guin"},
+		Cover:         models.Cover{ImageURL: sql.NullString{String: "https://example.com/covers/12.jpg", Valid: true}},
+		Genres:        []*models.Genre{{Name: "Science Fiction", Slug: "science-fiction"}, {Name: "Classics", Slug: "classics"}},
+	}
+}
+
+func TestReadRejectsMalformedONIX(t *testing.T) {
+	testMalformedFiles(t, []malformedFile{
+		{"ONIX of authors", FormatONIX, EntityAuthors, nil, "", 0, "import them as books"},
+		{"ONIX with a mapping", FormatONIX, EntityBooks, map[string]string{"Title": "title"}, "", 0, "cannot be mapped"},
+		{"ONIX without products", FormatONIX, EntityBooks, nil, `<ONIXMessage release="3.0"><Header/></ONIXMessage>`, 0, "holds no products"},
+		{"ONIX 2.1", FormatONIX, EntityBooks, nil, `<ONIXMessage release="2.1"><Product/></ONIXMessage>`, 0, "only ONIX 3.0 is supported"},
+		{"truncated ONIX", FormatONIX, EntityBooks, nil, "<ONIXMessage release=\"3.0\">\n<Product>", 2, "unexpected EOF"},
+	})
+}
+
+// writeONIX exports books as an ONIX message the way the catalog does
+func writeONIX(t *testing.T, products ...*onix.Product) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := onix.NewWriter(&buf, onix.Header{Sender: onix.Sender{SenderName: "Playground"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, product := range products {
+		if err := w.Write(product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestReadONIX(t *testing.T) {
+	t.Setenv("ONIX_CURRENCY", "EUR")
+
+	digital := testBook()
+	digital.ISBN = "0-441-47812-3"
+	digital.DigitalOnly = true
+	digital.Author = models.Author{}
+	digital.Cover = models.Cover{}
+	digital.Genres = nil
+
+	deleted := onix.ProductOf(testBook(), "Ace Books", "EUR")
+	deleted.NotificationType = onix.NotificationDelete
+
+	data := writeONIX(t,
+		onix.ProductOf(testBook(), "Ace Books", "EUR"),
+		onix.ProductOf(digital, "Ace Books", "EUR"),
+		onix.ProductOf(testBook(), "Ace Books", "USD"),
+		deleted,
+	)
+	rows, err := Read(strings.NewReader(data), FormatONIX, EntityBooks, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("read %d rows, want 4", len(rows))
+	}
+
+	// An exported book reads back with every field the importer sets
+	want := map[string]string{
+		"isbn":              "9780441478125",
+		"title":             "The Left Hand of Darkness",
+		"author_first_name": "Ursula K.",
+		"author_last_name":  "Le Guin",
+		"published_date":    "1969-03-01",
+		"pages":             "304",
+		"description":       "A lone envoy is sent to Gethen.",
+		"digital_only":      "false",
+		"price":             "9.99",
+		"genres":            "science-fiction|classics",
+		"cover_image_url":   "https://example.com/covers/12.jpg",
+	}
+	if !reflect.DeepEqual(rows[0].Values, want) || len(rows[0].Problems) > 0 {
+		t.Errorf("read book %v with problems %v, want %v", rows[0].Values, rows[0].Problems, want)
+	}
+	if rows[0].Line >= rows[1].Line {
+		t.Errorf("products start on lines %d and %d", rows[0].Line, rows[1].Line)
+	}
+
+	if values := rows[1].Values; values["isbn"] != "0441478123" || values["digital_only"] != "true" || values["author"] != "" || values["cover_image_url"] != "" {
+		t.Errorf("read digital book %v", values)
+	}
+
+	tests := []struct {
+		name  string
+		row   Row
+		field string
+	}{
+		{"priced in another currency", rows[2], "Price"},
+		{"deleted", rows[3], "NotificationType"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.row.Problems) != 1 || tt.row.Problems[0].Field != tt.field {
+				t.Errorf("read problems %v, want one with %s", tt.row.Problems, tt.field)
+			}
+		})
+	}
+}
+
+func TestProductRow(t *testing.T) {
+	product := onix.ProductOf(testBook(), "Ace Books", onix.Currency())
+	detail := &product.DescriptiveDetail
+	detail.Contributors = []onix.Contributor{
+		{SequenceNumber: 3, ContributorRoles: []string{onix.RoleAuthor}, PersonName: "Third Author"},
+		{SequenceNumber: 1, ContributorRoles: []string{"B01"}, PersonName: "An Editor"},
+		{SequenceNumber: 2, ContributorRoles: []string{onix.RoleAuthor}, PersonNameInverted: "Author, Second"},
+	}
+	detail.Extents = []onix.Extent{
+		{ExtentType: onix.ExtentTotalPages, ExtentValue: "320", ExtentUnit: onix.ExtentUnitPages},
+		{ExtentType: onix.ExtentMainContentPages, ExtentValue: "2", ExtentUnit: "09"},
+	}
+	product.PublishingDetail.PublishingDates[0].Date = onix.Date{Format: onix.DateFormatYYYYMM, Value: "196903"}
+	product.CollateralDetail.SupportingResources[0].ResourceVersions = []onix.ResourceVersion{
+		{ResourceForm: onix.ResourceFormLinkable, ResourceLink: "https://example.com/books/12/cover"},
+		{ResourceForm: onix.ResourceFormFile, ResourceLink: "https://example.com/covers/12.png"},
+	}
+
+	values := productRow(product, 1).Values
+	want := map[string]string{
+		"author":          "Author, Second",
+		"pages":           "320",
+		"published_date":  "1969-03-01",
+		"cover_image_url": "https://example.com/covers/12.png",
+	}
+	for field, value := range want {
+		if values[field] != value {
+			t.Errorf("read %s %q, want %q", field, values[field], value)
+		}
+	}
+}
+
+func TestOnixDate(t *testing.T) {
+	tests := []struct {
+		date onix.Date
+		want string
+	}{
+		{onix.Date{Value: "19690301"}, "1969-03-01"},
+		{onix.Date{Format: onix.DateFormatYYYYMMDD, Value: " 19690301 "}, "1969-03-01"},
+		{onix.Date{Format: onix.DateFormatYYYYMM, Value: "196903"}, "1969-03-01"},
+		{onix.Date{Format: onix.DateFormatYYYY, Value: "1969"}, "1969-01-01"},
+		{onix.Date{Format: onix.DateFormatYYYY, Value: "69"}, "69"},
+		{onix.Date{Value: "1969"}, "1969"},
+	}
+
+	for _, tt := range tests {
+		if got := onixDate(tt.date); got != tt.want {
+			t.Errorf("onixDate(%+v) = %q, want %q", tt.date, got, tt.want)
+		}
+	}
+}
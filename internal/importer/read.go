@@ -0,0 +1,278 @@
+// Package importer reads CSV and NDJSON files of books, authors and artists and ONIX messages
+// and MARC records of books and saves their rows, updating the books that already exist by ISBN.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-playground/internal/database/models"
+)
+
+// Entities that can be imported
+const (
+	EntityBooks   = "books"
+	EntityAuthors = "authors"
+	EntityArtists = "artists"
+)
+
+// Formats of import files
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatONIX    = "onix"    // ONIX for Books 3.0 messages of books
+	FormatMARC    = "marc"    // Binary MARC 21 records of books
+	FormatMARCXML = "marcxml" // MARC 21 records of books as MARCXML
+)
+
+// Fields lists the fields the rows of each entity can set. The author of a book is matched by
+// name, given as author ("First Last" or "Last, First") or as author_first_name and
+// author_last_name, and created when there is none. published_date is a date or a year,
+// a year keeps the day of a book published that year. genres holds genre slugs or names
+// separated by | or ;. cover_image_url links an image to the cover of a book. Authors and
+// artists take a name instead of first_name and last_name too.
+var Fields = map[string][]string{
+	EntityBooks: {
+		"isbn", "title", "published_date", "pages", "description", "digital_only", "price",
+		"author", "author_first_name", "author_last_name", "genres", "cover_image_url",
+	},
+	EntityAuthors: {"first_name", "last_name", "name"},
+	EntityArtists: {"first_name", "last_name", "name"},
+}
+
+// maxLineSize bounds a line of an NDJSON file
+const maxLineSize = 1 << 20
+
+// Row holds the values of one record of an import file by field, empty values are left out
+type Row struct {
+	Line   int // Line the record starts on counting from 1, the number of a binary MARC record
+	Values map[string]string
+
+	// Problems found while reading the record, a row with any is rejected with them
+	Problems []models.ImportRowError
+}
+
+// FileError is a problem with an import file as a whole, such as a malformed line or a
+// mapping to an unknown field. No row of such a file is imported.
+type FileError struct {
+	Line    int // 0 when the problem is not on a line
+	Message string
+}
+
+func (e *FileError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// FormatOf detects the format of a file from its name, it is empty when the name does not tell
+func FormatOf(fileName string) string {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".csv":
+		return FormatCSV
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".xml", ".onix":
+		return FormatONIX
+	case ".mrc", ".marc":
+		return FormatMARC
+	case ".marcxml":
+		return FormatMARCXML
+	}
+	return ""
+}
+
+// Read parses the records of an import file into rows of the entity. CSV files start with
+// a header row, NDJSON files hold an object per line. mapping renames columns or keys of the
+// file to fields, those named like a field need no mapping and any others are ignored.
+// ONIX messages and MARC records hold books with the fields they define, they are not mapped.
+func Read(r io.Reader, format string, entity string, mapping map[string]string) ([]Row, error) {
+	fields, ok := Fields[entity]
+	if !ok {
+		return nil, &FileError{Message: fmt.Sprintf("cannot import %q, use one of books, authors or artists", entity)}
+	}
+	if format == FormatONIX {
+		if entity != EntityBooks {
+			return nil, &FileError{Message: "ONIX messages hold books, import them as books"}
+		}
+		if len(mapping) > 0 {
+			return nil, &FileError{Message: "ONIX messages cannot be mapped"}
+		}
+		return readONIX(r)
+	}
+	if format == FormatMARC || format == FormatMARCXML {
+		if entity != EntityBooks {
+			return nil, &FileError{Message: "MARC records are books, import them as books"}
+		}
+		if len(mapping) > 0 {
+			return nil, &FileError{Message: "MARC records cannot be mapped"}
+		}
+		return readMARC(r, format)
+	}
+	for column, field := range mapping {
+		if !slices.Contains(fields, field) {
+			return nil, &FileError{Message: fmt.Sprintf("column %q is mapped to %q, which is not a field of %s", column, field, entity)}
+		}
+	}
+
+	// fieldOf names the field a column is imported into, if any
+	fieldOf := func(column string) (string, bool) {
+		column = strings.TrimSpace(column)
+		if field, ok := mapping[column]; ok {
+			return field, true
+		}
+		field := strings.ToLower(column)
+		return field, slices.Contains(fields, field)
+	}
+
+	switch format {
+	case FormatCSV:
+		return readCSV(r, entity, fieldOf)
+	case FormatNDJSON:
+		return readNDJSON(r, fieldOf)
+	}
+	return nil, &FileError{Message: fmt.Sprintf("unknown format %q, use csv, ndjson, onix, marc or marcxml", format)}
+}
+
+func readCSV(r io.Reader, entity string, fieldOf func(string) (string, bool)) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &FileError{Message: "the file is empty"}
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	// Spreadsheet programs like to start UTF-8 files with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	columns := make([]string, len(header))
+	matched := false
+	for i, column := range header {
+		if field, ok := fieldOf(column); ok {
+			columns[i] = field
+			matched = true
+		}
+	}
+	if !matched {
+		return nil, &FileError{Line: 1, Message: "no column of the header is a field of " + entity + ", map the columns to fields"}
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) && len(rows) == 0 {
+			return nil, &FileError{Message: "the file has no rows below its header"}
+		}
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := Row{Line: line, Values: map[string]string{}}
+		for i, value := range record {
+			if value = strings.TrimSpace(value); columns[i] != "" && value != "" {
+				row.Values[columns[i]] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// csvError reports a malformed CSV file with the line it went wrong on
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &FileError{Line: parseErr.Line, Message: parseErr.Err.Error()}
+	}
+	return err
+}
+
+func readNDJSON(r io.Reader, fieldOf func(string) (string, bool)) ([]Row, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+
+	var rows []Row
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var object map[string]any
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&object); err != nil || object == nil {
+			return nil, &FileError{Line: line, Message: "must be a JSON object"}
+		}
+
+		row := Row{Line: line, Values: map[string]string{}}
+		for key, value := range object {
+			field, ok := fieldOf(key)
+			if !ok {
+				continue
+			}
+			text, err := textOf(value)
+			if err != nil {
+				return nil, &FileError{Line: line, Message: fmt.Sprintf("%s %s", key, err.Error())}
+			}
+			if text = strings.TrimSpace(text); text != "" {
+				row.Values[field] = text
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, &FileError{Line: line + 1, Message: fmt.Sprintf("lines must not be longer than %d bytes", maxLineSize)}
+		}
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, &FileError{Message: "the file is empty"}
+	}
+	return rows, nil
+}
+
+// textOf turns a JSON value into the text of a field, lists become text separated by |
+func textOf(value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case []any:
+		var items []string
+		for _, item := range value {
+			text, err := textOf(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, text)
+		}
+		return strings.Join(items, "|"), nil
+	}
+	return "", errors.New("must be a string, number, boolean or list")
+}
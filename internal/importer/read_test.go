@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	data := "\ufeffISBN, Name of the book,Price,ignored\n" +
+		"978-0-441-47812-5,\"The Left Hand of Darkness\",9.99,x\n" +
+		"\n" +
+		"0-441-47812-3,  \"Multi\nline\",,y\n"
+
+	rows, err := Read(strings.NewReader(data), FormatCSV, EntityBooks, map[string]string{"Name of the book": "title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Row{
+		{Line: 2, Values: map[string]string{"isbn": "978-0-441-47812-5", "title": "The Left Hand of Darkness", "price": "9.99"}},
+		{Line: 4, Values: map[string]string{"isbn": "0-441-47812-3", "title": "Multi\nline"}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("read rows %+v, want %+v", rows, want)
+	}
+}
+
+func TestReadNDJSON(t *testing.T) {
+	data := `{"isbn": "978-0-441-47812-5", "Pages": 304, "digital_only": false, "genres": ["sf", "classics"], "extra": {"nested": true}}` + "\n" +
+		"\n" +
+		`{"name": "Le Guin, Ursula K.", "title": null, "price": 9.5}` + "\n"
+
+	rows, err := Read(strings.NewReader(data), FormatNDJSON, EntityBooks, map[string]string{"name": "author"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Row{
+		{Line: 1, Values: map[string]string{"isbn": "978-0-441-47812-5", "pages": "304", "digital_only": "false", "genres": "sf|classics"}},
+		{Line: 3, Values: map[string]string{"author": "Le Guin, Ursula K.", "price": "9.5"}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("read rows %+v, want %+v", rows, want)
+	}
+}
+
+// malformedFile is a file Read rejects as a whole with a *FileError
+type malformedFile struct {
+	name    string
+	format  string
+	entity  string
+	mapping map[string]string
+	data    string
+	line    int
+	message string
+}
+
+func testMalformedFiles(t *testing.T, tests []malformedFile) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := Read(strings.NewReader(tt.data), tt.format, tt.entity, tt.mapping)
+			var fileErr *FileError
+			if !errors.As(err, &fileErr) {
+				t.Fatalf("reading returned %v and %d rows, want a *FileError", err, len(rows))
+			}
+			if !strings.Contains(fileErr.Message, tt.message) {
+				t.Errorf("error %q does not contain %q", fileErr.Message, tt.message)
+			}
+			if fileErr.Line != tt.line {
+				t.Errorf("error is on line %d, want %d", fileErr.Line, tt.line)
+			}
+		})
+	}
+}
+
+func TestReadRejectsMalformedFiles(t *testing.T) {
+	testMalformedFiles(t, []malformedFile{
+		{"unknown entity", FormatCSV, "publishers", nil, "name\nx\n", 0, `cannot import "publishers"`},
+		{"unknown format", "xlsx", EntityBooks, nil, "isbn\nx\n", 0, `unknown format "xlsx"`},
+		{"mapping to an unknown field", FormatCSV, EntityBooks, map[string]string{"Name": "name"}, "Name\nx\n", 0, `"name", which is not a field of books`},
+		{"empty CSV", FormatCSV, EntityBooks, nil, "", 0, "the file is empty"},
+		{"CSV without rows", FormatCSV, EntityBooks, nil, "isbn,title\n", 0, "no rows below its header"},
+		{"CSV without fields", FormatCSV, EntityAuthors, nil, "isbn,title\nx,y\n", 1, "no column of the header is a field of authors"},
+		{"CSV with an unclosed quote", FormatCSV, EntityBooks, nil, "isbn,title\nx,y\nz,\"open\n", 3, "extraneous or missing"},
+		{"CSV with uneven rows", FormatCSV, EntityBooks, nil, "isbn,title\nx,y\nz\n", 3, "wrong number of fields"},
+		{"empty NDJSON", FormatNDJSON, EntityBooks, nil, "\n\n", 0, "the file is empty"},
+		{"NDJSON of arrays", FormatNDJSON, EntityBooks, nil, "{\"isbn\": \"x\"}\n[1, 2]\n", 2, "must be a JSON object"},
+		{"NDJSON of null", FormatNDJSON, EntityBooks, nil, "null\n", 1, "must be a JSON object"},
+		{"NDJSON spanning lines", FormatNDJSON, EntityBooks, nil, "{\"isbn\":\n\"x\"}\n", 1, "must be a JSON object"},
+		{"NDJSON with an object value", FormatNDJSON, EntityBooks, nil, "{\"title\": {\"en\": \"x\"}}\n", 1, "title must be a string, number, boolean or list"},
+		{"NDJSON with a long line", FormatNDJSON, EntityBooks, nil, "{}\n" + strings.Repeat(" ", maxLineSize+1) + "\n", 2, "must not be longer"},
+	})
+}
+
+func TestPersonName(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		first  string
+		last   string
+		field  string
+	}{
+		{"first and last", map[string]string{"first_name": "Ursula K.", "last_name": "Le Guin"}, "Ursula K.", "Le Guin", ""},
+		{"name", map[string]string{"name": "Ursula K. Le Guin"}, "Ursula K. Le", "Guin", ""},
+		{"inverted name", map[string]string{"name": "Le Guin, Ursula K."}, "Ursula K.", "Le Guin", ""},
+		{"fields win over the name", map[string]string{"first_name": "Ursula", "last_name": "Le Guin", "name": "Someone Else"}, "Ursula", "Le Guin", ""},
+		{"single word", map[string]string{"name": "Colette"}, "", "", "name"},
+		{"inverted without first name", map[string]string{"name": "Colette, "}, "", "", "name"},
+		{"nothing", map[string]string{}, "", "", "name"},
+		{"first name only", map[string]string{"first_name": "Ursula"}, "", "", "last_name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last, err := personName(tt.values, "first_name", "last_name", "name")
+			if tt.field != "" {
+				var errs rowErrors
+				if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != tt.field {
+					t.Errorf("personName returned %v, want an error of %s", err, tt.field)
+				}
+				return
+			}
+			if err != nil || first != tt.first || last != tt.last {
+				t.Errorf("personName returned %q, %q, %v, want %q, %q", first, last, err, tt.first, tt.last)
+			}
+		})
+	}
+}
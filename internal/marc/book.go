@@ -0,0 +1,104 @@
+package marc
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go-playground/internal/database/models"
+)
+
+// CoverLabel names the link to the cover image of a book in 856 $3
+const CoverLabel = "Cover image"
+
+// maxDescriptionSize keeps a 520 field within the 9999 bytes a field can have
+const maxDescriptionSize = 9000
+
+// RecordOf describes a book with its author, cover and genres as a MARC 21 bibliographic
+// record, with the ID of the book as its control number (001). The author is the main entry
+// (100) and the title the title statement (245), the ISBN and price are in 020, the year of
+// publication in 264 and 008, the pages in 300 and the description in 520. Genres are index
+// terms of 655 and the cover image a link of 856.
+func RecordOf(book *models.Book) *Record {
+	record := &Record{Leader: defaultLeader}
+
+	record.AddControl("001", strconv.FormatUint(uint64(book.ID), 10))
+	record.AddControl("005", book.UpdatedAt.UTC().Format("20060102150405")+".0")
+	record.AddControl("008", fixedData(book))
+
+	// ISBNs are stored as they were entered, MARC has them without hyphens
+	isbn := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(book.ISBN))
+	record.AddField("020", " ", " ", "a", isbn, "c", strconv.FormatFloat(float64(book.Price), 'f', 2, 32))
+
+	author := ""
+	if book.Author.LastName != "" {
+		author = strings.TrimSuffix(book.Author.LastName+", "+book.Author.FirstName, ", ")
+	} else {
+		author = book.Author.FirstName
+	}
+	titleIndicator := "0"
+	if author != "" {
+		record.AddField("100", "1", " ", "a", author, "e", "author")
+		titleIndicator = "1"
+	}
+	record.AddField("245", titleIndicator, "0", "a", book.Title)
+	record.AddField("264", " ", "1", "c", book.PublishedDate.Format("2006"))
+	if book.Pages > 0 {
+		record.AddField("300", " ", " ", "a", strconv.FormatUint(uint64(book.Pages), 10)+" pages")
+	}
+
+	record.AddField("336", " ", " ", "a", "text", "b", "txt", "2", "rdacontent")
+	if book.DigitalOnly {
+		record.AddField("337", " ", " ", "a", "computer", "b", "c", "2", "rdamedia")
+		record.AddField("338", " ", " ", "a", "online resource", "b", "cr", "2", "rdacarrier")
+	} else {
+		record.AddField("337", " ", " ", "a", "unmediated", "b", "n", "2", "rdamedia")
+		record.AddField("338", " ", " ", "a", "volume", "b", "nc", "2", "rdacarrier")
+	}
+
+	for _, part := range splitDescription(book.Description) {
+		record.AddField("520", " ", " ", "a", part)
+	}
+	for _, genre := range book.Genres {
+		record.AddField("655", " ", "4", "a", genre.Name)
+	}
+	if book.Cover.ImageURL.Valid && book.Cover.ImageURL.String != "" {
+		record.AddField("856", "4", "2", "3", CoverLabel, "u", book.Cover.ImageURL.String)
+	}
+
+	return record
+}
+
+// fixedData writes the fixed-length data elements (008) of a book: the date the record was
+// created, the year of publication, whether it is online and the codes for what is not known
+func fixedData(book *models.Book) string {
+	form := " "
+	if book.DigitalOnly {
+		form = "o"
+	}
+	return book.CreatedAt.UTC().Format("060102") + "s" + book.PublishedDate.Format("2006") + "    " + "xx " +
+		"    " + " " + form + "    " + " " + "000" + " " + "|" + " " + "und" + " " + "d"
+}
+
+// splitDescription cuts a description too long for one field into parts for repeated 520
+// fields, at line breaks where it can. Importing the record joins the parts with line breaks.
+func splitDescription(description string) []string {
+	var parts []string
+	for len(description) > maxDescriptionSize {
+		cut := strings.LastIndexByte(description[:maxDescriptionSize], '\n')
+		next := cut + 1
+		if cut <= 0 {
+			cut = maxDescriptionSize
+			for !utf8.RuneStart(description[cut]) {
+				cut--
+			}
+			next = cut
+		}
+		parts = append(parts, description[:cut])
+		description = description[next:]
+	}
+	if description != "" {
+		parts = append(parts, description)
+	}
+	return parts
+}
@@ -0,0 +1,160 @@
+// Package marc reads and writes MARC 21 bibliographic records, in the binary ISO 2709
+// transmission format libraries exchange them in and as MARCXML.
+// See https://www.loc.gov/marc/bibliographic/ for the fields and https://www.loc.gov/standards/marcxml/.
+package marc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Namespace of MARCXML
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+// Formats records are read and written in
+const (
+	FormatBinary = "marc"
+	FormatXML    = "marcxml"
+)
+
+// MediaTypes of the formats (RFC 2220 and RFC 6207)
+var MediaTypes = map[string]string{
+	FormatBinary: "application/marc",
+	FormatXML:    "application/marcxml+xml",
+}
+
+// Record is a MARC record, its fields are kept in the order they were read or added
+type Record struct {
+	Leader        string         `xml:"leader"`
+	ControlFields []ControlField `xml:"controlfield"`
+	DataFields    []DataField    `xml:"datafield"`
+}
+
+// ControlField is one of the fields 001 to 009, which have a value and no subfields
+type ControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type DataField struct {
+	Tag       string     `xml:"tag,attr"`
+	Ind1      string     `xml:"ind1,attr"`
+	Ind2      string     `xml:"ind2,attr"`
+	Subfields []Subfield `xml:"subfield"`
+}
+
+type Subfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Error is a problem with a file of MARC records that keeps the rest of it from being read
+type Error struct {
+	Line    int // Line of a MARCXML file, 0 when the problem is not on a line
+	Record  int // Number of a record of a binary file counting from 1, 0 when it is not in a record
+	Message string
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	case e.Record > 0:
+		return fmt.Sprintf("record %d: %s", e.Record, e.Message)
+	}
+	return e.Message
+}
+
+// Control returns the value of the control field with the tag, empty when there is none
+func (record *Record) Control(tag string) string {
+	for _, field := range record.ControlFields {
+		if field.Tag == tag {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Fields returns the data fields with the tag
+func (record *Record) Fields(tag string) []DataField {
+	var fields []DataField
+	for _, field := range record.DataFields {
+		if field.Tag == tag {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// AddControl adds a control field
+func (record *Record) AddControl(tag string, value string) {
+	record.ControlFields = append(record.ControlFields, ControlField{Tag: tag, Value: value})
+}
+
+// AddField adds a data field with the indicators and subfields given as pairs of code and
+// value, the subfields with an empty value are left out
+func (record *Record) AddField(tag string, ind1 string, ind2 string, subfields ...string) {
+	field := DataField{Tag: tag, Ind1: ind1, Ind2: ind2}
+	for i := 0; i+1 < len(subfields); i += 2 {
+		if subfields[i+1] != "" {
+			field.Subfields = append(field.Subfields, Subfield{Code: subfields[i], Value: subfields[i+1]})
+		}
+	}
+	record.DataFields = append(record.DataFields, field)
+}
+
+// Subfield returns the value of the first subfield with the code, empty when there is none
+func (field DataField) Subfield(code string) string {
+	for _, subfield := range field.Subfields {
+		if subfield.Code == code {
+			return subfield.Value
+		}
+	}
+	return ""
+}
+
+// UTF8 reports whether the record is encoded in UTF-8 (leader/09 a) rather than MARC-8
+func (record *Record) UTF8() bool {
+	return len(record.Leader) > 9 && record.Leader[9] == 'a'
+}
+
+// ASCII reports whether the fields of the record are plain ASCII, which reads the same in
+// MARC-8 and UTF-8
+func (record *Record) ASCII() bool {
+	ascii := func(value string) bool {
+		for i := 0; i < len(value); i++ {
+			if value[i] >= 0x80 {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, field := range record.ControlFields {
+		if !ascii(field.Value) {
+			return false
+		}
+	}
+	for _, field := range record.DataFields {
+		for _, subfield := range field.Subfields {
+			if !ascii(subfield.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// TrimPunctuation strips the ISBD punctuation that ends the subfields of a record, such as
+// the " /" before a statement of responsibility or the full stop at the end of a field
+func TrimPunctuation(value string) string {
+	value = strings.TrimSpace(value)
+	for value != "" && strings.ContainsRune("/:;,=.", rune(value[len(value)-1])) {
+		// An initial keeps its full stop, as in "Le Guin, Ursula K." or "Tolkien, J.R.R."
+		if n := len(value); value[n-1] == '.' && n >= 3 && (value[n-3] == ' ' || value[n-3] == '.') {
+			break
+		}
+		value = strings.TrimSpace(value[:len(value)-1])
+	}
+	return value
+}
@@ -0,0 +1,209 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Delimiters of the binary format
+const (
+	subfieldDelimiter = 0x1F
+	fieldTerminator   = 0x1E
+	recordTerminator  = 0x1D
+)
+
+// leaderSize is the length of the leader, directoryEntrySize that of an entry of the directory
+const (
+	leaderSize         = 24
+	directoryEntrySize = 12
+)
+
+// Reader reads the records of a binary MARC file one at a time
+type Reader struct {
+	r     *bufio.Reader
+	count int
+}
+
+// NewReader creates a reader of the binary MARC records read from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next record of the file and its number, counting from 1. It returns io.EOF
+// after the last record.
+func (r *Reader) Next() (*Record, int, error) {
+	// Records are often put on lines of their own
+	for {
+		b, err := r.r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			if r.count == 0 {
+				return nil, 0, &Error{Message: "the file holds no MARC records"}
+			}
+			return nil, 0, io.EOF
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if b != '\n' && b != '\r' && b != ' ' {
+			r.r.UnreadByte()
+			break
+		}
+	}
+	r.count++
+
+	prefix := make([]byte, 5)
+	if _, err := io.ReadFull(r.r, prefix); err != nil {
+		return nil, 0, r.error("the record ends within its leader")
+	}
+	length, ok := parseNumber(prefix)
+	if !ok || length < leaderSize+1 {
+		return nil, 0, r.error(fmt.Sprintf("the record starts with %q, not with its length, the file is not binary MARC", prefix))
+	}
+	data := make([]byte, length)
+	copy(data, prefix)
+	if _, err := io.ReadFull(r.r, data[5:]); err != nil {
+		return nil, 0, r.error(fmt.Sprintf("the record is %d bytes long, the file ends before", length))
+	}
+	if data[length-1] != recordTerminator {
+		return nil, 0, r.error("the record does not end where its length says")
+	}
+
+	record, message := parseRecord(data)
+	if message != "" {
+		return nil, 0, r.error(message)
+	}
+	return record, r.count, nil
+}
+
+func (r *Reader) error(message string) error {
+	return &Error{Record: r.count, Message: message}
+}
+
+// parseRecord reads a record of the binary format, or tells what is wrong with it
+func parseRecord(data []byte) (*Record, string) {
+	record := &Record{Leader: string(data[:leaderSize])}
+
+	base, ok := parseNumber(data[12:17])
+	if !ok || base <= leaderSize || base > len(data) || data[base-1] != fieldTerminator {
+		return nil, "the base address of the data in the leader is not where the directory ends"
+	}
+	directory := data[leaderSize : base-1]
+	if len(directory)%directoryEntrySize != 0 {
+		return nil, "the directory is not made of entries of 12 bytes"
+	}
+
+	for entry := directory; len(entry) > 0; entry = entry[directoryEntrySize:] {
+		tag := string(entry[:3])
+		length, lengthOK := parseNumber(entry[3:7])
+		start, startOK := parseNumber(entry[7:12])
+		if !lengthOK || !startOK || length < 1 || base+start+length > len(data)-1 {
+			return nil, fmt.Sprintf("the directory entry of field %s points outside of the record", tag)
+		}
+		field := bytes.TrimSuffix(data[base+start:base+start+length], []byte{fieldTerminator})
+
+		if strings.HasPrefix(tag, "00") {
+			record.AddControl(tag, string(field))
+			continue
+		}
+		if len(field) < 2 {
+			return nil, fmt.Sprintf("field %s has no indicators", tag)
+		}
+		dataField := DataField{Tag: tag, Ind1: string(field[0]), Ind2: string(field[1])}
+		for _, subfield := range bytes.Split(field[2:], []byte{subfieldDelimiter}) {
+			// The data before the first delimiter is empty
+			if len(subfield) > 0 {
+				dataField.Subfields = append(dataField.Subfields, Subfield{Code: string(subfield[:1]), Value: string(subfield[1:])})
+			}
+		}
+		record.DataFields = append(record.DataFields, dataField)
+	}
+	return record, ""
+}
+
+// parseNumber reads a number of the leader or the directory, which are unsigned and padded
+// with zeros. Anything else, like a sign, is not a number there.
+func parseNumber(digits []byte) (int, bool) {
+	if len(digits) == 0 {
+		return 0, false
+	}
+	n := 0
+	for _, digit := range digits {
+		if digit < '0' || digit > '9' {
+			return 0, false
+		}
+		n = n*10 + int(digit-'0')
+	}
+	return n, true
+}
+
+// Decoder reads the records of a MARCXML file one at a time, a collection of records or a
+// single record
+type Decoder struct {
+	xml     *xml.Decoder
+	started bool
+}
+
+// NewDecoder creates a decoder of the MARCXML read from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{xml: xml.NewDecoder(bufio.NewReader(r))}
+}
+
+// Next returns the next record of the file and the line it starts on. It returns io.EOF after
+// the last record.
+func (d *Decoder) Next() (*Record, int, error) {
+	for {
+		line, _ := d.xml.InputPos()
+		token, err := d.xml.Token()
+		if errors.Is(err, io.EOF) {
+			if !d.started {
+				return nil, 0, &Error{Message: "the file holds no MARCXML records"}
+			}
+			return nil, 0, io.EOF
+		}
+		if err != nil {
+			return nil, 0, d.syntaxError(err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if !d.started {
+			if start.Name.Local != "collection" && start.Name.Local != "record" {
+				return nil, 0, &Error{Message: fmt.Sprintf("the file is not MARCXML, it starts with <%s>", start.Name.Local)}
+			}
+			d.started = true
+			if start.Name.Local == "collection" {
+				continue
+			}
+		}
+
+		if start.Name.Local != "record" {
+			if err := d.xml.Skip(); err != nil {
+				return nil, 0, d.syntaxError(err)
+			}
+			continue
+		}
+		var record Record
+		if err := d.xml.DecodeElement(&record, &start); err != nil {
+			return nil, 0, d.syntaxError(err)
+		}
+		return &record, line, nil
+	}
+}
+
+// syntaxError reports malformed XML with the line it went wrong on
+func (d *Decoder) syntaxError(err error) error {
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &Error{Line: syntaxErr.Line, Message: syntaxErr.Msg}
+	}
+	line, _ := d.xml.InputPos()
+	return &Error{Line: line, Message: err.Error()}
+}
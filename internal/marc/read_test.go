@@ -0,0 +1,217 @@
+package marc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRecord has control and data fields, repeated fields and text outside of ASCII
+func testRecord() *Record {
+	record := &Record{Leader: defaultLeader}
+	record.AddControl("001", "42")
+	record.AddControl("008", "250101s2001    xx            000 | und d")
+	record.AddField("020", " ", " ", "a", "9780151003877", "c", "12.50")
+	record.AddField("100", "1", " ", "a", "Le Guin, Ursula K.", "e", "author")
+	record.AddField("245", "1", "0", "a", "Die linke Hand der Dunkelheit :", "b", "Roman über Gethen")
+	record.AddField("655", " ", "4", "a", "Science fiction")
+	record.AddField("655", " ", "4", "a", "Fantasy")
+	return record
+}
+
+func writeRecords(t testing.TB, format string, records ...*Record) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readAll reads every record of a file in the format, failing on the first error
+func readAll(data []byte, format string) ([]*Record, error) {
+	next := NewReader(bytes.NewReader(data)).Next
+	if format == FormatXML {
+		next = NewDecoder(bytes.NewReader(data)).Next
+	}
+
+	var records []*Record
+	for {
+		record, _, err := next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	second := &Record{}
+	second.AddControl("001", "43")
+	second.AddField("245", "0", "0", "a", "Untitled")
+
+	for _, format := range []string{FormatBinary, FormatXML} {
+		t.Run(format, func(t *testing.T) {
+			records, err := readAll(writeRecords(t, format, testRecord(), second), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 2 {
+				t.Fatalf("read %d records, want 2", len(records))
+			}
+
+			for i, want := range []*Record{testRecord(), second} {
+				got := records[i]
+				if !reflect.DeepEqual(got.ControlFields, want.ControlFields) {
+					t.Errorf("record %d has control fields %v, want %v", i+1, got.ControlFields, want.ControlFields)
+				}
+				if !reflect.DeepEqual(got.DataFields, want.DataFields) {
+					t.Errorf("record %d has data fields %v, want %v", i+1, got.DataFields, want.DataFields)
+				}
+				if !got.UTF8() {
+					t.Errorf("record %d has leader %q, want it marked as UTF-8", i+1, got.Leader)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteBinaryLeader(t *testing.T) {
+	data := writeRecords(t, FormatBinary, testRecord())
+
+	record, message := parseRecord(data)
+	if message != "" {
+		t.Fatal(message)
+	}
+	if length := record.Leader[0:5]; length != fmt.Sprintf("%05d", len(data)) {
+		t.Errorf("leader gives the length %s, the record is %d bytes", length, len(data))
+	}
+	if data[len(data)-1] != recordTerminator {
+		t.Error("the record does not end with a record terminator")
+	}
+}
+
+func TestWriteRejectsLongFields(t *testing.T) {
+	record := &Record{}
+	record.AddField("520", " ", " ", "a", strings.Repeat("x", 10000))
+
+	w, err := NewWriter(io.Discard, FormatBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(record); err == nil || !strings.Contains(err.Error(), "9999") {
+		t.Errorf("writing a field of 10000 bytes returned %v", err)
+	}
+}
+
+// corrupt returns a copy of a binary record with the bytes at offset replaced
+func corrupt(record []byte, offset int, replacement string) []byte {
+	data := bytes.Clone(record)
+	copy(data[offset:], replacement)
+	return data
+}
+
+func TestReaderRejectsMalformedRecords(t *testing.T) {
+	// A record with the control field 001 only, its single directory entry starts after the leader
+	single := &Record{}
+	single.AddControl("001", "1234567890123456")
+	valid := writeRecords(t, FormatBinary, single)
+	const entry = leaderSize
+
+	// A data field without indicators, its length covers the field terminator only
+	noIndicators := []byte("00039nam a2200037 i 4500" + "245000100000" + "\x1e" + "\x1e" + "\x1d")
+
+	tests := []struct {
+		name    string
+		data    []byte
+		message string
+	}{
+		{"empty file", nil, "holds no MARC records"},
+		{"text", []byte("this is not MARC at all"), "not binary MARC"},
+		{"signed record length", corrupt(valid, 0, "+0044"), "not binary MARC"},
+		{"short leader", []byte("0004"), "ends within its leader"},
+		{"truncated", valid[:len(valid)-5], "the file ends before"},
+		{"missing record terminator", corrupt(valid, len(valid)-1, "x"), "does not end where its length says"},
+		{"signed base address", corrupt(valid, 12, "+0037"), "base address"},
+		{"base address past the record", corrupt(valid, 12, "99999"), "base address"},
+		{"base address within the leader", corrupt(valid, 12, "00010"), "base address"},
+		{"directory of partial entries", corrupt(valid, 12, "00031"), "base address"},
+		{"negative start", corrupt(valid, entry+7, "-9999"), "points outside"},
+		{"signed start", corrupt(valid, entry+7, "+0000"), "points outside"},
+		{"start past the record", corrupt(valid, entry+7, "00100"), "points outside"},
+		{"negative length", corrupt(valid, entry+3, "-001"), "points outside"},
+		{"zero length", corrupt(valid, entry+3, "0000"), "points outside"},
+		{"length past the record", corrupt(valid, entry+3, "0099"), "points outside"},
+		{"length with spaces", corrupt(valid, entry+3, "  17"), "points outside"},
+		{"field without indicators", noIndicators, "has no indicators"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readAll(tt.data, FormatBinary)
+			var marcErr *Error
+			if !errors.As(err, &marcErr) {
+				t.Fatalf("reading returned %v, want an *Error", err)
+			}
+			if !strings.Contains(marcErr.Message, tt.message) {
+				t.Errorf("error %q does not contain %q", marcErr.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestDecoderRejectsMalformedXML(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		message string
+	}{
+		{"empty file", "", "holds no MARCXML records"},
+		{"other XML", `<?xml version="1.0"?><rss><channel/></rss>`, "not MARCXML, it starts with <rss>"},
+		{"unclosed record", "<collection>\n<record>\n<leader>x</leader>\n", "line"},
+		{"mismatched element", "<collection>\n<record></datafield></collection>", "line 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readAll([]byte(tt.data), FormatXML)
+			var marcErr *Error
+			if !errors.As(err, &marcErr) {
+				t.Fatalf("reading returned %v, want an *Error", err)
+			}
+			if !strings.Contains(marcErr.Error(), tt.message) {
+				t.Errorf("error %q does not contain %q", marcErr.Error(), tt.message)
+			}
+		})
+	}
+}
+
+// FuzzReader checks that no input makes the reader panic, files are uploaded by users
+func FuzzReader(f *testing.F) {
+	valid := writeRecords(f, FormatBinary, testRecord())
+	f.Add(valid)
+	f.Add(corrupt(valid, leaderSize+7, "-9999"))
+	f.Add(corrupt(valid, leaderSize+3, "-001"))
+	f.Add([]byte("00044nam a2200037 i 4500001-001-9999\x1e12345\x1e\x1d"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		readAll(data, FormatBinary)
+	})
+}
@@ -0,0 +1,162 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// bufferSize is how much of a file is collected before it is written out
+const bufferSize = 32 << 10
+
+// defaultLeader is the leader of a new record: a new record of language material, a
+// monograph encoded in UTF-8 with ISBD punctuation. Its lengths are filled in when it is written.
+const defaultLeader = "00000nam a2200000 i 4500"
+
+// Writer writes a file of MARC records one record at a time
+type Writer interface {
+	Write(record *Record) error
+	// Close ends the file and writes out what is buffered. It does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter starts a file of MARC records in the format, binary MARC or MARCXML
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	buffer := bufio.NewWriterSize(w, bufferSize)
+	switch format {
+	case FormatBinary:
+		return &binaryWriter{buffer: buffer}, nil
+	case FormatXML:
+		return newXMLWriter(buffer)
+	}
+	return nil, fmt.Errorf("unknown MARC format %q", format)
+}
+
+type binaryWriter struct {
+	buffer *bufio.Writer
+}
+
+// Write adds a record in UTF-8, with its leader and directory computed from the fields
+func (w *binaryWriter) Write(record *Record) error {
+	var directory, data bytes.Buffer
+	addField := func(tag string, field []byte) error {
+		if len(field) > 9999 {
+			return fmt.Errorf("field %s is %d bytes long, MARC allows 9999", tag, len(field))
+		}
+		fmt.Fprintf(&directory, "%-3.3s%04d%05d", tag, len(field), data.Len())
+		data.Write(field)
+		return nil
+	}
+
+	for _, field := range record.ControlFields {
+		if err := addField(field.Tag, append([]byte(field.Value), fieldTerminator)); err != nil {
+			return err
+		}
+	}
+	for _, field := range record.DataFields {
+		var value bytes.Buffer
+		value.WriteString(indicator(field.Ind1))
+		value.WriteString(indicator(field.Ind2))
+		for _, subfield := range field.Subfields {
+			value.WriteByte(subfieldDelimiter)
+			value.WriteString(subfield.Code)
+			value.WriteString(subfield.Value)
+		}
+		value.WriteByte(fieldTerminator)
+		if err := addField(field.Tag, value.Bytes()); err != nil {
+			return err
+		}
+	}
+	directory.WriteByte(fieldTerminator)
+
+	base := leaderSize + directory.Len()
+	length := base + data.Len() + 1
+	if length > 99999 {
+		return fmt.Errorf("the record is %d bytes long, MARC allows 99999", length)
+	}
+
+	leader := []byte(defaultLeader)
+	if len(record.Leader) == leaderSize {
+		leader = []byte(record.Leader)
+	}
+	copy(leader[0:5], fmt.Sprintf("%05d", length))
+	leader[9] = 'a'
+	copy(leader[10:12], "22")
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+	copy(leader[20:24], "4500")
+
+	w.buffer.Write(leader)
+	w.buffer.Write(directory.Bytes())
+	w.buffer.Write(data.Bytes())
+	return w.buffer.WriteByte(recordTerminator)
+}
+
+func (w *binaryWriter) Close() error {
+	return w.buffer.Flush()
+}
+
+// indicator is a blank for an indicator that is not set
+func indicator(value string) string {
+	if len(value) != 1 {
+		return " "
+	}
+	return value
+}
+
+type xmlWriter struct {
+	buffer  *bufio.Writer
+	encoder *xml.Encoder
+}
+
+// newXMLWriter starts a MARCXML collection
+func newXMLWriter(buffer *bufio.Writer) (*xmlWriter, error) {
+	if _, err := buffer.WriteString(xml.Header); err != nil {
+		return nil, err
+	}
+
+	encoder := xml.NewEncoder(buffer)
+	encoder.Indent("", "  ")
+	root := xml.StartElement{
+		Name: xml.Name{Local: "collection"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: Namespace}},
+	}
+	if err := encoder.EncodeToken(root); err != nil {
+		return nil, err
+	}
+	return &xmlWriter{buffer: buffer, encoder: encoder}, nil
+}
+
+// Write adds a record, with the lengths of the leader left as they are since MARCXML has no
+// directory
+func (w *xmlWriter) Write(record *Record) error {
+	out := *record
+	leader := []byte(defaultLeader)
+	if len(record.Leader) == leaderSize {
+		leader = []byte(record.Leader)
+	}
+	leader[9] = 'a'
+	out.Leader = string(leader)
+
+	out.DataFields = make([]DataField, len(record.DataFields))
+	for i, field := range record.DataFields {
+		field.Ind1 = indicator(field.Ind1)
+		field.Ind2 = indicator(field.Ind2)
+		out.DataFields[i] = field
+	}
+	return w.encoder.EncodeElement(out, xml.StartElement{Name: xml.Name{Local: "record"}})
+}
+
+func (w *xmlWriter) Close() error {
+	if err := w.encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "collection"}}); err != nil {
+		return err
+	}
+	if err := w.encoder.Flush(); err != nil {
+		return err
+	}
+	if _, err := w.buffer.WriteString("\n"); err != nil {
+		return err
+	}
+	return w.buffer.Flush()
+}
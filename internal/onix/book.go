@@ -0,0 +1,110 @@
+package onix
+
+import (
+	"strconv"
+	"strings"
+
+	"go-playground/internal/database/models"
+)
+
+// ProductOf describes a book with its author, cover and genres as an ONIX product published
+// and supplied by publisher, priced in currency. Books are identified by their ISBN and by
+// their ID as a proprietary identifier, which makes the record reference.
+func ProductOf(book *models.Book, publisher string, currency string) *Product {
+	id := strconv.FormatUint(uint64(book.ID), 10)
+	product := &Product{
+		RecordReference:  "book-" + id,
+		NotificationType: NotificationConfirmed,
+		ProductIdentifiers: []ProductIdentifier{
+			{ProductIDType: ProductIDProprietary, IDTypeName: "Book ID", IDValue: id},
+		},
+		DescriptiveDetail: DescriptiveDetail{
+			ProductComposition: CompositionSingleItem,
+			ProductForm:        FormBook,
+			TitleDetails: []TitleDetail{{
+				TitleType:     TitleDistinctive,
+				TitleElements: []TitleElement{{TitleElementLevel: TitleLevelProduct, TitleText: book.Title}},
+			}},
+		},
+		CollateralDetail: &CollateralDetail{},
+		PublishingDetail: &PublishingDetail{
+			Publishers:       []Publisher{{PublishingRole: PublishingRolePublisher, PublisherName: publisher}},
+			PublishingStatus: PublishingStatusActive,
+			PublishingDates: []PublishingDate{{
+				PublishingDateRole: DatePublication,
+				Date:               Date{Format: DateFormatYYYYMMDD, Value: book.PublishedDate.Format("20060102")},
+			}},
+		},
+		ProductSupplies: []ProductSupply{{
+			SupplyDetails: []SupplyDetail{{
+				Supplier:            Supplier{SupplierRole: SupplierPublisherToRetailers, SupplierName: publisher},
+				ProductAvailability: AvailabilityAvailable,
+				Prices: []Price{{
+					PriceType:    PriceRRPIncludingTax,
+					PriceAmount:  strconv.FormatFloat(float64(book.Price), 'f', 2, 32),
+					CurrencyCode: currency,
+				}},
+			}},
+		}},
+	}
+
+	// ISBNs are stored as they were entered, ONIX has them without hyphens
+	isbn := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(book.ISBN))
+	switch len(isbn) {
+	case 13:
+		product.ProductIdentifiers = append(product.ProductIdentifiers, ProductIdentifier{ProductIDType: ProductIDISBN13, IDValue: isbn})
+	case 10:
+		product.ProductIdentifiers = append(product.ProductIdentifiers, ProductIdentifier{ProductIDType: ProductIDISBN10, IDValue: isbn})
+	}
+
+	detail := &product.DescriptiveDetail
+	if book.DigitalOnly {
+		detail.ProductForm = FormDigitalDownload
+	}
+	if book.Author.FirstName != "" || book.Author.LastName != "" {
+		detail.Contributors = append(detail.Contributors, Contributor{
+			SequenceNumber:   1,
+			ContributorRoles: []string{RoleAuthor},
+			PersonName:       strings.TrimSpace(book.Author.FirstName + " " + book.Author.LastName),
+			NamesBeforeKey:   book.Author.FirstName,
+			KeyNames:         book.Author.LastName,
+		})
+	}
+	if book.Pages > 0 {
+		detail.Extents = append(detail.Extents, Extent{
+			ExtentType:  ExtentMainContentPages,
+			ExtentValue: strconv.FormatUint(uint64(book.Pages), 10),
+			ExtentUnit:  ExtentUnitPages,
+		})
+	}
+	for _, genre := range book.Genres {
+		detail.Subjects = append(detail.Subjects, Subject{
+			SubjectSchemeIdentifier: SubjectSchemeProprietary,
+			SubjectSchemeName:       GenreScheme,
+			SubjectCode:             genre.Slug,
+			SubjectHeadingText:      genre.Name,
+		})
+	}
+
+	collateral := product.CollateralDetail
+	if book.Description != "" {
+		collateral.TextContents = append(collateral.TextContents, TextContent{
+			TextType:        TextDescription,
+			ContentAudience: AudienceUnrestricted,
+			Text:            Text{Format: TextFormatText, Value: book.Description},
+		})
+	}
+	if book.Cover.ImageURL.Valid && book.Cover.ImageURL.String != "" {
+		collateral.SupportingResources = append(collateral.SupportingResources, SupportingResource{
+			ResourceContentType: ResourceFrontCover,
+			ContentAudience:     AudienceUnrestricted,
+			ResourceMode:        ResourceModeImage,
+			ResourceVersions:    []ResourceVersion{{ResourceForm: ResourceFormFile, ResourceLink: book.Cover.ImageURL.String}},
+		})
+	}
+	if len(collateral.TextContents) == 0 && len(collateral.SupportingResources) == 0 {
+		product.CollateralDetail = nil
+	}
+
+	return product
+}
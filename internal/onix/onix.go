@@ -0,0 +1,247 @@
+// Package onix reads and writes ONIX for Books 3.0 messages with reference tags, the XML
+// publishers and retailers exchange product metadata in. Only the composites the catalog
+// has a use for are modelled, anything else in a message is skipped when it is read.
+// See https://www.editeur.org/93/Release-3.0-Downloads/ for the specification and code lists.
+package onix
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// Namespace of ONIX 3.0 messages with reference tags
+const Namespace = "http://ns.editeur.org/onix/3.0/reference"
+
+// Release of ONIX that is read and written
+const Release = "3.0"
+
+// Codes of the ONIX code lists that are read or written, the list number is in the comment
+const (
+	NotificationConfirmed = "03" // List 1
+	NotificationDelete    = "05" // List 1
+
+	ProductIDProprietary = "01" // List 5
+	ProductIDISBN10      = "02" // List 5
+	ProductIDISBN13      = "15" // List 5
+
+	CompositionSingleItem = "00" // List 2
+	FormBook              = "BA" // List 150, book, detail unspecified
+	FormDigitalDownload   = "ED" // List 150, digital download
+
+	TitleDistinctive  = "01" // List 15
+	TitleLevelProduct = "01" // List 149
+
+	RoleAuthor = "A01" // List 17, by (author)
+
+	ExtentMainContentPages = "00" // List 23
+	ExtentTotalPages       = "07" // List 23, used when there is no main content count
+	ExtentUnitPages        = "03" // List 24
+
+	SubjectSchemeProprietary = "24" // List 27
+
+	TextDescription      = "03" // List 153
+	TextShortDescription = "02" // List 153
+	AudienceUnrestricted = "00" // List 154
+
+	ResourceFrontCover   = "01" // List 158
+	ResourceModeImage    = "03" // List 159
+	ResourceFormLinkable = "01" // List 161
+	ResourceFormFile     = "02" // List 161
+
+	PublishingRolePublisher = "01" // List 45
+	PublishingStatusActive  = "04" // List 64
+	DatePublication         = "01" // List 163
+
+	DateFormatYYYYMMDD = "00" // List 55, the default
+	DateFormatYYYYMM   = "01" // List 55
+	DateFormatYYYY     = "05" // List 55
+
+	SupplierPublisherToRetailers = "01" // List 93
+	AvailabilityAvailable        = "20" // List 65
+
+	PriceRRPExcludingTax = "01" // List 58
+	PriceRRPIncludingTax = "02" // List 58
+
+	TextFormatHTML  = "02" // List 34
+	TextFormatXHTML = "05" // List 34
+	TextFormatText  = "06" // List 34
+)
+
+// GenreScheme names the proprietary subject scheme the genres of the catalog are exported in,
+// subjects of this scheme are read back as genres
+const GenreScheme = "Genres"
+
+// Currency is the ISO 4217 code of the prices of the catalog, set by ONIX_CURRENCY
+func Currency() string {
+	if currency := os.Getenv("ONIX_CURRENCY"); currency != "" {
+		return strings.ToUpper(currency)
+	}
+	return "USD"
+}
+
+type Header struct {
+	Sender       Sender `xml:"Sender"`
+	SentDateTime string `xml:"SentDateTime"`
+}
+
+type Sender struct {
+	SenderName   string `xml:"SenderName,omitempty"`
+	EmailAddress string `xml:"EmailAddress,omitempty"`
+}
+
+// SentDateTime formats the time a message is sent at as ONIX does
+func SentDateTime(t time.Time) string {
+	return t.UTC().Format("20060102T1504Z")
+}
+
+type Product struct {
+	RecordReference    string              `xml:"RecordReference"`
+	NotificationType   string              `xml:"NotificationType"`
+	ProductIdentifiers []ProductIdentifier `xml:"ProductIdentifier"`
+	DescriptiveDetail  DescriptiveDetail   `xml:"DescriptiveDetail"`
+	CollateralDetail   *CollateralDetail   `xml:"CollateralDetail,omitempty"`
+	PublishingDetail   *PublishingDetail   `xml:"PublishingDetail,omitempty"`
+	ProductSupplies    []ProductSupply     `xml:"ProductSupply"`
+}
+
+type ProductIdentifier struct {
+	ProductIDType string `xml:"ProductIDType"`
+	IDTypeName    string `xml:"IDTypeName,omitempty"`
+	IDValue       string `xml:"IDValue"`
+}
+
+type DescriptiveDetail struct {
+	ProductComposition string        `xml:"ProductComposition"`
+	ProductForm        string        `xml:"ProductForm"`
+	TitleDetails       []TitleDetail `xml:"TitleDetail"`
+	Contributors       []Contributor `xml:"Contributor"`
+	Extents            []Extent      `xml:"Extent"`
+	Subjects           []Subject     `xml:"Subject"`
+}
+
+type TitleDetail struct {
+	TitleType     string         `xml:"TitleType"`
+	TitleElements []TitleElement `xml:"TitleElement"`
+}
+
+type TitleElement struct {
+	TitleElementLevel  string `xml:"TitleElementLevel"`
+	TitleText          string `xml:"TitleText,omitempty"`
+	TitlePrefix        string `xml:"TitlePrefix,omitempty"`
+	TitleWithoutPrefix string `xml:"TitleWithoutPrefix,omitempty"`
+	Subtitle           string `xml:"Subtitle,omitempty"`
+}
+
+// Text is the title as it is printed, from TitleText or from the prefix and the title without it
+func (element TitleElement) Text() string {
+	if element.TitleText != "" {
+		return strings.TrimSpace(element.TitleText)
+	}
+	return strings.TrimSpace(element.TitlePrefix + " " + element.TitleWithoutPrefix)
+}
+
+type Contributor struct {
+	SequenceNumber     int      `xml:"SequenceNumber,omitempty"`
+	ContributorRoles   []string `xml:"ContributorRole"`
+	PersonName         string   `xml:"PersonName,omitempty"`
+	PersonNameInverted string   `xml:"PersonNameInverted,omitempty"`
+	NamesBeforeKey     string   `xml:"NamesBeforeKey,omitempty"`
+	KeyNames           string   `xml:"KeyNames,omitempty"`
+}
+
+// HasRole reports whether the contributor has the role, one of code list 17
+func (contributor Contributor) HasRole(role string) bool {
+	for _, code := range contributor.ContributorRoles {
+		if code == role {
+			return true
+		}
+	}
+	return false
+}
+
+type Extent struct {
+	ExtentType  string `xml:"ExtentType"`
+	ExtentValue string `xml:"ExtentValue"`
+	ExtentUnit  string `xml:"ExtentUnit"`
+}
+
+type Subject struct {
+	SubjectSchemeIdentifier string `xml:"SubjectSchemeIdentifier"`
+	SubjectSchemeName       string `xml:"SubjectSchemeName,omitempty"`
+	SubjectCode             string `xml:"SubjectCode,omitempty"`
+	SubjectHeadingText      string `xml:"SubjectHeadingText,omitempty"`
+}
+
+type CollateralDetail struct {
+	TextContents        []TextContent        `xml:"TextContent"`
+	SupportingResources []SupportingResource `xml:"SupportingResource"`
+}
+
+type TextContent struct {
+	TextType        string `xml:"TextType"`
+	ContentAudience string `xml:"ContentAudience"`
+	Text            Text   `xml:"Text"`
+}
+
+// Text is a text of a product. It is written as plain text, marked up texts are read as the
+// plain text they show, see UnmarshalXML.
+type Text struct {
+	Format string `xml:"textformat,attr,omitempty"`
+	Value  string `xml:",chardata"`
+}
+
+type SupportingResource struct {
+	ResourceContentType string            `xml:"ResourceContentType"`
+	ContentAudience     string            `xml:"ContentAudience"`
+	ResourceMode        string            `xml:"ResourceMode"`
+	ResourceVersions    []ResourceVersion `xml:"ResourceVersion"`
+}
+
+type ResourceVersion struct {
+	ResourceForm string `xml:"ResourceForm"`
+	ResourceLink string `xml:"ResourceLink"`
+}
+
+type PublishingDetail struct {
+	Publishers       []Publisher      `xml:"Publisher"`
+	PublishingStatus string           `xml:"PublishingStatus,omitempty"`
+	PublishingDates  []PublishingDate `xml:"PublishingDate"`
+}
+
+type Publisher struct {
+	PublishingRole string `xml:"PublishingRole"`
+	PublisherName  string `xml:"PublisherName"`
+}
+
+type PublishingDate struct {
+	PublishingDateRole string `xml:"PublishingDateRole"`
+	Date               Date   `xml:"Date"`
+}
+
+// Date is a date in one of the formats of code list 55, YYYYMMDD unless it says otherwise
+type Date struct {
+	Format string `xml:"dateformat,attr,omitempty"`
+	Value  string `xml:",chardata"`
+}
+
+type ProductSupply struct {
+	SupplyDetails []SupplyDetail `xml:"SupplyDetail"`
+}
+
+type SupplyDetail struct {
+	Supplier            Supplier `xml:"Supplier"`
+	ProductAvailability string   `xml:"ProductAvailability"`
+	Prices              []Price  `xml:"Price"`
+}
+
+type Supplier struct {
+	SupplierRole string `xml:"SupplierRole"`
+	SupplierName string `xml:"SupplierName,omitempty"`
+}
+
+type Price struct {
+	PriceType    string `xml:"PriceType,omitempty"`
+	PriceAmount  string `xml:"PriceAmount"`
+	CurrencyCode string `xml:"CurrencyCode,omitempty"`
+}
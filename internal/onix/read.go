@@ -0,0 +1,219 @@
+package onix
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is a problem with an ONIX message that keeps the rest of it from being read
+type Error struct {
+	Line    int // 0 when the problem is not on a line
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// Decoder reads the products of an ONIX message one at a time, so a message of any size is
+// read with one product in memory
+type Decoder struct {
+	xml *xml.Decoder
+
+	// Header of the message, set once the products after it are read
+	Header Header
+
+	started bool
+}
+
+// NewDecoder creates a decoder of the ONIX message read from r. Messages are read in UTF-8
+// or ISO-8859-1 and may use the entities of HTML, as ONIX allows.
+func NewDecoder(r io.Reader) *Decoder {
+	decoder := xml.NewDecoder(bufio.NewReader(r))
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = charsetReader
+	return &Decoder{xml: decoder}
+}
+
+// Next returns the next product of the message and the line it starts on. It returns io.EOF
+// after the last product.
+func (d *Decoder) Next() (*Product, int, error) {
+	for {
+		line, _ := d.xml.InputPos()
+		token, err := d.xml.Token()
+		if errors.Is(err, io.EOF) {
+			if !d.started {
+				return nil, 0, &Error{Message: "the file holds no ONIX message"}
+			}
+			return nil, 0, io.EOF
+		}
+		if err != nil {
+			return nil, 0, d.syntaxError(err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if !d.started {
+			if err := checkRoot(start); err != nil {
+				return nil, 0, err
+			}
+			d.started = true
+			continue
+		}
+
+		switch start.Name.Local {
+		case "Header":
+			if err := d.xml.DecodeElement(&d.Header, &start); err != nil {
+				return nil, 0, d.syntaxError(err)
+			}
+		case "Product":
+			var product Product
+			if err := d.xml.DecodeElement(&product, &start); err != nil {
+				return nil, 0, d.syntaxError(err)
+			}
+			return &product, line, nil
+		default:
+			if err := d.xml.Skip(); err != nil {
+				return nil, 0, d.syntaxError(err)
+			}
+		}
+	}
+}
+
+// checkRoot makes sure a message is ONIX 3.0 with reference tags
+func checkRoot(start xml.StartElement) error {
+	switch start.Name.Local {
+	case "ONIXMessage":
+	case "ONIXmessage":
+		return &Error{Message: "ONIX messages with short tags are not supported, send reference tags"}
+	default:
+		return &Error{Message: fmt.Sprintf("the file is not an ONIX message, it starts with <%s>", start.Name.Local)}
+	}
+
+	release := "2.1 or older"
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "release" {
+			release = attr.Value
+		}
+	}
+	if !strings.HasPrefix(release, "3.") {
+		return &Error{Message: fmt.Sprintf("only ONIX %s is supported, the message is release %s", Release, release)}
+	}
+	return nil
+}
+
+// syntaxError reports malformed XML with the line it went wrong on
+func (d *Decoder) syntaxError(err error) error {
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &Error{Line: syntaxErr.Line, Message: syntaxErr.Msg}
+	}
+	line, _ := d.xml.InputPos()
+	return &Error{Line: line, Message: err.Error()}
+}
+
+// charsetReader decodes the encodings besides UTF-8 that ONIX messages are sent in
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8":
+		return input, nil
+	case "iso-8859-1", "latin1", "latin-1":
+		return &latin1Reader{r: bufio.NewReader(input)}, nil
+	}
+	return nil, fmt.Errorf("encoding %q is not supported, use UTF-8 or ISO-8859-1", charset)
+}
+
+// latin1Reader turns ISO-8859-1 into UTF-8, each byte is the code point of its character
+type latin1Reader struct {
+	r       io.ByteReader
+	pending []byte
+}
+
+func (r *latin1Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.pending) > 0 {
+			copied := copy(p[n:], r.pending)
+			r.pending = r.pending[copied:]
+			n += copied
+			continue
+		}
+
+		b, err := r.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		r.pending = utf8.AppendRune(nil, rune(b))
+	}
+	return n, nil
+}
+
+// blockTags end a line of a marked up text
+var blockTags = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/h[1-6])\b[^>]*>`)
+
+// markup is what is left of the tags of a marked up text
+var markup = regexp.MustCompile(`<[^>]*>`)
+
+// UnmarshalXML reads a text as the plain text it shows. XHTML elements in it and HTML
+// escaped in it, usually in a CDATA section, are taken out with lines kept between blocks.
+func (text *Text) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "textformat" {
+			text.Format = attr.Value
+		}
+	}
+
+	var content strings.Builder
+	depth := 0
+	for depth >= 0 {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.CharData:
+			content.Write(token)
+		case xml.StartElement:
+			depth++
+			if token.Name.Local == "br" {
+				content.WriteString("\n")
+			}
+		case xml.EndElement:
+			depth--
+			if depth >= 0 && blockTags.MatchString("</"+token.Name.Local+">") {
+				content.WriteString("\n")
+			}
+		}
+	}
+
+	value := content.String()
+	if text.Format == TextFormatHTML || text.Format == TextFormatXHTML {
+		value = blockTags.ReplaceAllString(value, "\n")
+		value = html.UnescapeString(markup.ReplaceAllString(value, ""))
+	}
+
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	text.Value = strings.Join(lines, "\n")
+	return nil
+}
@@ -0,0 +1,176 @@
+package onix
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go-playground/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+func testBook() *models.Book {
+	return &models.Book{
+		Model:         gorm.Model{ID: 12},
+		Title:         "The Left Hand of Darkness",
+		PublishedDate: time.Date(1969, 3, 1, 0, 0, 0, 0, time.UTC),
+		Pages:         304,
+		Description:   "A lone envoy is sent to Gethen.\nThe people there have no fixed sex.",
+		ISBN:          "978-0-441-47812-5",
+		Price:         9.99,
+		Author:        models.Author{FirstName: "Ursula K.", LastName: "Le Guin"},
+		Cover:         models.Cover{ImageURL: sql.NullString{String: "https://example.com/covers/12.jpg", Valid: true}},
+		Genres:        []*models.Genre{{Name: "Science Fiction", Slug: "science-fiction"}},
+	}
+}
+
+// readAll reads every product of a message, failing on the first error
+func readAll(data string) (*Decoder, []*Product, error) {
+	decoder := NewDecoder(strings.NewReader(data))
+	var products []*Product
+	for {
+		product, _, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			return decoder, products, nil
+		}
+		if err != nil {
+			return decoder, products, err
+		}
+		products = append(products, product)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	digital := testBook()
+	digital.ID = 13
+	digital.DigitalOnly = true
+	digital.ISBN = "0-441-47812-3"
+	digital.Description = ""
+	digital.Cover = models.Cover{}
+	digital.Genres = nil
+
+	want := []*Product{ProductOf(testBook(), "Ace Books", "EUR"), ProductOf(digital, "Ace Books", "EUR")}
+	header := Header{Sender: Sender{SenderName: "Ace Books", EmailAddress: "feeds@example.com"}, SentDateTime: SentDateTime(time.Now())}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, product := range want {
+		if err := w.Write(product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	decoder, got, err := readAll(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoder.Header, header) {
+		t.Errorf("read header %+v, want %+v", decoder.Header, header)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("read %d products, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("product %d reads back as\n%+v\nwant\n%+v", i+1, got[i], want[i])
+		}
+	}
+
+	// The identifiers name the ISBN as it is, without hyphens
+	if ids := got[1].ProductIdentifiers; len(ids) != 2 || ids[1].ProductIDType != ProductIDISBN10 || ids[1].IDValue != "0441478123" {
+		t.Errorf("the ISBN-10 is identified as %+v", ids)
+	}
+	if got[1].DescriptiveDetail.ProductForm != FormDigitalDownload || got[1].CollateralDetail != nil {
+		t.Errorf("the digital book is written as %+v", got[1])
+	}
+}
+
+func TestDecoderRejectsMalformedMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		line    int
+		message string
+	}{
+		{"empty file", "", 0, "holds no ONIX message"},
+		{"text", "not xml at all", 0, "holds no ONIX message"},
+		{"other XML", `<?xml version="1.0"?><rss version="2.0"><channel/></rss>`, 0, "not an ONIX message, it starts with <rss>"},
+		{"short tags", `<ONIXmessage release="3.0"><header/></ONIXmessage>`, 0, "short tags are not supported"},
+		{"ONIX 2.1", `<ONIXMessage release="2.1"><Product/></ONIXMessage>`, 0, "the message is release 2.1"},
+		{"without release", `<ONIXMessage><Product/></ONIXMessage>`, 0, "release 2.1 or older"},
+		{"unsupported encoding", `<?xml version="1.0" encoding="windows-1252"?><ONIXMessage release="3.0"/>`, 1, `encoding "windows-1252" is not supported`},
+		{"mismatched element", "<ONIXMessage release=\"3.0\">\n<Product>\n<RecordReference>1</Product>\n</ONIXMessage>", 3, "element <RecordReference> closed by </Product>"},
+		{"truncated", "<ONIXMessage release=\"3.0\">\n<Product>\n<RecordReference>1</RecordReference>", 3, "unexpected EOF"},
+		{"unknown entity", "<ONIXMessage release=\"3.0\">\n<Product><RecordReference>&bogus;</RecordReference></Product></ONIXMessage>", 2, "invalid character entity &bogus;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readAll(tt.data)
+			var onixErr *Error
+			if !errors.As(err, &onixErr) {
+				t.Fatalf("reading returned %v, want an *Error", err)
+			}
+			if !strings.Contains(onixErr.Message, tt.message) {
+				t.Errorf("error %q does not contain %q", onixErr.Message, tt.message)
+			}
+			if onixErr.Line != tt.line {
+				t.Errorf("error is on line %d, want %d", onixErr.Line, tt.line)
+			}
+		})
+	}
+}
+
+func TestDecoderReadsMessages(t *testing.T) {
+	message := `<?xml version="1.0" encoding="ISO-8859-1"?>
+<ONIXMessage release="3.0.8" xmlns="http://ns.editeur.org/onix/3.0/reference">
+  <Header><Sender><SenderName>Gallimard</SenderName></Sender><SentDateTime>20250101T0900Z</SentDateTime></Header>
+  <Extension>skipped with <Nested>everything</Nested> in it</Extension>
+  <Product>
+    <RecordReference>r1</RecordReference>
+    <DescriptiveDetail>
+      <TitleDetail><TitleType>01</TitleType><TitleElement><TitleElementLevel>01</TitleElementLevel><TitlePrefix>L'</TitlePrefix><TitleWithoutPrefix>` + "\xc9" + `tranger</TitleWithoutPrefix></TitleElement></TitleDetail>
+      <Unknown>also skipped</Unknown>
+    </DescriptiveDetail>
+    <CollateralDetail>
+      <TextContent><TextType>03</TextType><Text textformat="05"><p>First   paragraph</p><p>Second<br/>line &eacute;</p></Text></TextContent>
+      <TextContent><TextType>02</TextType><Text textformat="02"><![CDATA[<b>Bold</b> &amp; <i>plain</i><br>next]]></Text></TextContent>
+    </CollateralDetail>
+  </Product>
+</ONIXMessage>`
+
+	decoder, products, err := readAll(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoder.Header.Sender.SenderName != "Gallimard" {
+		t.Errorf("read header %+v", decoder.Header)
+	}
+	if len(products) != 1 {
+		t.Fatalf("read %d products, want 1", len(products))
+	}
+
+	product := products[0]
+	if title := product.DescriptiveDetail.TitleDetails[0].TitleElements[0].Text(); title != "L' Étranger" {
+		t.Errorf("read title %q from ISO-8859-1, want %q", title, "L' Étranger")
+	}
+	texts := product.CollateralDetail.TextContents
+	if got, want := texts[0].Text.Value, "First paragraph\nSecond\nline é"; got != want {
+		t.Errorf("read XHTML text %q, want %q", got, want)
+	}
+	if got, want := texts[1].Text.Value, "Bold & plain\nnext"; got != want {
+		t.Errorf("read HTML text %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package onix
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+)
+
+// bufferSize is how much of a message is collected before it is written out
+const bufferSize = 32 << 10
+
+// Writer writes an ONIX message one product at a time
+type Writer struct {
+	buffer  *bufio.Writer
+	encoder *xml.Encoder
+}
+
+// NewWriter starts an ONIX 3.0 message with reference tags with the header
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	buffer := bufio.NewWriterSize(w, bufferSize)
+	if _, err := buffer.WriteString(xml.Header); err != nil {
+		return nil, err
+	}
+
+	encoder := xml.NewEncoder(buffer)
+	encoder.Indent("", "  ")
+	root := xml.StartElement{
+		Name: xml.Name{Local: "ONIXMessage"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "release"}, Value: Release}, {Name: xml.Name{Local: "xmlns"}, Value: Namespace}},
+	}
+	if err := encoder.EncodeToken(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.EncodeElement(header, xml.StartElement{Name: xml.Name{Local: "Header"}}); err != nil {
+		return nil, err
+	}
+	return &Writer{buffer: buffer, encoder: encoder}, nil
+}
+
+// Write adds a product to the message
+func (w *Writer) Write(product *Product) error {
+	return w.encoder.EncodeElement(product, xml.StartElement{Name: xml.Name{Local: "Product"}})
+}
+
+// Close ends the message and writes out what is buffered. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "ONIXMessage"}}); err != nil {
+		return err
+	}
+	if err := w.encoder.Flush(); err != nil {
+		return err
+	}
+	if _, err := w.buffer.WriteString("\n"); err != nil {
+		return err
+	}
+	return w.buffer.Flush()
+}
@@ -0,0 +1,252 @@
+package opds
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Namespaces of OPDS 1.2 feeds
+const (
+	namespaceAtom       = "http://www.w3.org/2005/Atom"
+	namespaceDC         = "http://purl.org/dc/terms/"
+	namespaceOPDS       = "http://opds-spec.org/2010/catalog"
+	namespaceOpenSearch = "http://a9.com/-/spec/opensearch/1.1/"
+)
+
+// The elements and attributes of the other namespaces carry the prefixes declared on the
+// feed, encoding/xml would declare a namespace on every one of them
+type atomFeed struct {
+	XMLName         xml.Name `xml:"feed"`
+	Xmlns           string   `xml:"xmlns,attr"`
+	XmlnsDC         string   `xml:"xmlns:dc,attr"`
+	XmlnsOPDS       string   `xml:"xmlns:opds,attr"`
+	XmlnsOpenSearch string   `xml:"xmlns:opensearch,attr"`
+
+	ID           string      `xml:"id"`
+	Title        string      `xml:"title"`
+	Updated      string      `xml:"updated"`
+	Author       atomAuthor  `xml:"author"`
+	TotalResults *int64      `xml:"opensearch:totalResults"`
+	ItemsPerPage *int        `xml:"opensearch:itemsPerPage"`
+	StartIndex   *int        `xml:"opensearch:startIndex"`
+	Links        []atomLink  `xml:"link"`
+	Entries      []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomLink struct {
+	Rel         string     `xml:"rel,attr,omitempty"`
+	Href        string     `xml:"href,attr"`
+	Type        string     `xml:"type,attr,omitempty"`
+	Title       string     `xml:"title,attr,omitempty"`
+	FacetGroup  string     `xml:"opds:facetGroup,attr,omitempty"`
+	ActiveFacet string     `xml:"opds:activeFacet,attr,omitempty"`
+	Price       *atomPrice `xml:"opds:price"`
+}
+
+type atomPrice struct {
+	CurrencyCode string `xml:"currencycode,attr"`
+	Value        string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomAuthor   `xml:"author"`
+	Identifier string         `xml:"dc:identifier,omitempty"`
+	Issued     string         `xml:"dc:issued,omitempty"`
+	Extent     string         `xml:"dc:extent,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary"`
+	Content    *atomText      `xml:"content"`
+	Links      []atomLink     `xml:"link"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteAtom writes a feed as OPDS 1.2
+func WriteAtom(w io.Writer, feed *Feed) error {
+	out := atomFeed{
+		Xmlns:           namespaceAtom,
+		XmlnsDC:         namespaceDC,
+		XmlnsOPDS:       namespaceOPDS,
+		XmlnsOpenSearch: namespaceOpenSearch,
+		ID:              feed.ID,
+		Title:           feed.Title,
+		Updated:         atomTime(feed.Updated),
+		Author:          atomAuthor{Name: feed.Author},
+	}
+
+	out.Links = append(out.Links, atomLink{Rel: RelSelf, Href: feed.ID, Type: atomType(feed.Kind)})
+	for _, link := range feed.Links {
+		out.Links = append(out.Links, newAtomLink(link))
+	}
+	for _, group := range feed.Facets {
+		for _, facet := range group.Facets {
+			link := atomLink{Rel: RelFacet, Href: facet.Href, Type: MediaTypeAcquisition, Title: facet.Title, FacetGroup: group.Title}
+			if facet.Active {
+				link.ActiveFacet = "true"
+			}
+			out.Links = append(out.Links, link)
+		}
+	}
+
+	if page := feed.Page; page != nil {
+		out.ItemsPerPage = &page.Limit
+		if page.Total != nil {
+			startIndex := page.Offset + 1
+			out.TotalResults, out.StartIndex = page.Total, &startIndex
+		}
+	}
+
+	for _, navigation := range feed.Navigation {
+		rel := navigation.Rel
+		if rel == "" {
+			rel = RelSubsection
+		}
+		entry := atomEntry{
+			Title:   navigation.Title,
+			ID:      navigation.Href,
+			Updated: atomTime(navigation.Updated),
+			Links:   []atomLink{{Rel: rel, Href: navigation.Href, Type: atomType(navigation.Kind)}},
+		}
+		if navigation.Summary != "" {
+			entry.Content = &atomText{Type: "text", Value: navigation.Summary}
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+
+	for _, publication := range feed.Publications {
+		out.Entries = append(out.Entries, newAtomEntry(publication))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func newAtomEntry(publication Publication) atomEntry {
+	entry := atomEntry{
+		Title:   publication.Title,
+		ID:      publication.ID,
+		Updated: atomTime(publication.Modified),
+	}
+	if publication.ISBN != "" {
+		entry.Identifier = "urn:isbn:" + publication.ISBN
+	}
+	if !publication.Published.IsZero() {
+		entry.Issued = publication.Published.Format(time.DateOnly)
+	}
+	if publication.Pages > 0 {
+		entry.Extent = strconv.FormatUint(uint64(publication.Pages), 10) + " pages"
+	}
+	for _, author := range publication.Authors {
+		entry.Authors = append(entry.Authors, atomAuthor{Name: author.Name, URI: author.Href})
+	}
+	for _, subject := range publication.Subjects {
+		entry.Categories = append(entry.Categories, atomCategory{Term: subject.Code, Label: subject.Name})
+	}
+	if publication.Description != "" {
+		entry.Summary = &atomText{Type: "text", Value: publication.Description}
+	}
+
+	for _, image := range publication.Images {
+		rel := RelImage
+		if image.Thumbnail {
+			rel = RelThumbnail
+		}
+		entry.Links = append(entry.Links, atomLink{Rel: rel, Href: image.Href, Type: image.Type})
+	}
+	if publication.Alternate != "" {
+		entry.Links = append(entry.Links, atomLink{Rel: RelAlternate, Href: publication.Alternate, Type: "application/json"})
+	}
+	buy := publication.Buy
+	entry.Links = append(entry.Links, atomLink{
+		Rel:   RelBuy,
+		Href:  buy.Href,
+		Type:  buy.Type,
+		Price: &atomPrice{CurrencyCode: buy.Currency, Value: strconv.FormatFloat(float64(buy.Price), 'f', 2, 32)},
+	})
+	return entry
+}
+
+func newAtomLink(link Link) atomLink {
+	linkType := link.Type
+	if link.Kind != "" {
+		linkType = atomType(link.Kind)
+	}
+	return atomLink{Rel: link.Rel, Href: link.Href, Type: linkType, Title: link.Title}
+}
+
+// atomType is the media type of an OPDS 1.2 feed of the kind
+func atomType(kind string) string {
+	if kind == KindAcquisition {
+		return MediaTypeAcquisition
+	}
+	return MediaTypeNavigation
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+type openSearchDescription struct {
+	XMLName        xml.Name      `xml:"OpenSearchDescription"`
+	Xmlns          string        `xml:"xmlns,attr"`
+	ShortName      string        `xml:"ShortName"`
+	Description    string        `xml:"Description"`
+	InputEncoding  string        `xml:"InputEncoding"`
+	OutputEncoding string        `xml:"OutputEncoding"`
+	URL            openSearchURL `xml:"Url"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// WriteOpenSearch writes the OpenSearch description OPDS 1.2 clients search the catalog
+// with, template is the URL of the search feed with {searchTerms} for the query
+func WriteOpenSearch(w io.Writer, name string, template string) error {
+	out := openSearchDescription{
+		Xmlns:          namespaceOpenSearch,
+		ShortName:      name,
+		Description:    "Search the books of " + name,
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		URL:            openSearchURL{Type: MediaTypeAcquisition, Template: template},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
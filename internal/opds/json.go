@@ -0,0 +1,185 @@
+package opds
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type jsonFeed struct {
+	Metadata     jsonFeedMetadata   `json:"metadata"`
+	Links        []jsonLink         `json:"links"`
+	Navigation   []jsonLink         `json:"navigation,omitempty"`
+	Facets       []jsonFacetGroup   `json:"facets,omitempty"`
+	Publications *[]jsonPublication `json:"publications,omitempty"` // A pointer, omitempty leaves out empty slices
+}
+
+type jsonFeedMetadata struct {
+	Title         string    `json:"title"`
+	Modified      time.Time `json:"modified"`
+	NumberOfItems *int64    `json:"numberOfItems,omitempty"`
+	ItemsPerPage  *int      `json:"itemsPerPage,omitempty"`
+	CurrentPage   *int      `json:"currentPage,omitempty"`
+}
+
+type jsonLink struct {
+	Rel        string          `json:"rel,omitempty"`
+	Href       string          `json:"href"`
+	Type       string          `json:"type,omitempty"`
+	Title      string          `json:"title,omitempty"`
+	Templated  bool            `json:"templated,omitempty"`
+	Width      int             `json:"width,omitempty"`
+	Height     int             `json:"height,omitempty"`
+	Properties *jsonProperties `json:"properties,omitempty"`
+}
+
+type jsonProperties struct {
+	Price *jsonPrice `json:"price,omitempty"`
+}
+
+type jsonPrice struct {
+	Currency string  `json:"currency"`
+	Value    float32 `json:"value"`
+}
+
+type jsonFacetGroup struct {
+	Metadata struct {
+		Title string `json:"title"`
+	} `json:"metadata"`
+	Links []jsonLink `json:"links"`
+}
+
+type jsonPublication struct {
+	Metadata jsonPublicationMetadata `json:"metadata"`
+	Links    []jsonLink              `json:"links"`
+	Images   []jsonLink              `json:"images,omitempty"`
+}
+
+type jsonPublicationMetadata struct {
+	Type          string            `json:"@type"`
+	Identifier    string            `json:"identifier"`
+	Title         string            `json:"title"`
+	Author        []jsonContributor `json:"author,omitempty"`
+	Published     string            `json:"published,omitempty"`
+	Modified      time.Time         `json:"modified"`
+	Description   string            `json:"description,omitempty"`
+	NumberOfPages uint              `json:"numberOfPages,omitempty"`
+	Subject       []jsonSubject     `json:"subject,omitempty"`
+}
+
+type jsonContributor struct {
+	Name  string     `json:"name"`
+	Links []jsonLink `json:"links,omitempty"`
+}
+
+type jsonSubject struct {
+	Name  string     `json:"name"`
+	Code  string     `json:"code,omitempty"`
+	Links []jsonLink `json:"links,omitempty"`
+}
+
+// WriteJSON writes a feed as OPDS 2.0
+func WriteJSON(w io.Writer, feed *Feed) error {
+	out := jsonFeed{
+		Metadata: jsonFeedMetadata{Title: feed.Title, Modified: feed.Updated.UTC()},
+		Links:    []jsonLink{{Rel: RelSelf, Href: feed.ID, Type: MediaTypeJSON}},
+	}
+	for _, link := range feed.Links {
+		out.Links = append(out.Links, newJSONLink(link))
+	}
+
+	if page := feed.Page; page != nil {
+		out.Metadata.NumberOfItems, out.Metadata.ItemsPerPage = page.Total, &page.Limit
+		if page.Total != nil && page.Limit > 0 {
+			currentPage := page.Offset/page.Limit + 1
+			out.Metadata.CurrentPage = &currentPage
+		}
+	}
+
+	for _, navigation := range feed.Navigation {
+		rel := navigation.Rel
+		if rel == "" {
+			rel = RelSubsection
+		}
+		out.Navigation = append(out.Navigation, jsonLink{Rel: rel, Href: navigation.Href, Type: MediaTypeJSON, Title: navigation.Title})
+	}
+
+	for _, group := range feed.Facets {
+		facets := jsonFacetGroup{Links: []jsonLink{}}
+		facets.Metadata.Title = group.Title
+		for _, facet := range group.Facets {
+			link := jsonLink{Href: facet.Href, Type: MediaTypeJSON, Title: facet.Title}
+			if facet.Active {
+				link.Rel = RelSelf
+			}
+			facets.Links = append(facets.Links, link)
+		}
+		out.Facets = append(out.Facets, facets)
+	}
+
+	publications := []jsonPublication{}
+	for _, publication := range feed.Publications {
+		publications = append(publications, newJSONPublication(publication))
+	}
+	// A feed of publications is one even when it has none
+	if feed.Kind == KindAcquisition || len(publications) > 0 {
+		out.Publications = &publications
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(out)
+}
+
+func newJSONPublication(publication Publication) jsonPublication {
+	out := jsonPublication{
+		Metadata: jsonPublicationMetadata{
+			Type:          "http://schema.org/Book",
+			Identifier:    publication.ID,
+			Title:         publication.Title,
+			Modified:      publication.Modified.UTC(),
+			Description:   publication.Description,
+			NumberOfPages: publication.Pages,
+		},
+	}
+	if !publication.Published.IsZero() {
+		out.Metadata.Published = publication.Published.Format(time.DateOnly)
+	}
+	for _, author := range publication.Authors {
+		contributor := jsonContributor{Name: author.Name}
+		if author.Href != "" {
+			contributor.Links = []jsonLink{{Href: author.Href, Type: MediaTypeJSON}}
+		}
+		out.Metadata.Author = append(out.Metadata.Author, contributor)
+	}
+	for _, subject := range publication.Subjects {
+		item := jsonSubject{Name: subject.Name, Code: subject.Code}
+		if subject.Href != "" {
+			item.Links = []jsonLink{{Href: subject.Href, Type: MediaTypeJSON}}
+		}
+		out.Metadata.Subject = append(out.Metadata.Subject, item)
+	}
+
+	for _, image := range publication.Images {
+		out.Images = append(out.Images, jsonLink{Href: image.Href, Type: image.Type, Width: image.Width, Height: image.Height})
+	}
+	if publication.Alternate != "" {
+		out.Links = append(out.Links, jsonLink{Rel: RelAlternate, Href: publication.Alternate, Type: "application/json"})
+	}
+	buy := publication.Buy
+	out.Links = append(out.Links, jsonLink{
+		Rel:        RelBuy,
+		Href:       buy.Href,
+		Type:       buy.Type,
+		Properties: &jsonProperties{Price: &jsonPrice{Currency: buy.Currency, Value: buy.Price}},
+	})
+	return out
+}
+
+func newJSONLink(link Link) jsonLink {
+	linkType := link.Type
+	if link.Kind != "" {
+		linkType = MediaTypeJSON
+	}
+	return jsonLink{Rel: link.Rel, Href: link.Href, Type: linkType, Title: link.Title, Templated: link.Templated}
+}
@@ -0,0 +1,154 @@
+// Package opds describes the catalog as OPDS feeds for e-reader apps, as Atom for OPDS 1.2
+// and as JSON for OPDS 2.0. Both are written from the same Feed.
+// See https://specs.opds.io/opds-1.2 and https://drafts.opds.io/opds-2.0.
+package opds
+
+import "time"
+
+// Versions of OPDS the feeds are written in
+const (
+	Version1 = "v1.2"
+	Version2 = "v2"
+)
+
+// Kinds of feeds
+const (
+	KindNavigation  = "navigation"  // Links to other feeds
+	KindAcquisition = "acquisition" // Publications
+)
+
+// Media types of the feeds and documents
+const (
+	MediaTypeAtom        = "application/atom+xml"
+	MediaTypeNavigation  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	MediaTypeAcquisition = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	MediaTypeOpenSearch  = "application/opensearchdescription+xml"
+	MediaTypeJSON        = "application/opds+json"
+)
+
+// Relations of links
+const (
+	RelSelf       = "self"
+	RelStart      = "start"
+	RelUp         = "up"
+	RelSearch     = "search"
+	RelFirst      = "first"
+	RelPrevious   = "previous"
+	RelNext       = "next"
+	RelSubsection = "subsection"
+	RelAlternate  = "alternate"
+	RelImage      = "http://opds-spec.org/image"
+	RelThumbnail  = "http://opds-spec.org/image/thumbnail"
+	RelBuy        = "http://opds-spec.org/acquisition/buy"
+	RelFacet      = "http://opds-spec.org/facet"
+	RelSortNew    = "http://opds-spec.org/sort/new"
+)
+
+// Feed is a navigation or acquisition feed of the catalog, independent of the version it is
+// written in
+type Feed struct {
+	ID      string // Absolute URL of the feed
+	Title   string
+	Updated time.Time
+	Kind    string
+
+	// Author names the catalog, Atom feeds need one for the entries without an author
+	Author string
+
+	// Links to the feed itself, the start of the catalog, search and other pages
+	Links []Link
+
+	Navigation   []Navigation
+	Publications []Publication
+	Facets       []FacetGroup
+
+	// Page of a paginated feed, nil for the others
+	Page *Page
+}
+
+// Link of a feed. Links to other feeds give their Kind, their type depends on the version
+// the feed is written in, other links give their Type.
+type Link struct {
+	Rel       string
+	Href      string
+	Kind      string
+	Type      string
+	Title     string
+	Templated bool // Href is a URI template (RFC 6570), as search links of OPDS 2.0 are
+}
+
+// Navigation is an entry of a navigation feed linking to another feed
+type Navigation struct {
+	Title   string
+	Href    string
+	Rel     string // RelSubsection unless set
+	Kind    string // Kind of the feed linked to
+	Summary string
+	Updated time.Time
+}
+
+// Page tells where a page of a paginated feed is within all its items. Total is nil on
+// pages continued after a cursor, where Offset is not known either.
+type Page struct {
+	Total  *int64
+	Limit  int
+	Offset int
+}
+
+// FacetGroup is a set of alternative views of an acquisition feed, such as its genres
+type FacetGroup struct {
+	Title  string
+	Facets []Facet
+}
+
+type Facet struct {
+	Title  string
+	Href   string
+	Active bool // The feed is this view
+}
+
+// Publication is an entry of an acquisition feed
+type Publication struct {
+	ID          string // urn:isbn of the book, or the URL of the book without an ISBN
+	Title       string
+	Authors     []Contributor
+	ISBN        string
+	Published   time.Time
+	Modified    time.Time
+	Description string
+	Pages       uint
+	Subjects    []Subject
+	Images      []Image
+
+	// Alternate links the book in the API
+	Alternate string
+	Buy       Acquisition
+}
+
+type Contributor struct {
+	Name string
+	Href string // Acquisition feed of the books of the contributor
+}
+
+type Subject struct {
+	Name string
+	Code string
+	Href string // Acquisition feed of the books of the subject
+}
+
+// Image of the cover of a publication, the thumbnails are the smaller ones
+type Image struct {
+	Href      string
+	Type      string
+	Width     int
+	Height    int
+	Thumbnail bool
+}
+
+// Acquisition is where a publication is bought and for how much
+type Acquisition struct {
+	Href     string
+	Type     string
+	Price    float32
+	Currency string
+}
@@ -0,0 +1,366 @@
+package opds
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFeed is a page of an acquisition feed with a publication whose text needs escaping
+func testFeed() *Feed {
+	total := int64(45)
+	return &Feed{
+		ID:      "https://books.example.com/opds/v1.2/books?offset=20",
+		Title:   "Books",
+		Updated: time.Date(2025, 4, 2, 10, 30, 0, 0, time.FixedZone("CEST", 2*60*60)),
+		Kind:    KindAcquisition,
+		Author:  "Playground",
+		Links: []Link{
+			{Rel: RelStart, Href: "https://books.example.com/opds/v1.2", Kind: KindNavigation},
+			{Rel: RelSearch, Href: "https://books.example.com/opds/search.xml", Type: MediaTypeOpenSearch},
+			{Rel: RelNext, Href: "https://books.example.com/opds/v1.2/books?offset=30&limit=10", Kind: KindAcquisition},
+		},
+		Facets: []FacetGroup{{Title: "Genres", Facets: []Facet{
+			{Title: "All", Href: "https://books.example.com/opds/v1.2/books", Active: true},
+			{Title: "Horror", Href: "https://books.example.com/opds/v1.2/genres/horror"},
+		}}},
+		Publications: []Publication{{
+			ID:          "urn:isbn:9780441478125",
+			Title:       "Tom & Jerry <Collected>",
+			Authors:     []Contributor{{Name: "Ursula K. Le Guin", Href: "https://books.example.com/opds/v1.2/authors/3"}, {Name: "Anonymous"}},
+			ISBN:        "9780441478125",
+			Published:   time.Date(1969, 3, 1, 0, 0, 0, 0, time.UTC),
+			Modified:    time.Date(2025, 1, 5, 8, 0, 0, 0, time.UTC),
+			Description: "Cats & mice",
+			Pages:       304,
+			Subjects:    []Subject{{Name: "Science Fiction", Code: "science-fiction", Href: "https://books.example.com/opds/v1.2/genres/science-fiction"}},
+			Images: []Image{
+				{Href: "https://books.example.com/covers/12.jpg", Type: "image/jpeg", Width: 600, Height: 900},
+				{Href: "https://books.example.com/covers/12-thumb.jpg", Type: "image/jpeg", Width: 200, Height: 300, Thumbnail: true},
+			},
+			Alternate: "https://books.example.com/books/12",
+			Buy:       Acquisition{Href: "https://books.example.com/books/12", Type: "text/html", Price: 9.9, Currency: "EUR"},
+		}},
+		Page: &Page{Total: &total, Limit: 10, Offset: 20},
+	}
+}
+
+// The feeds are read back with the namespaces resolved, as clients read them
+type readAtomFeed struct {
+	XMLName      xml.Name       `xml:"http://www.w3.org/2005/Atom feed"`
+	ID           string         `xml:"id"`
+	Updated      string         `xml:"updated"`
+	Author       string         `xml:"author>name"`
+	TotalResults *int64         `xml:"http://a9.com/-/spec/opensearch/1.1/ totalResults"`
+	ItemsPerPage *int           `xml:"http://a9.com/-/spec/opensearch/1.1/ itemsPerPage"`
+	StartIndex   *int           `xml:"http://a9.com/-/spec/opensearch/1.1/ startIndex"`
+	Links        []readAtomLink `xml:"link"`
+	Entries      []struct {
+		Title      string         `xml:"title"`
+		ID         string         `xml:"id"`
+		Updated    string         `xml:"updated"`
+		Authors    []atomAuthor   `xml:"author"`
+		Identifier string         `xml:"http://purl.org/dc/terms/ identifier"`
+		Issued     string         `xml:"http://purl.org/dc/terms/ issued"`
+		Extent     string         `xml:"http://purl.org/dc/terms/ extent"`
+		Categories []atomCategory `xml:"category"`
+		Summary    string         `xml:"summary"`
+		Content    string         `xml:"content"`
+		Links      []readAtomLink `xml:"link"`
+	} `xml:"entry"`
+}
+
+type readAtomLink struct {
+	Rel         string `xml:"rel,attr"`
+	Href        string `xml:"href,attr"`
+	Type        string `xml:"type,attr"`
+	FacetGroup  string `xml:"http://opds-spec.org/2010/catalog facetGroup,attr"`
+	ActiveFacet string `xml:"http://opds-spec.org/2010/catalog activeFacet,attr"`
+	Price       *struct {
+		CurrencyCode string `xml:"currencycode,attr"`
+		Value        string `xml:",chardata"`
+	} `xml:"http://opds-spec.org/2010/catalog price"`
+}
+
+func writeAtom(t *testing.T, feed *Feed) readAtomFeed {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := WriteAtom(&buf, feed); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("the feed starts with %.40q, want the XML declaration", buf.String())
+	}
+
+	var out readAtomFeed
+	if err := xml.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("the feed is not well-formed: %v\n%s", err, buf.String())
+	}
+	return out
+}
+
+func TestWriteAtom(t *testing.T) {
+	out := writeAtom(t, testFeed())
+
+	if out.Updated != "2025-04-02T08:30:00Z" {
+		t.Errorf("feed updated %q, want it in UTC", out.Updated)
+	}
+	if out.Author != "Playground" {
+		t.Errorf("feed author %q", out.Author)
+	}
+	if out.TotalResults == nil || *out.TotalResults != 45 || out.StartIndex == nil || *out.StartIndex != 21 || out.ItemsPerPage == nil || *out.ItemsPerPage != 10 {
+		t.Errorf("feed paginated with total %v, start %v and items %v, want 45, 21 and 10", out.TotalResults, out.StartIndex, out.ItemsPerPage)
+	}
+
+	links := []readAtomLink{
+		{Rel: RelSelf, Href: "https://books.example.com/opds/v1.2/books?offset=20", Type: MediaTypeAcquisition},
+		{Rel: RelStart, Href: "https://books.example.com/opds/v1.2", Type: MediaTypeNavigation},
+		{Rel: RelSearch, Href: "https://books.example.com/opds/search.xml", Type: MediaTypeOpenSearch},
+		{Rel: RelNext, Href: "https://books.example.com/opds/v1.2/books?offset=30&limit=10", Type: MediaTypeAcquisition},
+		{Rel: RelFacet, Href: "https://books.example.com/opds/v1.2/books", Type: MediaTypeAcquisition, FacetGroup: "Genres", ActiveFacet: "true"},
+		{Rel: RelFacet, Href: "https://books.example.com/opds/v1.2/genres/horror", Type: MediaTypeAcquisition, FacetGroup: "Genres"},
+	}
+	if !reflect.DeepEqual(out.Links, links) {
+		t.Errorf("feed links\n%+v\nwant\n%+v", out.Links, links)
+	}
+
+	if len(out.Entries) != 1 {
+		t.Fatalf("feed has %d entries, want 1", len(out.Entries))
+	}
+	entry := out.Entries[0]
+	if entry.Title != "Tom & Jerry <Collected>" || entry.Summary != "Cats & mice" {
+		t.Errorf("entry text reads back as %q and %q", entry.Title, entry.Summary)
+	}
+	if entry.Identifier != "urn:isbn:9780441478125" || entry.Issued != "1969-03-01" || entry.Extent != "304 pages" {
+		t.Errorf("entry described as %q, %q and %q", entry.Identifier, entry.Issued, entry.Extent)
+	}
+	authors := []atomAuthor{{Name: "Ursula K. Le Guin", URI: "https://books.example.com/opds/v1.2/authors/3"}, {Name: "Anonymous"}}
+	if !reflect.DeepEqual(entry.Authors, authors) {
+		t.Errorf("entry authors %+v, want %+v", entry.Authors, authors)
+	}
+	if categories := []atomCategory{{Term: "science-fiction", Label: "Science Fiction"}}; !reflect.DeepEqual(entry.Categories, categories) {
+		t.Errorf("entry categories %+v, want %+v", entry.Categories, categories)
+	}
+
+	rels := make([]string, len(entry.Links))
+	for i, link := range entry.Links {
+		rels[i] = link.Rel
+	}
+	if want := []string{RelImage, RelThumbnail, RelAlternate, RelBuy}; !reflect.DeepEqual(rels, want) {
+		t.Errorf("entry links %v, want %v", rels, want)
+	}
+	if price := entry.Links[3].Price; price == nil || price.CurrencyCode != "EUR" || price.Value != "9.90" {
+		t.Errorf("entry priced at %+v, want 9.90 EUR", price)
+	}
+}
+
+func TestWriteAtomPages(t *testing.T) {
+	total := int64(0)
+	tests := []struct {
+		name  string
+		page  *Page
+		total *int64
+		start *int
+		items *int
+	}{
+		{"not paginated", nil, nil, nil, nil},
+		{"empty", &Page{Total: &total, Limit: 10}, &total, ptr(1), ptr(10)},
+		{"after a cursor", &Page{Limit: 25}, nil, nil, ptr(25)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := testFeed()
+			feed.Page = tt.page
+			out := writeAtom(t, feed)
+			if !reflect.DeepEqual(out.TotalResults, tt.total) || !reflect.DeepEqual(out.StartIndex, tt.start) || !reflect.DeepEqual(out.ItemsPerPage, tt.items) {
+				t.Errorf("feed paginated with total %v, start %v and items %v", out.TotalResults, out.StartIndex, out.ItemsPerPage)
+			}
+		})
+	}
+}
+
+func TestWriteAtomNavigation(t *testing.T) {
+	feed := &Feed{
+		ID:      "https://books.example.com/opds/v1.2",
+		Title:   "Catalog",
+		Updated: time.Date(2025, 4, 2, 8, 30, 0, 0, time.UTC),
+		Kind:    KindNavigation,
+		Navigation: []Navigation{
+			{Title: "New books", Href: "https://books.example.com/opds/v1.2/new", Rel: RelSortNew, Kind: KindAcquisition, Summary: "Latest first"},
+			{Title: "Genres", Href: "https://books.example.com/opds/v1.2/genres", Kind: KindNavigation},
+		},
+	}
+	out := writeAtom(t, feed)
+
+	if len(out.Entries) != 2 {
+		t.Fatalf("feed has %d entries, want 2", len(out.Entries))
+	}
+	if out.Links[0].Type != MediaTypeNavigation {
+		t.Errorf("navigation feed links itself as %q", out.Links[0].Type)
+	}
+	if link := out.Entries[0].Links[0]; link.Rel != RelSortNew || link.Type != MediaTypeAcquisition || out.Entries[0].Content != "Latest first" {
+		t.Errorf("first entry %+v", out.Entries[0])
+	}
+	if link := out.Entries[1].Links[0]; link.Rel != RelSubsection || link.Type != MediaTypeNavigation {
+		t.Errorf("second entry links %+v, want a subsection", link)
+	}
+}
+
+func TestWriteOpenSearch(t *testing.T) {
+	var buf bytes.Buffer
+	template := "https://books.example.com/opds/v1.2/search?q={searchTerms}&limit=20"
+	if err := WriteOpenSearch(&buf, "Playground", template); err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		XMLName   xml.Name `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+		ShortName string   `xml:"ShortName"`
+		URL       struct {
+			Type     string `xml:"type,attr"`
+			Template string `xml:"template,attr"`
+		} `xml:"Url"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("the description is not well-formed: %v\n%s", err, buf.String())
+	}
+	if out.ShortName != "Playground" || out.URL.Template != template || out.URL.Type != MediaTypeAcquisition {
+		t.Errorf("description reads back as %+v", out)
+	}
+}
+
+// writeJSON writes a feed as OPDS 2.0 and decodes it to plain values
+func writeJSON(t *testing.T, feed *Feed) (string, map[string]any) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, feed); err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("the feed is not valid JSON: %v\n%s", err, buf.String())
+	}
+	return buf.String(), out
+}
+
+func TestWriteJSON(t *testing.T) {
+	data, out := writeJSON(t, testFeed())
+
+	if strings.Contains(data, `\u0026`) || !strings.Contains(data, "offset=30&limit=10") {
+		t.Error("the links are written with & escaped")
+	}
+
+	metadata := out["metadata"].(map[string]any)
+	wantMetadata := map[string]any{
+		"title":         "Books",
+		"modified":      "2025-04-02T08:30:00Z",
+		"numberOfItems": 45.0,
+		"itemsPerPage":  10.0,
+		"currentPage":   3.0,
+	}
+	if !reflect.DeepEqual(metadata, wantMetadata) {
+		t.Errorf("feed metadata %v, want %v", metadata, wantMetadata)
+	}
+
+	links := out["links"].([]any)
+	if self := links[0].(map[string]any); self["rel"] != RelSelf || self["type"] != MediaTypeJSON {
+		t.Errorf("first link %v, want the feed itself", self)
+	}
+	if start := links[1].(map[string]any); start["type"] != MediaTypeJSON {
+		t.Errorf("start link %v, want the JSON feed", start)
+	}
+	if search := links[2].(map[string]any); search["type"] != MediaTypeOpenSearch {
+		t.Errorf("search link %v keeps its type", search)
+	}
+
+	facets := out["facets"].([]any)[0].(map[string]any)["links"].([]any)
+	if active := facets[0].(map[string]any); active["rel"] != RelSelf {
+		t.Errorf("active facet %v, want it linked as self", active)
+	}
+	if _, ok := facets[1].(map[string]any)["rel"]; ok {
+		t.Errorf("inactive facet %v has a rel", facets[1])
+	}
+
+	publication := out["publications"].([]any)[0].(map[string]any)
+	publicationMetadata := publication["metadata"].(map[string]any)
+	if publicationMetadata["title"] != "Tom & Jerry <Collected>" || publicationMetadata["published"] != "1969-03-01" || publicationMetadata["numberOfPages"] != 304.0 {
+		t.Errorf("publication metadata %v", publicationMetadata)
+	}
+	authors := publicationMetadata["author"].([]any)
+	if _, ok := authors[1].(map[string]any)["links"]; ok {
+		t.Errorf("author without a feed has links %v", authors[1])
+	}
+	buy := publication["links"].([]any)[1].(map[string]any)
+	price := buy["properties"].(map[string]any)["price"].(map[string]any)
+	if buy["rel"] != RelBuy || price["currency"] != "EUR" || price["value"] != 9.9 {
+		t.Errorf("buy link %v", buy)
+	}
+	if images := publication["images"].([]any); len(images) != 2 || images[1].(map[string]any)["width"] != 200.0 {
+		t.Errorf("publication images %v", images)
+	}
+}
+
+func TestWriteJSONPages(t *testing.T) {
+	total := int64(0)
+	tests := []struct {
+		name     string
+		page     *Page
+		metadata []string
+	}{
+		{"not paginated", nil, []string{"modified", "title"}},
+		{"empty", &Page{Total: &total, Limit: 10}, []string{"currentPage", "itemsPerPage", "modified", "numberOfItems", "title"}},
+		{"after a cursor", &Page{Limit: 25}, []string{"itemsPerPage", "modified", "title"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := testFeed()
+			feed.Page = tt.page
+			_, out := writeJSON(t, feed)
+
+			var names []string
+			for name := range out["metadata"].(map[string]any) {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.metadata) {
+				t.Errorf("feed metadata has %v, want %v", names, tt.metadata)
+			}
+		})
+	}
+}
+
+func TestWriteJSONEmptyFeeds(t *testing.T) {
+	tests := []struct {
+		kind         string
+		publications bool
+	}{
+		{KindAcquisition, true},
+		{KindNavigation, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			data, out := writeJSON(t, &Feed{ID: "https://books.example.com/opds/v2/books", Title: "Books", Kind: tt.kind})
+			publications, ok := out["publications"]
+			if ok != tt.publications {
+				t.Fatalf("feed has publications %v, want them written: %v\n%s", publications, tt.publications, data)
+			}
+			if ok && len(publications.([]any)) != 0 {
+				t.Errorf("empty feed has publications %v", publications)
+			}
+		})
+	}
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,339 @@
+// Package patch applies JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902) documents
+// to JSON documents decoded into maps, slices and plain values.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types of the patch formats
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// Kinds of failures, check them with errors.Is
+var (
+	ErrInvalid = errors.New("patch is invalid")
+	ErrFailed  = errors.New("patch cannot be applied")
+)
+
+// Error is a failure of one operation of a JSON Patch. It matches its Kind with errors.Is.
+type Error struct {
+	Kind    error
+	Index   int // position of the operation in the patch
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Merge applies a merge patch to doc. Members of the patch replace those of doc, nested
+// objects are merged and null removes a member. doc is left as it is.
+func Merge(doc any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	target, ok := doc.(map[string]any)
+	if ok {
+		target = maps.Clone(target)
+	} else {
+		target = map[string]any{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(target, name)
+			continue
+		}
+		target[name] = Merge(target[name], value)
+	}
+	return target
+}
+
+// Operation is one step of a JSON Patch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"` // nil when the member is missing, "null" when it is null
+}
+
+// Parse decodes a JSON Patch and checks that every operation is well-formed
+func Parse(data []byte) ([]Operation, error) {
+	var operations []Operation
+	if err := json.Unmarshal(data, &operations); err != nil {
+		return nil, err
+	}
+
+	for i, operation := range operations {
+		invalid := func(message string) error {
+			return &Error{Kind: ErrInvalid, Index: i, Message: message}
+		}
+
+		switch operation.Op {
+		case "add", "replace", "test":
+			if operation.Value == nil {
+				return nil, invalid(operation.Op + " needs a value")
+			}
+		case "move", "copy":
+			if _, err := parsePointer(operation.From); err != nil {
+				return nil, invalid("from " + err.Error())
+			}
+		case "remove":
+		default:
+			return nil, invalid(fmt.Sprintf("unknown op %q", operation.Op))
+		}
+		if _, err := parsePointer(operation.Path); err != nil {
+			return nil, invalid("path " + err.Error())
+		}
+	}
+	return operations, nil
+}
+
+// Apply runs the operations of a JSON Patch on doc in order and returns the patched document.
+// doc may be modified, the patch is applied as a whole or fails as a whole.
+func Apply(doc any, operations []Operation) (any, error) {
+	for i, operation := range operations {
+		var err error
+		doc, err = applyOperation(doc, operation)
+		if err != nil {
+			return nil, &Error{Kind: ErrFailed, Index: i, Message: err.Error()}
+		}
+	}
+	return doc, nil
+}
+
+func applyOperation(doc any, operation Operation) (any, error) {
+	path, _ := parsePointer(operation.Path)
+
+	switch operation.Op {
+	case "add":
+		value, err := decodeValue(operation.Value)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		value, err := decodeValue(operation.Value)
+		if err != nil {
+			return nil, err
+		}
+		// The whole document always exists, replacing it needs nothing removed
+		if len(path) == 0 {
+			return value, nil
+		}
+		if doc, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "move":
+		from, _ := parsePointer(operation.From)
+		if operation.Path != operation.From && strings.HasPrefix(operation.Path, operation.From+"/") {
+			return nil, fmt.Errorf("cannot move %s into itself", operation.From)
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "copy":
+		from, _ := parsePointer(operation.From)
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		// Copy through JSON so later operations on either copy leave the other alone
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = decodeValue(data); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "test":
+		expected, err := decodeValue(operation.Value)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			return nil, fmt.Errorf("test of %s failed", operation.Path)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", operation.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%q must be empty or start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func decodeValue(data json.RawMessage) (any, error) {
+	var value any
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// get returns the value the tokens point to
+func get(doc any, tokens []string) (any, error) {
+	for i, token := range tokens {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%s does not exist", pointerOf(tokens[:i+1]))
+			}
+			doc = value
+		case []any:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", pointerOf(tokens[:i+1]), err)
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("%s is not an object or array", pointerOf(tokens[:i]))
+		}
+	}
+	return doc, nil
+}
+
+// add sets the member the tokens point to or inserts into an array before the element they point to
+func add(doc any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parentTokens, token := tokens[:len(tokens)-1], tokens[len(tokens)-1]
+	parent, err := get(doc, parentTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node := parent.(type) {
+	case map[string]any:
+		node[token] = value
+		return doc, nil
+	case []any:
+		index := len(node)
+		if token != "-" {
+			if index, err = arrayIndex(token, len(node)); err != nil {
+				return nil, fmt.Errorf("%s: %w", pointerOf(tokens), err)
+			}
+		}
+		node = append(node[:index:index], append([]any{value}, node[index:]...)...)
+		return set(doc, parentTokens, node)
+	default:
+		return nil, fmt.Errorf("%s is not an object or array", pointerOf(parentTokens))
+	}
+}
+
+// remove deletes the member or array element the tokens point to
+func remove(doc any, tokens []string) (any, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("the whole document cannot be removed")
+	}
+
+	parentTokens, token := tokens[:len(tokens)-1], tokens[len(tokens)-1]
+	parent, err := get(doc, parentTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node := parent.(type) {
+	case map[string]any:
+		if _, ok := node[token]; !ok {
+			return nil, fmt.Errorf("%s does not exist", pointerOf(tokens))
+		}
+		delete(node, token)
+		return doc, nil
+	case []any:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pointerOf(tokens), err)
+		}
+		node = append(node[:index:index], node[index+1:]...)
+		return set(doc, parentTokens, node)
+	default:
+		return nil, fmt.Errorf("%s is not an object or array", pointerOf(parentTokens))
+	}
+}
+
+// set replaces the existing value the tokens point to, used to store arrays that were resized
+func set(doc any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, tokens[:len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[token] = value
+	case []any:
+		index, _ := strconv.Atoi(token)
+		node[index] = value
+	}
+	return doc, nil
+}
+
+// arrayIndex parses an array index token, which must not exceed max
+func arrayIndex(token string, max int) (int, error) {
+	// Only digits without leading zeros are an index (RFC 6901 4), Atoi would take a sign
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("index %d is out of range", index)
+	}
+	return index, nil
+}
+
+func pointerOf(tokens []string) string {
+	var pointer strings.Builder
+	for _, token := range tokens {
+		pointer.WriteString("/" + strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return pointer.String()
+}
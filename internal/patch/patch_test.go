@@ -0,0 +1,224 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, data string) any {
+	t.Helper()
+
+	var value any
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	return value
+}
+
+func TestApply(t *testing.T) {
+	// The examples of RFC 6902 appendix A, followed by the edge cases of array indexes
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"add an object member", `{"foo": "bar"}`, `[{"op": "add", "path": "/baz", "value": "qux"}]`, `{"baz": "qux", "foo": "bar"}`},
+		{"add an array element", `{"foo": ["bar", "baz"]}`, `[{"op": "add", "path": "/foo/1", "value": "qux"}]`, `{"foo": ["bar", "qux", "baz"]}`},
+		{"remove an object member", `{"baz": "qux", "foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, `{"foo": "bar"}`},
+		{"remove an array element", `{"foo": ["bar", "qux", "baz"]}`, `[{"op": "remove", "path": "/foo/1"}]`, `{"foo": ["bar", "baz"]}`},
+		{"replace a value", `{"baz": "qux", "foo": "bar"}`, `[{"op": "replace", "path": "/baz", "value": "boo"}]`, `{"baz": "boo", "foo": "bar"}`},
+		{
+			"move a value",
+			`{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			`[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			`{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{"move an array element", `{"foo": ["all", "grass", "cows", "eat"]}`, `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`, `{"foo": ["all", "cows", "eat", "grass"]}`},
+		{
+			"test a value",
+			`{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			`[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			`{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{"add a nested member object", `{"foo": "bar"}`, `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`, `{"foo": "bar", "child": {"grandchild": {}}}`},
+		{"add an array value", `{"foo": ["bar"]}`, `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`, `{"foo": ["bar", ["abc", "def"]]}`},
+		{"escaped pointer", `{"/": 9, "~1": 10}`, `[{"op": "test", "path": "/~01", "value": 10}, {"op": "remove", "path": "/~1"}]`, `{"~1": 10}`},
+		{"add to an empty array", `{"foo": []}`, `[{"op": "add", "path": "/foo/0", "value": 1}]`, `{"foo": [1]}`},
+		{"add after the last element", `{"foo": [1, 2]}`, `[{"op": "add", "path": "/foo/2", "value": 3}]`, `{"foo": [1, 2, 3]}`},
+		{"append to a nested array", `{"foo": [[1]]}`, `[{"op": "add", "path": "/foo/0/-", "value": 2}]`, `{"foo": [[1, 2]]}`},
+		{"replace the whole document", `{"foo": "bar"}`, `[{"op": "replace", "path": "", "value": [1]}]`, `[1]`},
+		{"add null", `{"foo": "bar"}`, `[{"op": "add", "path": "/foo", "value": null}]`, `{"foo": null}`},
+		{"test an object", `{"foo": {"a": 1, "b": [true]}}`, `[{"op": "test", "path": "/foo", "value": {"b": [true], "a": 1}}]`, `{"foo": {"a": 1, "b": [true]}}`},
+		{
+			"copy a value",
+			`{"foo": {"bar": [1]}}`,
+			`[{"op": "copy", "from": "/foo", "path": "/baz"}, {"op": "add", "path": "/baz/bar/-", "value": 2}]`,
+			`{"foo": {"bar": [1]}, "baz": {"bar": [1, 2]}}`,
+		},
+		{"move to the same place", `{"foo": 1}`, `[{"op": "move", "from": "/foo", "path": "/foo"}]`, `{"foo": 1}`},
+		{"move to a sibling with a longer name", `{"foo": 1}`, `[{"op": "move", "from": "/foo", "path": "/foobar"}]`, `{"foobar": 1}`},
+		{"operations apply in order", `{}`, `[{"op": "add", "path": "/a", "value": []}, {"op": "add", "path": "/a/-", "value": 1}, {"op": "copy", "from": "/a/0", "path": "/b"}]`, `{"a": [1], "b": 1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operations, err := Parse([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Apply(decode(t, tt.doc), operations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("patched document is %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyFails(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		index   int
+		message string
+	}{
+		{"test a wrong value", `{"baz": "qux"}`, `[{"op": "test", "path": "/baz", "value": "bar"}]`, 0, "test of /baz failed"},
+		{"test a string against a number", `{"/": 9}`, `[{"op": "test", "path": "/~1", "value": "9"}]`, 0, "failed"},
+		{"test a missing member", `{}`, `[{"op": "test", "path": "/baz", "value": null}]`, 0, "/baz does not exist"},
+		{"add to a missing object", `{"foo": "bar"}`, `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`, 0, "/baz does not exist"},
+		{"add into a string", `{"foo": "bar"}`, `[{"op": "add", "path": "/foo/bar", "value": 1}]`, 0, "/foo is not an object or array"},
+		{"remove a missing member", `{"foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, 0, "/baz does not exist"},
+		{"remove the whole document", `{"foo": "bar"}`, `[{"op": "remove", "path": ""}]`, 0, "cannot be removed"},
+		{"replace a missing member", `{"foo": "bar"}`, `[{"op": "replace", "path": "/baz", "value": 1}]`, 0, "/baz does not exist"},
+		{"move a missing member", `{"foo": "bar"}`, `[{"op": "move", "from": "/baz", "path": "/qux"}]`, 0, "/baz does not exist"},
+		{"move into itself", `{"foo": {"bar": 1}}`, `[{"op": "move", "from": "/foo", "path": "/foo/bar"}]`, 0, "cannot move /foo into itself"},
+		{"copy a missing member", `{"foo": "bar"}`, `[{"op": "copy", "from": "/baz", "path": "/qux"}]`, 0, "/baz does not exist"},
+		{"later operation fails", `{"foo": "bar"}`, `[{"op": "add", "path": "/a", "value": 1}, {"op": "remove", "path": "/b"}]`, 1, "/b does not exist"},
+
+		{"add past the end", `{"foo": [1, 2]}`, `[{"op": "add", "path": "/foo/3", "value": 3}]`, 0, "index 3 is out of range"},
+		{"remove the end", `{"foo": [1, 2]}`, `[{"op": "remove", "path": "/foo/2"}]`, 0, "index 2 is out of range"},
+		{"remove with -", `{"foo": [1, 2]}`, `[{"op": "remove", "path": "/foo/-"}]`, 0, `"-" is not an array index`},
+		{"test with -", `{"foo": [1, 2]}`, `[{"op": "test", "path": "/foo/-", "value": 2}]`, 0, `"-" is not an array index`},
+		{"remove from an empty array", `{"foo": []}`, `[{"op": "remove", "path": "/foo/0"}]`, 0, "index 0 is out of range"},
+		{"leading zero", `{"foo": [1, 2]}`, `[{"op": "replace", "path": "/foo/01", "value": 3}]`, 0, `"01" is not an array index`},
+		{"negative index", `{"foo": [1, 2]}`, `[{"op": "add", "path": "/foo/-1", "value": 3}]`, 0, `"-1" is not an array index`},
+		{"signed index", `{"foo": [1, 2]}`, `[{"op": "add", "path": "/foo/+1", "value": 3}]`, 0, `"+1" is not an array index`},
+		{"empty index", `{"foo": [1, 2]}`, `[{"op": "add", "path": "/foo/", "value": 3}]`, 0, `"" is not an array index`},
+		{"name as index", `{"foo": [1, 2]}`, `[{"op": "add", "path": "/foo/bar", "value": 3}]`, 0, `"bar" is not an array index`},
+		{"huge index", `{"foo": [1, 2]}`, `[{"op": "add", "path": "/foo/99999999999999999999", "value": 3}]`, 0, "is not an array index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operations, err := Parse([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Apply(decode(t, tt.doc), operations)
+			if !errors.Is(err, ErrFailed) {
+				t.Fatalf("applying returned %v, want ErrFailed", err)
+			}
+			var patchErr *Error
+			if !errors.As(err, &patchErr) || patchErr.Index != tt.index {
+				t.Errorf("error %v is not of operation %d", err, tt.index)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %q does not contain %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidPatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		message string
+	}{
+		{"unknown op", `[{"op": "append", "path": "/a", "value": 1}]`, `unknown op "append"`},
+		{"missing op", `[{"path": "/a", "value": 1}]`, `unknown op ""`},
+		{"add without a value", `[{"op": "add", "path": "/a"}]`, "add needs a value"},
+		{"replace without a value", `[{"op": "replace", "path": "/a"}]`, "replace needs a value"},
+		{"test without a value", `[{"op": "test", "path": "/a"}]`, "test needs a value"},
+		{"relative path", `[{"op": "remove", "path": "a"}]`, "path"},
+		{"relative from", `[{"op": "move", "from": "a", "path": "/b"}]`, "from"},
+		{"second operation", `[{"op": "remove", "path": "/a"}, {"op": "add", "path": "/b"}]`, "operation 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.patch))
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("parsing returned %v, want ErrInvalid", err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %q does not contain %q", err, tt.message)
+			}
+		})
+	}
+
+	// A patch that is not an array of operations is not JSON Patch at all
+	for _, data := range []string{`{"op": "remove", "path": "/a"}`, `[`, `"remove"`} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("parsing %s succeeded", data)
+		}
+	}
+}
+
+func TestApplyLeavesDocumentOnFailure(t *testing.T) {
+	doc := decode(t, `{"foo": [1, 2], "bar": {"baz": 1}}`)
+	operations, err := Parse([]byte(`[{"op": "add", "path": "/foo/-", "value": 3}, {"op": "remove", "path": "/bar/missing"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Apply(doc, operations)
+	if err == nil || got != nil {
+		t.Fatalf("a failing patch returned %v and %v, want no document", got, err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// The examples of RFC 7396 appendix A
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{`{"a": "b"}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "b"}`, `{"b": "c"}`, `{"a": "b", "b": "c"}`},
+		{`{"a": "b"}`, `{"a": null}`, `{}`},
+		{`{"a": "b", "b": "c"}`, `{"a": null}`, `{"b": "c"}`},
+		{`{"a": ["b"]}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "c"}`, `{"a": ["b"]}`, `{"a": ["b"]}`},
+		{`{"a": {"b": "c"}}`, `{"a": {"b": "d", "c": null}}`, `{"a": {"b": "d"}}`},
+		{`{"a": [{"b": "c"}]}`, `{"a": [1]}`, `{"a": [1]}`},
+		{`["a", "b"]`, `["c", "d"]`, `["c", "d"]`},
+		{`{"a": "b"}`, `["c"]`, `["c"]`},
+		{`{"a": "foo"}`, `null`, `null`},
+		{`{"a": "foo"}`, `"bar"`, `"bar"`},
+		{`{"e": null}`, `{"a": 1}`, `{"e": null, "a": 1}`},
+		{`[1, 2]`, `{"a": "b", "c": null}`, `{"a": "b"}`},
+		{`{}`, `{"a": {"bb": {"ccc": null}}}`, `{"a": {"bb": {}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.doc+" "+tt.patch, func(t *testing.T) {
+			doc := decode(t, tt.doc)
+			got := Merge(doc, decode(t, tt.patch))
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("merged document is %v, want %v", got, want)
+			}
+			if !reflect.DeepEqual(doc, decode(t, tt.doc)) {
+				t.Errorf("merging changed the document to %v", doc)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"go-playground/internal/server/utils"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -12,14 +13,14 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(401, gin.H{"error": "Authorization header is required"})
+			c.Error(utils.NewAPIError(http.StatusUnauthorized, "missing_token", "Authorization header is required"))
 			c.Abort()
 			return
 		}
 
 		// Check if the header has the Bearer prefix
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(401, gin.H{"error": "Authorization header format must be Bearer {token}"})
+			c.Error(utils.NewAPIError(http.StatusUnauthorized, "invalid_token", "Authorization header format must be Bearer {token}"))
 			c.Abort()
 			return
 		}
@@ -27,7 +28,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Safely extract the token
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 		if token == "" {
-			c.JSON(401, gin.H{"error": "Token is required"})
+			c.Error(utils.NewAPIError(http.StatusUnauthorized, "missing_token", "Token is required"))
 			c.Abort()
 			return
 		}
@@ -35,7 +36,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Validate the token (this is a placeholder, implement your own validation logic)
 		claims, err := utils.ValidateJWT(token)
 		if err != nil {
-			c.JSON(401, gin.H{"error": "Invalid token"})
+			c.Error(utils.NewAPIError(http.StatusUnauthorized, "invalid_token", "Invalid token"))
 			c.Abort()
 			return
 		}
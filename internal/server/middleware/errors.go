@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-playground/internal/database"
+	"go-playground/internal/database/query"
+	"go-playground/internal/patch"
+	"go-playground/internal/server/types"
+	"go-playground/internal/server/utils"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// StatusClientClosedRequest is the non-standard status nginx uses for requests the client abandoned
+const StatusClientClosedRequest = 499
+
+func init() {
+	// Name fields in validation errors the way clients send them
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// ErrorMiddleware answers requests whose handler recorded an error with c.Error and
+// did not write a response, using an application/problem+json body (RFC 7807).
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		problem := newProblem(c.Errors.Last().Err)
+		problem.Instance = c.Request.URL.Path
+
+		c.Header("Content-Type", "application/problem+json")
+		c.JSON(problem.Status, problem)
+	}
+}
+
+// newProblem describes err as a problem document with a stable code.
+// Unknown errors become a 500 without details, gin's logger still prints them.
+func newProblem(err error) types.Problem {
+	var (
+		apiErr      *utils.APIError
+		dbErr       *database.Error
+		queryErr    *query.Error
+		validation  validator.ValidationErrors
+		typeErr     *json.UnmarshalTypeError
+		syntaxErr   *json.SyntaxError
+		maxBytesErr *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &apiErr):
+		problem := problemOf(apiErr.Status, apiErr.Code, apiErr.Detail)
+		problem.Errors = apiErr.Fields
+		return problem
+
+	case errors.As(err, &validation):
+		problem := problemOf(http.StatusUnprocessableEntity, "validation_failed", "The request contains invalid fields")
+		for _, field := range validation {
+			problem.Errors = append(problem.Errors, types.FieldError{Field: field.Field(), Message: validationMessage(field)})
+		}
+		return problem
+	case errors.As(err, &typeErr):
+		problem := problemOf(http.StatusUnprocessableEntity, "validation_failed", "The request contains invalid fields")
+		problem.Errors = []types.FieldError{{Field: typeErr.Field, Message: "must be " + jsonTypeName(typeErr.Type)}}
+		return problem
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return problemOf(http.StatusBadRequest, "invalid_body", "The request body is not valid JSON")
+	case errors.As(err, &maxBytesErr):
+		return problemOf(http.StatusRequestEntityTooLarge, "payload_too_large", "The request body is too large")
+	case errors.As(err, &queryErr):
+		return problemOf(http.StatusBadRequest, "invalid_query", queryErr.Error())
+	case errors.Is(err, patch.ErrInvalid):
+		return problemOf(http.StatusBadRequest, "invalid_patch", err.Error())
+	case errors.Is(err, patch.ErrFailed):
+		return problemOf(http.StatusConflict, "patch_failed", err.Error())
+
+	case errors.Is(err, context.Canceled):
+		return problemOf(StatusClientClosedRequest, "client_closed_request", "The client closed the request")
+	case errors.Is(err, context.DeadlineExceeded):
+		return problemOf(http.StatusServiceUnavailable, "timeout", "The database did not respond in time")
+
+	case errors.Is(err, database.ErrNotFound):
+		return problemOf(http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, database.ErrConflict):
+		return problemOf(http.StatusConflict, "conflict", err.Error())
+	case errors.Is(err, database.ErrStale):
+		return problemOf(http.StatusConflict, "edit_conflict", err.Error())
+	case errors.Is(err, database.ErrConstraint):
+		return problemOf(http.StatusConflict, "constraint_violation", err.Error())
+	case errors.As(err, &dbErr) && errors.Is(err, database.ErrValidation):
+		problem := problemOf(http.StatusUnprocessableEntity, "validation_failed", err.Error())
+		if dbErr.Field != "" {
+			problem.Errors = []types.FieldError{{Field: dbErr.Field, Message: dbErr.Message}}
+		}
+		return problem
+	case errors.Is(err, database.ErrSearchUnavailable):
+		return problemOf(http.StatusServiceUnavailable, "search_unavailable", err.Error())
+	}
+
+	return problemOf(http.StatusInternalServerError, "internal_error", "An unexpected error occurred")
+}
+
+// problemOf creates a problem without a type URI, the status text serves as title (RFC 7807 4.2)
+func problemOf(status int, code string, detail string) types.Problem {
+	title := http.StatusText(status)
+	if status == StatusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+	return types.Problem{Type: "about:blank", Title: title, Status: status, Detail: detail, Code: code}
+}
+
+// validationMessage explains a failed binding rule of a field
+func validationMessage(field validator.FieldError) string {
+	switch field.Tag() {
+	case "required", "required_without":
+		return "is required"
+	case "min":
+		if field.Kind() == reflect.String {
+			return "must be at least " + field.Param() + " characters long"
+		}
+		return "must be at least " + field.Param()
+	case "max":
+		if field.Kind() == reflect.String {
+			return "must be at most " + field.Param() + " characters long"
+		}
+		return "must be at most " + field.Param()
+	case "oneof":
+		return "must be one of " + field.Param()
+	default:
+		return "does not satisfy " + field.Tag()
+	}
+}
+
+// jsonTypeName names the JSON type a Go value is decoded from
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etagWriter holds back the body of a response until its entity tag is known
+type etagWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// ETagMiddleware tags successful GET responses with a hash of their body and answers requests
+// whose If-None-Match lists the tag with 304 Not Modified, so clients can revalidate cached
+// responses without downloading them again. The body is buffered, keep it off file downloads.
+func ETagMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &etagWriter{ResponseWriter: original}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		// Nothing written yet, e.g. a failure the ErrorMiddleware still has to answer
+		if writer.body.Len() == 0 {
+			return
+		}
+		if writer.Status() != http.StatusOK {
+			original.Write(writer.body.Bytes())
+			return
+		}
+
+		etag := writer.Header().Get("ETag")
+		if etag == "" {
+			sum := sha256.Sum256(writer.body.Bytes())
+			etag = `W/"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+			writer.Header().Set("ETag", etag)
+		}
+
+		if noneMatch(c.GetHeader("If-None-Match"), etag) {
+			original.WriteHeader(http.StatusNotModified)
+			original.WriteHeaderNow()
+			return
+		}
+		original.Write(writer.body.Bytes())
+	}
+}
+
+// noneMatch reports whether an If-None-Match header lists the tag, comparing weakly (RFC 9110 13.1.2)
+func noneMatch(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
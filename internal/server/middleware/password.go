@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"go-playground/internal/server/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordChangeMiddleware rejects requests from users that still have to change
+// their password, such as the seeded admin account on its first login
+func PasswordChangeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, ok := c.Value("user").(*utils.Claims); ok && claims.MustChangePassword {
+			c.Error(utils.NewAPIError(http.StatusForbidden, "password_change_required", "Password change required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
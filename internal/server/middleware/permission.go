@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"go-playground/internal/server/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission is a middleware that only lets requests through when the
+// claims set by AuthMiddleware grant the given permission
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("user")
+		claims, ok := value.(*utils.Claims)
+		if !exists || !ok {
+			c.Error(utils.NewAPIError(http.StatusUnauthorized, "missing_token", "Authentication required"))
+			c.Abort()
+			return
+		}
+
+		if !claims.HasPermission(permission) {
+			c.Error(utils.NewAPIError(http.StatusForbidden, "missing_permission", "Missing permission "+permission))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
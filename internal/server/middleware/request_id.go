@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID of a request, it is echoed in every response
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits the IDs taken over from clients or proxies to harmless ones
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestIDMiddleware keeps the request ID sent by the client or a proxy in front of the
+// server, or generates one, and stores it as "requestID" to tie logs and audit entries to it.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			random := make([]byte, 16)
+			if _, err := rand.Read(random); err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			id = hex.EncodeToString(random)
+		}
+
+		c.Set("requestID", id)
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+	}
+}
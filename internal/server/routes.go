@@ -1,14 +1,19 @@
 package server
 
 import (
+	"errors"
+	"go-playground/internal/database"
 	"go-playground/internal/database/models"
 	"go-playground/internal/server/middleware"
 	"go-playground/internal/server/routes"
 	adminRoutes "go-playground/internal/server/routes/admin"
 	"go-playground/internal/server/types"
+	"go-playground/internal/server/utils"
+	"go-playground/internal/storage"
 	"go-playground/openapi"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -20,41 +25,63 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"}, // Add your frontend URL
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "Origin"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "Origin", "If-Match", "If-None-Match", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "Content-Type", "ETag", middleware.RequestIDHeader},
 		AllowCredentials: true, // Enable cookies/auth
 	}))
+	r.Use(middleware.RequestIDMiddleware())
+	r.Use(middleware.ErrorMiddleware())
 
 	r.GET("/health", s.healthHandler)
+	r.GET("/.well-known/jwks.json", s.jwksHandler)
 
 	api := r.Group("/api/v1")
 	{
 		api.GET("/health", s.healthHandler)
-		authors := api.Group("/authors")
+
+		// Files are not buffered for an ETag, their keys change with their content
+		etag := middleware.ETagMiddleware()
+
+		authors := api.Group("/authors", etag)
 		{
 			authors.GET("", s.listAuthorsHandler)
 			authors.GET("/:id", s.getAuthorHandler)
 		}
 
-		books := api.Group("/books")
+		books := api.Group("/books", etag)
 		{
 			books.GET("", s.listBooksHandler)
 			books.GET("/:id", s.getBookHandler)
 		}
 
-		artists := api.Group("/artists")
+		artists := api.Group("/artists", etag)
 		{
 			artists.GET("", s.ListArtistsHandler)
 			artists.GET("/:id", s.GetArtistHandler)
 		}
 
+		genres := api.Group("/genres", etag)
+		{
+			genres.GET("", s.listGenresHandler)
+			genres.GET("/:slug", s.getGenreHandler)
+			genres.GET("/:slug/books", s.listGenreBooksHandler)
+		}
+
+		api.GET("/search", etag, s.searchHandler)
+		api.GET("/files/*key", s.fileHandler)
+
+		opds := api.Group("/opds", etag)
+		{
+			routes.RegisterOPDSRoutes(opds)
+		}
+
 		auth := api.Group("/auth")
 		{
 			routes.RegisterAuthRoutes(auth)
 		}
 
 		admin := api.Group("/admin")
-		admin.Use(middleware.AuthMiddleware())
+		admin.Use(middleware.AuthMiddleware(), middleware.PasswordChangeMiddleware(), etag)
 		{
 			adminBooks := admin.Group("/books")
 			adminRoutes.RegisterBookRoutes(adminBooks)
@@ -67,6 +94,28 @@ func (s *Server) RegisterRoutes() http.Handler {
 
 			adminArtists := admin.Group("/artists")
 			adminRoutes.RegisterArtistRoutes(adminArtists)
+
+			adminGenres := admin.Group("/genres")
+			adminRoutes.RegisterGenreRoutes(adminGenres)
+
+			adminSearch := admin.Group("/search")
+			adminRoutes.RegisterSearchRoutes(adminSearch)
+
+			adminUsers := admin.Group("/users")
+			adminRoutes.RegisterUserRoutes(adminUsers)
+
+			adminAudit := admin.Group("/audit")
+			adminRoutes.RegisterAuditRoutes(adminAudit)
+
+			adminImports := admin.Group("/imports")
+			adminRoutes.RegisterImportRoutes(adminImports)
+		}
+
+		// Exports are streamed, they are not buffered for an ETag
+		adminExport := api.Group("/admin/export")
+		adminExport.Use(middleware.AuthMiddleware(), middleware.PasswordChangeMiddleware())
+		{
+			adminRoutes.RegisterExportRoutes(adminExport)
 		}
 	}
 
@@ -76,7 +125,19 @@ func (s *Server) RegisterRoutes() http.Handler {
 }
 
 func (s *Server) healthHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, s.db.Health())
+	c.JSON(http.StatusOK, s.db.Health(c.Request.Context()))
+}
+
+// JWKS
+// @Summary JSON Web Key Set
+// @Description Public keys that verify the access tokens issued by this API
+// @Tags auth
+// @Produce json
+// @Success 200 {object} utils.JSONWebKeySet
+// @Router /.well-known/jwks.json [get]
+func (s *Server) jwksHandler(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.PublicKeySet())
 }
 
 // Authors
@@ -86,28 +147,26 @@ func (s *Server) healthHandler(c *gin.Context) {
 // @Produce json
 // @Param limit query int false "Limit"
 // @Param offset query int false "Offset"
-// @Success 200 {array} types.ListAuthorResponse
-// @Failure 500 {string} string
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Param If-None-Match header string false "ETag of a cached response, answered with 304 while it is current"
+// @Success 200 {object} types.ListResponse[types.ListAuthorResponse]
+// @Header 200 {string} ETag "Tag of the response to send in If-None-Match"
+// @Success 304
+// @Failure 500 {object} types.Problem
 // @Router /authors [get]
 func (s *Server) listAuthorsHandler(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit format"})
-		return
-	}
-
-	offset, err := strconv.Atoi(offsetStr)
+	listQuery, err := utils.ParseListQuery(c, database.AuthorFields)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset format"})
+		c.Error(err)
 		return
 	}
 
 	var authors []models.Author
-	if err := s.db.List(&authors, limit, offset); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	page, err := s.db.List(c.Request.Context(), &authors, listQuery)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -120,7 +179,7 @@ func (s *Server) listAuthorsHandler(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, utils.NewListResponse(c, response, page, listQuery))
 }
 
 // Authors
@@ -129,24 +188,28 @@ func (s *Server) listAuthorsHandler(c *gin.Context) {
 // @Tags authors
 // @Produce json
 // @Param id path int true "Author ID"
+// @Param If-None-Match header string false "ETag of a cached response, answered with 304 while it is current"
 // @Success 200 {object} models.Author
-// @Failure 404 {object} string
-// @Failure 500 {object} string
+// @Header 200 {string} ETag "Version of the entity, send it in If-None-Match or in If-Match of a change"
+// @Success 304
+// @Failure 404 {object} types.Problem
+// @Failure 500 {object} types.Problem
 // @Router /authors/{id} [get]
 func (s *Server) getAuthorHandler(c *gin.Context) {
 	idStr := c.Param("id")
 	id64, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		c.Error(utils.NewAPIError(http.StatusBadRequest, "invalid_id", "Invalid ID format"))
 		return
 	}
 
-	author, err := s.db.GetAuthor(uint(id64))
+	author, err := s.db.GetAuthor(c.Request.Context(), uint(id64))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	utils.SetETag(c, author.Version)
 	c.JSON(http.StatusOK, author)
 }
 
@@ -157,45 +220,78 @@ func (s *Server) getAuthorHandler(c *gin.Context) {
 // @Produce json
 // @Param limit query int false "Limit"
 // @Param offset query int false "Offset"
-// @Success 200 {array} types.ListBookResponse
-// @Failure 500 {string} string
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Param If-None-Match header string false "ETag of a cached response, answered with 304 while it is current"
+// @Success 200 {object} types.ListResponse[types.ListBookResponse]
+// @Header 200 {string} ETag "Tag of the response to send in If-None-Match"
+// @Success 304
+// @Failure 500 {object} types.Problem
 // @Router /books [get]
 func (s *Server) listBooksHandler(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
+	listQuery, err := utils.ParseListQuery(c, database.BookFields)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit format"})
+		c.Error(err)
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
+	books, page, err := s.db.ListBooks(c.Request.Context(), listQuery)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset format"})
+		c.Error(err)
 		return
 	}
 
-	books, err := s.db.ListBooks(limit, offset)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+	c.JSON(http.StatusOK, utils.NewListResponse(c, toListBookResponse(books), page, listQuery))
+}
 
+// toListBookResponse maps books onto the public list representation
+func toListBookResponse(books []models.Book) []types.ListBookResponse {
 	var response []types.ListBookResponse
 	for _, book := range books {
+		genres := []string{}
+		for _, genre := range book.Genres {
+			genres = append(genres, genre.Name)
+		}
+
 		response = append(response, types.ListBookResponse{
 			ID:            book.ID,
 			Title:         book.Title,
 			DigitalOnly:   book.DigitalOnly,
 			PublishedDate: book.PublishedDate.Format("2006-01-02"),
+			Pages:         book.Pages,
+			Description:   book.Description,
+			ISBN:          book.ISBN,
+			Price:         book.Price,
+			Genres:        genres,
 			Author:        types.ListAuthorResponse{ID: book.Author.ID, FirstName: book.Author.FirstName, LastName: book.Author.LastName},
-			Cover:         types.ListCoverResponse{ID: book.Cover.ID, ImageURL: book.Cover.ImageURL.String},
+			Cover:         toListCoverResponse(book.Cover),
 		})
 	}
+	return response
+}
 
-	c.JSON(http.StatusOK, response)
+// toListCoverResponse maps a cover and its image variants onto the public representation
+func toListCoverResponse(cover models.Cover) types.ListCoverResponse {
+	response := types.ListCoverResponse{
+		ID:            cover.ID,
+		ImageURL:      cover.ImageURL.String,
+		Width:         cover.ImageWidth,
+		Height:        cover.ImageHeight,
+		DominantColor: cover.DominantColor,
+		BlurHash:      cover.BlurHash,
+		Variants:      []types.CoverVariantResponse{},
+	}
+	for _, variant := range cover.Variants {
+		response.Variants = append(response.Variants, types.CoverVariantResponse{
+			Name:   variant.Name,
+			Format: variant.Format,
+			Width:  variant.Width,
+			Height: variant.Height,
+			URL:    variant.URL,
+		})
+	}
+	return response
 }
 
 // Books
@@ -204,24 +300,28 @@ func (s *Server) listBooksHandler(c *gin.Context) {
 // @Tags books
 // @Produce json
 // @Param id path int true "Book ID"
+// @Param If-None-Match header string false "ETag of a cached response, answered with 304 while it is current"
 // @Success 200 {object} models.Book
-// @Failure 404 {object} string
-// @Failure 500 {object} string
+// @Header 200 {string} ETag "Version of the entity, send it in If-None-Match or in If-Match of a change"
+// @Success 304
+// @Failure 404 {object} types.Problem
+// @Failure 500 {object} types.Problem
 // @Router /books/{id} [get]
 func (s *Server) getBookHandler(c *gin.Context) {
 	idStr := c.Param("id")
 	id64, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		c.Error(utils.NewAPIError(http.StatusBadRequest, "invalid_id", "Invalid ID format"))
 		return
 	}
 
-	book, err := s.db.GetBook(uint(id64))
+	book, err := s.db.GetBook(c.Request.Context(), uint(id64))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	utils.SetETag(c, book.Version)
 	c.JSON(http.StatusOK, book)
 }
 
@@ -232,28 +332,26 @@ func (s *Server) getBookHandler(c *gin.Context) {
 // @Produce json
 // @Param limit query int false "Limit"
 // @Param offset query int false "Offset"
-// @Success 200 {array} types.ListArtistResponse
-// @Failure 500 {string} string
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Param If-None-Match header string false "ETag of a cached response, answered with 304 while it is current"
+// @Success 200 {object} types.ListResponse[types.ListArtistResponse]
+// @Header 200 {string} ETag "Tag of the response to send in If-None-Match"
+// @Success 304
+// @Failure 500 {object} types.Problem
 // @Router /artists [get]
 func (s *Server) ListArtistsHandler(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit format"})
-		return
-	}
-
-	offset, err := strconv.Atoi(offsetStr)
+	listQuery, err := utils.ParseListQuery(c, database.ArtistFields)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset format"})
+		c.Error(err)
 		return
 	}
 
 	var artists []models.Artist
-	if err := s.db.List(&artists, limit, offset); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	page, err := s.db.List(c.Request.Context(), &artists, listQuery)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -266,7 +364,7 @@ func (s *Server) ListArtistsHandler(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, utils.NewListResponse(c, response, page, listQuery))
 }
 
 // Artists
@@ -275,23 +373,244 @@ func (s *Server) ListArtistsHandler(c *gin.Context) {
 // @Tags artists
 // @Produce json
 // @Param id path int true "Artist ID"
+// @Param If-None-Match header string false "ETag of a cached response, answered with 304 while it is current"
 // @Success 200 {object} models.Artist
-// @Failure 404 {object} string
-// @Failure 500 {object} string
+// @Header 200 {string} ETag "Version of the entity, send it in If-None-Match or in If-Match of a change"
+// @Success 304
+// @Failure 404 {object} types.Problem
+// @Failure 500 {object} types.Problem
 // @Router /artists/{id} [get]
 func (s *Server) GetArtistHandler(c *gin.Context) {
 	idStr := c.Param("id")
 	id64, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		c.Error(utils.NewAPIError(http.StatusBadRequest, "invalid_id", "Invalid ID format"))
 		return
 	}
 
-	author, err := s.db.GetArtist(uint(id64))
+	author, err := s.db.GetArtist(c.Request.Context(), uint(id64))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	utils.SetETag(c, author.Version)
 	c.JSON(http.StatusOK, author)
 }
+
+// Genres
+// @Summary List genres
+// @Description List all genres as a flat list, use parent_id to build the tree
+// @Tags genres
+// @Produce json
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Param If-None-Match header string false "ETag of a cached response, answered with 304 while it is current"
+// @Success 200 {object} types.ListResponse[types.ListGenreResponse]
+// @Header 200 {string} ETag "Tag of the response to send in If-None-Match"
+// @Success 304
+// @Failure 500 {object} types.Problem
+// @Router /genres [get]
+func (s *Server) listGenresHandler(c *gin.Context) {
+	listQuery, err := utils.ParseListQuery(c, database.GenreFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var genres []models.Genre
+	page, err := s.db.List(c.Request.Context(), &genres, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var response []types.ListGenreResponse
+	for _, genre := range genres {
+		response = append(response, toListGenreResponse(genre))
+	}
+
+	c.JSON(http.StatusOK, utils.NewListResponse(c, response, page, listQuery))
+}
+
+// Genres
+// @Summary Get genre
+// @Description Get genre by slug, including its parent and direct children
+// @Tags genres
+// @Produce json
+// @Param slug path string true "Genre slug"
+// @Param If-None-Match header string false "ETag of a cached response, answered with 304 while it is current"
+// @Success 200 {object} types.GetGenreResponse
+// @Header 200 {string} ETag "Version of the entity, send it in If-None-Match or in If-Match of a change"
+// @Success 304
+// @Failure 404 {object} types.Problem
+// @Router /genres/{slug} [get]
+func (s *Server) getGenreHandler(c *gin.Context) {
+	genre, err := s.db.GetGenreBySlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := types.GetGenreResponse{
+		ListGenreResponse: toListGenreResponse(*genre),
+		Children:          []types.ListGenreResponse{},
+	}
+	if genre.Parent != nil {
+		parent := toListGenreResponse(*genre.Parent)
+		response.Parent = &parent
+	}
+	for _, child := range genre.Children {
+		response.Children = append(response.Children, toListGenreResponse(child))
+	}
+
+	utils.SetETag(c, genre.Version)
+	c.JSON(http.StatusOK, response)
+}
+
+// Genres
+// @Summary List books in genre
+// @Description List books classified in the genre or any of its subgenres
+// @Tags genres
+// @Produce json
+// @Param slug path string true "Genre slug"
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Param If-None-Match header string false "ETag of a cached response, answered with 304 while it is current"
+// @Success 200 {object} types.ListResponse[types.ListBookResponse]
+// @Header 200 {string} ETag "Tag of the response to send in If-None-Match"
+// @Success 304
+// @Failure 404 {object} types.Problem
+// @Failure 500 {object} types.Problem
+// @Router /genres/{slug}/books [get]
+func (s *Server) listGenreBooksHandler(c *gin.Context) {
+	listQuery, err := utils.ParseListQuery(c, database.BookFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	genre, err := s.db.GetGenreBySlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	genreIDs, err := s.db.GenreDescendantIDs(c.Request.Context(), genre.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	books, page, err := s.db.ListBooksByGenre(c.Request.Context(), genreIDs, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewListResponse(c, toListBookResponse(books), page, listQuery))
+}
+
+func toListGenreResponse(genre models.Genre) types.ListGenreResponse {
+	return types.ListGenreResponse{
+		ID:       genre.ID,
+		Name:     genre.Name,
+		Slug:     genre.Slug,
+		ParentID: genre.ParentID,
+	}
+}
+
+// Search
+// @Summary Search catalog
+// @Description Full-text search over books, authors and artists, grouped by type and ranked by relevance
+// @Tags search
+// @Produce json
+// @Param q query string true "Search query"
+// @Param limit query int false "Maximum hits per type, 1 to 100, default 10"
+// @Param If-None-Match header string false "ETag of a cached response, answered with 304 while it is current"
+// @Success 200 {object} types.SearchResponse
+// @Header 200 {string} ETag "Tag of the response to send in If-None-Match"
+// @Success 304
+// @Failure 400 {object} types.Problem
+// @Failure 503 {object} types.Problem
+// @Failure 500 {object} types.Problem
+// @Router /search [get]
+func (s *Server) searchHandler(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		c.Error(utils.NewAPIError(http.StatusBadRequest, "invalid_query", "Query parameter q is required"))
+		return
+	}
+
+	limit, err := utils.ParseLimit(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	results, err := s.db.Search(c.Request.Context(), query, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := types.SearchResponse{
+		Query:   query,
+		Books:   []types.SearchHit{},
+		Authors: []types.SearchHit{},
+		Artists: []types.SearchHit{},
+	}
+	for _, result := range results {
+		hit := types.SearchHit{ID: result.EntityID, Title: result.Title, Snippet: result.Snippet, Rank: result.Rank}
+		switch result.EntityType {
+		case database.SearchEntityBook:
+			response.Books = append(response.Books, hit)
+		case database.SearchEntityAuthor:
+			response.Authors = append(response.Authors, hit)
+		case database.SearchEntityArtist:
+			response.Artists = append(response.Artists, hit)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Files
+// @Summary Get file
+// @Description Serve an uploaded file, such as a cover image
+// @Tags files
+// @Produce octet-stream
+// @Param key path string true "Storage key of the file"
+// @Success 200 {file} file
+// @Failure 404 {object} types.Problem
+// @Router /files/{key} [get]
+func (s *Server) fileHandler(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	reader, info, err := s.storage.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			c.Error(utils.NewAPIError(http.StatusNotFound, "not_found", "File not found"))
+			return
+		}
+		c.Error(err)
+		return
+	}
+	defer reader.Close()
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// Uploads are stored under a new key every time, so the content never changes
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, info.Size, contentType, reader, nil)
+}
@@ -3,9 +3,9 @@ package admin
 import (
 	"go-playground/internal/database"
 	"go-playground/internal/database/models"
+	"go-playground/internal/server/middleware"
 	"go-playground/internal/server/utils"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -46,10 +46,20 @@ func RegisterArtistRoutes(r *gin.RouterGroup) {
 		db: database.New(),
 	}
 
-	r.GET("", controller.listArtistsHandler)
-	r.POST("", controller.createArtistHandler)
-	r.DELETE("/:id", controller.deleteArtistHandler)
-	r.PATCH("/:id", controller.updateArtistHandler)
+	r.GET("", middleware.RequirePermission(models.PermissionArtistsRead), controller.listArtistsHandler)
+	r.POST("", middleware.RequirePermission(models.PermissionArtistsWrite), controller.createArtistHandler)
+	r.DELETE("/:id", middleware.RequirePermission(models.PermissionArtistsWrite), controller.deleteArtistHandler)
+	r.PATCH("/:id", middleware.RequirePermission(models.PermissionArtistsWrite), controller.updateArtistHandler)
+	r.GET("/trash", middleware.RequirePermission(models.PermissionArtistsRead), controller.listTrashedArtistsHandler)
+	r.POST("/trash/:id/restore", middleware.RequirePermission(models.PermissionArtistsWrite), controller.restoreArtistHandler)
+	r.DELETE("/trash/:id", middleware.RequirePermission(models.PermissionArtistsWrite), controller.purgeArtistHandler)
+
+	revisions := &RevisionsController{
+		db:         controller.db,
+		entityType: models.RevisionEntityArtist,
+		rollback:   controller.rollbackArtist,
+	}
+	registerRevisionRoutes(r, revisions, models.PermissionArtistsRead, models.PermissionArtistsWrite)
 }
 
 // @Summary List artists
@@ -58,17 +68,28 @@ func RegisterArtistRoutes(r *gin.RouterGroup) {
 // @Produce json
 // @Param limit query int false "Limit number of artists returned"
 // @Param offset query int false "Offset for pagination"
-// @Success 200 {array} models.Artist
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.Artist]
+// @Failure 403 {object} types.Problem
 // @Router /admin/artists [get]
 // @Authorize Bearer
 func (b *ArtistController) listArtistsHandler(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	listQuery, err := utils.ParseListQuery(c, database.ArtistFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
 	var artists []models.Artist
-	b.db.List(&artists, limit, offset)
+	page, err := b.db.List(c.Request.Context(), &artists, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	c.JSON(http.StatusOK, artists)
+	c.JSON(http.StatusOK, utils.NewListResponse(c, artists, page, listQuery))
 }
 
 // @Summary Create artist
@@ -78,19 +99,34 @@ func (b *ArtistController) listArtistsHandler(c *gin.Context) {
 // @Produce json
 // @Param artist body ArtistDTO true "Artist to create"
 // @Success 201 {object} models.Artist
-// @Failure 400 {string} string
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/artists [post]
 // @Authorize Bearer
 func (b *ArtistController) createArtistHandler(c *gin.Context) {
 	var inputDTO ArtistDTO
 	if err := c.ShouldBindJSON(&inputDTO); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	artist := inputDTO.ToModel()
 
-	b.db.Create(&artist)
+	err := b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Create(c.Request.Context(), &artist); err != nil {
+			return err
+		}
+		if err := recordAudit(c, tx, models.AuditActionCreate, artist.ID, nil, &artist); err != nil {
+			return err
+		}
+		return recordRevision(c, tx, models.RevisionEntityArtist, artist.ID, nil, artistFields(artist))
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.SetETag(c, artist.Version)
 	c.JSON(http.StatusCreated, artist)
 }
 
@@ -99,63 +135,228 @@ func (b *ArtistController) createArtistHandler(c *gin.Context) {
 // @Tags artists admin
 // @Produce json
 // @Param id path int true "Artist ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
 // @Success 204
-// @Failure 404 {string} string
+// @Failure 404 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/artists/{id} [delete]
 // @Authorize Bearer
 func (b *ArtistController) deleteArtistHandler(c *gin.Context) {
 	id, err := utils.GetIDParam(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	var artist models.Artist
-	if err := b.db.Read(&artist, uint(id)); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		var artist models.Artist
+		if err := tx.Read(c.Request.Context(), &artist, uint(id)); err != nil {
+			return err
+		}
+		if err := utils.CheckIfMatch(c, artist.Version); err != nil {
+			return err
+		}
+		if err := recordAudit(c, tx, models.AuditActionDelete, artist.ID, &artist, nil); err != nil {
+			return err
+		}
+		return tx.Delete(c.Request.Context(), &artist, uint(id))
+	})
+	if err != nil {
+		c.Error(err)
 		return
 	}
-
-	b.db.Delete(&artist, uint(id))
 	c.JSON(http.StatusNoContent, nil)
 }
 
 // @Summary Update artist
 // @Description Update an artist by ID
+// @Description Send the fields to change as JSON, a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Description selected by its Content-Type. Patches apply to the fields as they are, e.g. from the last GET.
 // @Tags artists admin
-// @Accept json
+// @Accept json,application/merge-patch+json,application/json-patch+json
 // @Produce json
 // @Param id path int true "Artist ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
 // @Param artist body ArtistDTO true "Artist fields to update"
 // @Success 200 {object} models.Artist
-// @Failure 400 {string} string
-// @Failure 404 {string} string
+// @Header 200 {string} ETag "ETag of the updated version"
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/artists/{id} [patch]
 // @Authorize Bearer
 func (b *ArtistController) updateArtistHandler(c *gin.Context) {
 	id, err := utils.GetIDParam(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	var artist models.Artist
-	if err := b.db.Read(&artist, uint(id)); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
+	update, err := readUpdate(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var artist *models.Artist
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		artist, err = b.updateArtist(c, tx, id, update, models.AuditActionUpdate)
+		return err
+	})
+	if err != nil {
+		c.Error(err)
 		return
 	}
+	utils.SetETag(c, artist.Version)
+	c.JSON(http.StatusOK, artist)
+}
+
+// updateArtist applies an update to an artist and records the change under action
+func (b *ArtistController) updateArtist(c *gin.Context, tx database.Service, id uint, update *updateDocument, action string) (*models.Artist, error) {
+	var artist models.Artist
+	if err := tx.Read(c.Request.Context(), &artist, id); err != nil {
+		return nil, err
+	}
+	if err := utils.CheckIfMatch(c, artist.Version); err != nil {
+		return nil, err
+	}
 
-	var updateDTO ArtistDTO
 	// Set the ID to enable partial updates through the required_without=ID validation
-	updateDTO.ID = &id
+	dto := &ArtistDTO{ID: &id}
+	if _, err := update.decode(artistFields(artist), dto); err != nil {
+		return nil, err
+	}
+
+	before := artist
+	dto.ApplyToModel(&artist)
+	if err := tx.Update(c.Request.Context(), &artist); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(c, tx, action, artist.ID, &before, &artist); err != nil {
+		return nil, err
+	}
+	return &artist, recordRevision(c, tx, models.RevisionEntityArtist, artist.ID, artistFields(before), artistFields(artist))
+}
+
+// rollbackArtist sets an artist back to the fields stored in a revision
+func (b *ArtistController) rollbackArtist(c *gin.Context, tx database.Service, id uint, revision *models.Revision) (any, error) {
+	update, err := jsonUpdate(revision.Data)
+	if err != nil {
+		return nil, err
+	}
+	return b.updateArtist(c, tx, id, update, models.AuditActionRollback)
+}
+
+// artistFields captures the fields of an artist that updates change and its revisions keep
+func artistFields(artist models.Artist) ArtistDTO {
+	return ArtistDTO{
+		ID:        &artist.ID,
+		FirstName: &artist.FirstName,
+		LastName:  &artist.LastName,
+	}
+}
+
+// @Summary List trashed artists
+// @Description Get a list of the deleted artists that can still be restored, with pagination
+// @Tags artists admin
+// @Produce json
+// @Param limit query int false "Limit number of artists returned"
+// @Param offset query int false "Offset for pagination"
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by including deleted_at, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.Artist]
+// @Failure 403 {object} types.Problem
+// @Router /admin/artists/trash [get]
+// @Authorize Bearer
+func (b *ArtistController) listTrashedArtistsHandler(c *gin.Context) {
+	listQuery, err := utils.ParseListQuery(c, database.TrashFields(database.ArtistFields))
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	if err := c.ShouldBindJSON(&updateDTO); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var artists []models.Artist
+	page, err := b.db.ListTrash(c.Request.Context(), &artists, listQuery)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	updateDTO.ApplyToModel(&artist)
+	c.JSON(http.StatusOK, utils.NewListResponse(c, artists, page, listQuery))
+}
+
+// @Summary Restore artist
+// @Description Restore a deleted artist from the trash together with their covers
+// @Tags artists admin
+// @Produce json
+// @Param id path int true "Artist ID"
+// @Success 200 {object} models.Artist
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/artists/trash/{id}/restore [post]
+// @Authorize Bearer
+func (b *ArtistController) restoreArtistHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	b.db.Update(&artist)
+	var artist models.Artist
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Restore(c.Request.Context(), &artist, id); err != nil {
+			return err
+		}
+		if err := recordRevision(c, tx, models.RevisionEntityArtist, artist.ID, nil, artistFields(artist)); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionRestore, artist.ID, nil, &artist)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.SetETag(c, artist.Version)
 	c.JSON(http.StatusOK, artist)
 }
+
+// @Summary Purge artist
+// @Description Permanently delete an artist in the trash together with their cover associations
+// @Tags artists admin
+// @Produce json
+// @Param id path int true "Artist ID"
+// @Success 204
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/artists/trash/{id} [delete]
+// @Authorize Bearer
+func (b *ArtistController) purgeArtistHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var artist models.Artist
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Purge(c.Request.Context(), &artist, id); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionPurge, artist.ID, &artist, nil)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
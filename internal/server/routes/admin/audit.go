@@ -0,0 +1,177 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/server/middleware"
+	"go-playground/internal/server/utils"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditController handles the audit log routes
+type AuditController struct {
+	db database.Service
+}
+
+// redactedFields are recorded as changed without their values
+var redactedFields = map[string]bool{
+	"password": true,
+}
+
+// Register routes for the audit module
+func RegisterAuditRoutes(r *gin.RouterGroup) {
+	controller := &AuditController{
+		db: database.New(),
+	}
+
+	r.GET("", middleware.RequirePermission(models.PermissionAuditRead), controller.listAuditEntriesHandler)
+}
+
+// @Summary List audit entries
+// @Description Get the changes made through the admin API with pagination. Filter on entity_type and entity_id
+// @Description for the history of an entity, on actor_id for the changes of a user and on created_at for a time range.
+// @Tags audit admin
+// @Produce json
+// @Param limit query int false "Limit number of entries returned"
+// @Param offset query int false "Offset for pagination"
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.AuditEntry]
+// @Failure 400 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/audit [get]
+// @Authorize Bearer
+func (controller *AuditController) listAuditEntriesHandler(c *gin.Context) {
+	listQuery, err := utils.ParseListQuery(c, database.AuditFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var entries []models.AuditEntry
+	page, err := controller.db.List(c.Request.Context(), &entries, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewListResponse(c, entries, page, listQuery))
+}
+
+// actor is who made a change and where the request came from, as kept in the audit log
+type actor struct {
+	userID    uint
+	ip        string
+	requestID string
+}
+
+// requestActor is the user making the request
+func requestActor(c *gin.Context) actor {
+	return actor{
+		userID:    c.MustGet("user").(*utils.Claims).UserID,
+		ip:        c.ClientIP(),
+		requestID: c.GetString("requestID"),
+	}
+}
+
+// recordAudit adds an entry for a change made by the requesting user to the audit log, using
+// the transaction of the change so neither is saved without the other. before is nil for
+// created entities and after is nil for deleted ones, the entity type is the model name.
+func recordAudit(c *gin.Context, tx database.Service, action string, entityID uint, before any, after any) error {
+	return addAuditEntry(c.Request.Context(), tx, requestActor(c), action, entityID, before, after)
+}
+
+// addAuditEntry adds an entry for a change made by actor to the audit log, like recordAudit
+func addAuditEntry(ctx context.Context, tx database.Service, actor actor, action string, entityID uint, before any, after any) error {
+	entity := after
+	if entity == nil {
+		entity = before
+	}
+	entityType := reflect.Indirect(reflect.ValueOf(entity)).Type()
+
+	changes, err := diffEntities(before, after, entityType)
+	if err != nil {
+		return err
+	}
+
+	entry := models.AuditEntry{
+		ActorID:    actor.userID,
+		Action:     action,
+		EntityType: strings.ToLower(entityType.Name()),
+		EntityID:   entityID,
+		Changes:    changes,
+		IP:         actor.ip,
+		RequestID:  actor.requestID,
+	}
+	return tx.Create(ctx, &entry)
+}
+
+// diffEntities compares the JSON form of two versions of an entity field by field.
+// A missing version is compared as the zero value, so only fields that were set show up.
+func diffEntities(before any, after any, entityType reflect.Type) (models.Changes, error) {
+	if before == nil {
+		before = reflect.New(entityType).Interface()
+	}
+	if after == nil {
+		after = reflect.New(entityType).Interface()
+	}
+
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+	return diffFields(beforeFields, afterFields), nil
+}
+
+// diffFields lists the fields whose value differs between two sets of JSON fields
+func diffFields(beforeFields map[string]any, afterFields map[string]any) models.Changes {
+	changes := models.Changes{}
+	for name := range mergeKeys(beforeFields, afterFields) {
+		// Every save touches the update time and version, they say nothing about what changed
+		if name == "UpdatedAt" || name == "version" || reflect.DeepEqual(beforeFields[name], afterFields[name]) {
+			continue
+		}
+
+		change := models.FieldChange{Before: beforeFields[name], After: afterFields[name]}
+		if redactedFields[name] {
+			change = models.FieldChange{Before: "[redacted]", After: "[redacted]"}
+		}
+		changes[name] = change
+	}
+	return changes
+}
+
+// jsonFields decodes the JSON form of an entity into its top level fields
+func jsonFields(entity any) (map[string]any, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]any{}
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+// mergeKeys returns the set of keys of both maps
+func mergeKeys(a map[string]any, b map[string]any) map[string]bool {
+	keys := map[string]bool{}
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	return keys
+}
@@ -3,9 +3,9 @@ package admin
 import (
 	"go-playground/internal/database"
 	"go-playground/internal/database/models"
+	"go-playground/internal/server/middleware"
 	"go-playground/internal/server/utils"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -46,10 +46,20 @@ func RegisterAuthorRoutes(r *gin.RouterGroup) {
 		db: database.New(),
 	}
 
-	r.GET("", controller.listAuthorsHandler)
-	r.POST("", controller.createAuthorHandler)
-	r.DELETE("/:id", controller.deleteAuthorHandler)
-	r.PATCH("/:id", controller.updateAuthorHandler)
+	r.GET("", middleware.RequirePermission(models.PermissionAuthorsRead), controller.listAuthorsHandler)
+	r.POST("", middleware.RequirePermission(models.PermissionAuthorsWrite), controller.createAuthorHandler)
+	r.DELETE("/:id", middleware.RequirePermission(models.PermissionAuthorsWrite), controller.deleteAuthorHandler)
+	r.PATCH("/:id", middleware.RequirePermission(models.PermissionAuthorsWrite), controller.updateAuthorHandler)
+	r.GET("/trash", middleware.RequirePermission(models.PermissionAuthorsRead), controller.listTrashedAuthorsHandler)
+	r.POST("/trash/:id/restore", middleware.RequirePermission(models.PermissionAuthorsWrite), controller.restoreAuthorHandler)
+	r.DELETE("/trash/:id", middleware.RequirePermission(models.PermissionAuthorsWrite), controller.purgeAuthorHandler)
+
+	revisions := &RevisionsController{
+		db:         controller.db,
+		entityType: models.RevisionEntityAuthor,
+		rollback:   controller.rollbackAuthor,
+	}
+	registerRevisionRoutes(r, revisions, models.PermissionAuthorsRead, models.PermissionAuthorsWrite)
 }
 
 // @Summary List authors
@@ -58,17 +68,28 @@ func RegisterAuthorRoutes(r *gin.RouterGroup) {
 // @Produce json
 // @Param limit query int false "Limit number of authors returned"
 // @Param offset query int false "Offset for pagination"
-// @Success 200 {array} models.Author
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.Author]
+// @Failure 403 {object} types.Problem
 // @Router /admin/authors [get]
 // @Authorize Bearer
 func (controller *AuthorsController) listAuthorsHandler(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	listQuery, err := utils.ParseListQuery(c, database.AuthorFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
 	var authors []models.Author
-	controller.db.List(&authors, limit, offset)
+	page, err := controller.db.List(c.Request.Context(), &authors, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	c.JSON(http.StatusOK, authors)
+	c.JSON(http.StatusOK, utils.NewListResponse(c, authors, page, listQuery))
 }
 
 // @Summary Create author
@@ -78,19 +99,34 @@ func (controller *AuthorsController) listAuthorsHandler(c *gin.Context) {
 // @Produce json
 // @Param author body AuthorDTO true "Author to create"
 // @Success 201 {object} models.Author
-// @Failure 400 {string} string
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/authors [post]
 // @Authorize Bearer
 func (controller *AuthorsController) createAuthorHandler(c *gin.Context) {
 	var inputDTO AuthorDTO
 	if err := c.ShouldBindJSON(&inputDTO); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	author := inputDTO.ToModel()
-	controller.db.Create(&author)
+	err := controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Create(c.Request.Context(), &author); err != nil {
+			return err
+		}
+		if err := recordAudit(c, tx, models.AuditActionCreate, author.ID, nil, &author); err != nil {
+			return err
+		}
+		return recordRevision(c, tx, models.RevisionEntityAuthor, author.ID, nil, authorFields(author))
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
+	utils.SetETag(c, author.Version)
 	c.JSON(http.StatusCreated, author)
 }
 
@@ -99,62 +135,228 @@ func (controller *AuthorsController) createAuthorHandler(c *gin.Context) {
 // @Tags authors admin
 // @Produce json
 // @Param id path int true "Author ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
 // @Success 204
-// @Failure 404 {string} string
+// @Failure 404 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/authors/{id} [delete]
 // @Authorize Bearer
 func (controller *AuthorsController) deleteAuthorHandler(c *gin.Context) {
 	id, err := utils.GetIDParam(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	var author models.Author
-	if err := controller.db.Read(&author, id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+	err = controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		var author models.Author
+		if err := tx.Read(c.Request.Context(), &author, id); err != nil {
+			return err
+		}
+		if err := utils.CheckIfMatch(c, author.Version); err != nil {
+			return err
+		}
+		if err := recordAudit(c, tx, models.AuditActionDelete, author.ID, &author, nil); err != nil {
+			return err
+		}
+		return tx.Delete(c.Request.Context(), &author, id)
+	})
+	if err != nil {
+		c.Error(err)
 		return
 	}
-
-	controller.db.Delete(&author, id)
 	c.Status(http.StatusNoContent)
 }
 
 // @Summary Update author
 // @Description Update an author by ID
+// @Description Send the fields to change as JSON, a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Description selected by its Content-Type. Patches apply to the fields as they are, e.g. from the last GET.
 // @Tags authors admin
-// @Accept json
+// @Accept json,application/merge-patch+json,application/json-patch+json
 // @Produce json
 // @Param id path int true "Author ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
 // @Param author body AuthorDTO true "Updated author data"
 // @Success 200 {object} models.Author
-// @Failure 400 {string} string
-// @Failure 404 {string} string
+// @Header 200 {string} ETag "ETag of the updated version"
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/authors/{id} [patch]
 // @Authorize Bearer
 func (controller *AuthorsController) updateAuthorHandler(c *gin.Context) {
 	id, err := utils.GetIDParam(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	var author models.Author
-	if err := controller.db.Read(&author, id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+	update, err := readUpdate(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	var updateDTO AuthorDTO
+	var author *models.Author
+	err = controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		author, err = controller.updateAuthor(c, tx, id, update, models.AuditActionUpdate)
+		return err
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.SetETag(c, author.Version)
+	c.JSON(http.StatusOK, author)
+}
+
+// updateAuthor applies an update to an author and records the change under action
+func (controller *AuthorsController) updateAuthor(c *gin.Context, tx database.Service, id uint, update *updateDocument, action string) (*models.Author, error) {
+	var author models.Author
+	if err := tx.Read(c.Request.Context(), &author, id); err != nil {
+		return nil, err
+	}
+	if err := utils.CheckIfMatch(c, author.Version); err != nil {
+		return nil, err
+	}
+
 	// Set the ID to enable partial updates through the required_without=ID validation
-	updateDTO.ID = &id
+	dto := &AuthorDTO{ID: &id}
+	if _, err := update.decode(authorFields(author), dto); err != nil {
+		return nil, err
+	}
 
-	if err := c.ShouldBindJSON(&updateDTO); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	before := author
+	dto.ApplyToModel(&author)
+	if err := tx.Update(c.Request.Context(), &author); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(c, tx, action, author.ID, &before, &author); err != nil {
+		return nil, err
+	}
+	return &author, recordRevision(c, tx, models.RevisionEntityAuthor, author.ID, authorFields(before), authorFields(author))
+}
+
+// rollbackAuthor sets an author back to the fields stored in a revision
+func (controller *AuthorsController) rollbackAuthor(c *gin.Context, tx database.Service, id uint, revision *models.Revision) (any, error) {
+	update, err := jsonUpdate(revision.Data)
+	if err != nil {
+		return nil, err
+	}
+	return controller.updateAuthor(c, tx, id, update, models.AuditActionRollback)
+}
+
+// authorFields captures the fields of an author that updates change and its revisions keep
+func authorFields(author models.Author) AuthorDTO {
+	return AuthorDTO{
+		ID:        &author.ID,
+		FirstName: &author.FirstName,
+		LastName:  &author.LastName,
+	}
+}
+
+// @Summary List trashed authors
+// @Description Get a list of the deleted authors that can still be restored, with pagination
+// @Tags authors admin
+// @Produce json
+// @Param limit query int false "Limit number of authors returned"
+// @Param offset query int false "Offset for pagination"
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by including deleted_at, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.Author]
+// @Failure 403 {object} types.Problem
+// @Router /admin/authors/trash [get]
+// @Authorize Bearer
+func (controller *AuthorsController) listTrashedAuthorsHandler(c *gin.Context) {
+	listQuery, err := utils.ParseListQuery(c, database.TrashFields(database.AuthorFields))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var authors []models.Author
+	page, err := controller.db.ListTrash(c.Request.Context(), &authors, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewListResponse(c, authors, page, listQuery))
+}
+
+// @Summary Restore author
+// @Description Restore a deleted author from the trash
+// @Tags authors admin
+// @Produce json
+// @Param id path int true "Author ID"
+// @Success 200 {object} models.Author
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/authors/trash/{id}/restore [post]
+// @Authorize Bearer
+func (controller *AuthorsController) restoreAuthorHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	updateDTO.ApplyToModel(&author)
-	controller.db.Update(&author)
+	var author models.Author
+	err = controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Restore(c.Request.Context(), &author, id); err != nil {
+			return err
+		}
+		if err := recordRevision(c, tx, models.RevisionEntityAuthor, author.ID, nil, authorFields(author)); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionRestore, author.ID, nil, &author)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.SetETag(c, author.Version)
 	c.JSON(http.StatusOK, author)
 }
+
+// @Summary Purge author
+// @Description Permanently delete an author in the trash. Their books have to be purged first.
+// @Tags authors admin
+// @Produce json
+// @Param id path int true "Author ID"
+// @Success 204
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/authors/trash/{id} [delete]
+// @Authorize Bearer
+func (controller *AuthorsController) purgeAuthorHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var author models.Author
+	err = controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Purge(c.Request.Context(), &author, id); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionPurge, author.ID, &author, nil)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
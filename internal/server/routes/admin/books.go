@@ -1,11 +1,12 @@
 package admin
 
 import (
+	"context"
 	"go-playground/internal/database"
 	"go-playground/internal/database/models"
+	"go-playground/internal/server/middleware"
 	"go-playground/internal/server/utils"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -28,6 +29,7 @@ type BookDTO struct {
 	ISBN          *string    `json:"isbn" binding:"required_without=ID"`
 	Price         *float32   `json:"price" binding:"required_without=ID"`
 	AuthorID      *uint      `json:"author_id" binding:"required_without=ID"`
+	GenreIDs      *[]uint    `json:"genre_ids"`
 }
 
 // ApplyToModel applies the DTO data to a model instance
@@ -71,10 +73,20 @@ func RegisterBookRoutes(r *gin.RouterGroup) {
 		db: database.New(),
 	}
 
-	r.GET("", controller.listBooksHandler)
-	r.POST("", controller.createBookHandler)
-	r.DELETE("/:id", controller.deleteBookHandler)
-	r.PATCH("/:id", controller.updateBookHandler)
+	r.GET("", middleware.RequirePermission(models.PermissionBooksRead), controller.listBooksHandler)
+	r.POST("", middleware.RequirePermission(models.PermissionBooksWrite), controller.createBookHandler)
+	r.DELETE("/:id", middleware.RequirePermission(models.PermissionBooksWrite), controller.deleteBookHandler)
+	r.PATCH("/:id", middleware.RequirePermission(models.PermissionBooksWrite), controller.updateBookHandler)
+	r.GET("/trash", middleware.RequirePermission(models.PermissionBooksRead), controller.listTrashedBooksHandler)
+	r.POST("/trash/:id/restore", middleware.RequirePermission(models.PermissionBooksWrite), controller.restoreBookHandler)
+	r.DELETE("/trash/:id", middleware.RequirePermission(models.PermissionBooksWrite), controller.purgeBookHandler)
+
+	revisions := &RevisionsController{
+		db:         controller.db,
+		entityType: models.RevisionEntityBook,
+		rollback:   controller.rollbackBook,
+	}
+	registerRevisionRoutes(r, revisions, models.PermissionBooksRead, models.PermissionBooksWrite)
 }
 
 // @Summary List books
@@ -83,17 +95,28 @@ func RegisterBookRoutes(r *gin.RouterGroup) {
 // @Produce json
 // @Param limit query int false "Limit number of books returned"
 // @Param offset query int false "Offset for pagination"
-// @Success 200 {array} models.Book
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.Book]
+// @Failure 403 {object} types.Problem
 // @Router /admin/books [get]
 // @Authorize Bearer
 func (b *BooksController) listBooksHandler(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	listQuery, err := utils.ParseListQuery(c, database.BookFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
 	var books []models.Book
-	b.db.List(&books, limit, offset)
+	page, err := b.db.List(c.Request.Context(), &books, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	c.JSON(http.StatusOK, books)
+	c.JSON(http.StatusOK, utils.NewListResponse(c, books, page, listQuery))
 }
 
 // @Summary Create book
@@ -103,18 +126,39 @@ func (b *BooksController) listBooksHandler(c *gin.Context) {
 // @Produce json
 // @Param book body BookDTO true "Book to create"
 // @Success 201 {object} models.Book
-// @Failure 400 {string} string
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/books [post]
 // @Authorize Bearer
 func (b *BooksController) createBookHandler(c *gin.Context) {
 	var inputDTO BookDTO
 	if err := c.ShouldBindJSON(&inputDTO); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	book := inputDTO.ToModel()
-	b.db.Create(&book)
+
+	err := b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		genres, err := checkBookReferences(c.Request.Context(), tx, &inputDTO)
+		if err != nil {
+			return err
+		}
+		book.Genres = genres
+		if err := tx.Create(c.Request.Context(), &book); err != nil {
+			return err
+		}
+		if err := recordAudit(c, tx, models.AuditActionCreate, book.ID, nil, &book); err != nil {
+			return err
+		}
+		return recordRevision(c, tx, models.RevisionEntityBook, book.ID, nil, bookFields(book))
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.SetETag(c, book.Version)
 	c.JSON(http.StatusCreated, book)
 }
 
@@ -123,62 +167,279 @@ func (b *BooksController) createBookHandler(c *gin.Context) {
 // @Tags books admin
 // @Produce json
 // @Param id path int true "Book ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
 // @Success 204
-// @Failure 404 {string} string
+// @Failure 404 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/books/{id} [delete]
 // @Authorize Bearer
 func (b *BooksController) deleteBookHandler(c *gin.Context) {
 	id, err := utils.GetIDParam(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	var book models.Book
-	if err := b.db.Read(&book, uint(id)); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		var book models.Book
+		if err := tx.Read(c.Request.Context(), &book, uint(id)); err != nil {
+			return err
+		}
+		if err := utils.CheckIfMatch(c, book.Version); err != nil {
+			return err
+		}
+		if err := recordAudit(c, tx, models.AuditActionDelete, book.ID, &book, nil); err != nil {
+			return err
+		}
+		return tx.Delete(c.Request.Context(), &book, uint(id))
+	})
+	if err != nil {
+		c.Error(err)
 		return
 	}
-
-	b.db.Delete(&book, uint(id))
 	c.JSON(http.StatusNoContent, nil)
 }
 
 // @Summary Update book
 // @Description Update a book by ID
+// @Description Send the fields to change as JSON, a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Description selected by its Content-Type. Patches apply to the fields as they are, e.g. from the last GET.
 // @Tags books admin
-// @Accept json
+// @Accept json,application/merge-patch+json,application/json-patch+json
 // @Produce json
 // @Param id path int true "Book ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
 // @Param book body BookDTO true "Book fields to update"
 // @Success 200 {object} models.Book
-// @Failure 400 {string} string
-// @Failure 404 {string} string
+// @Header 200 {string} ETag "ETag of the updated version"
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/books/{id} [patch]
 // @Authorize Bearer
 func (b *BooksController) updateBookHandler(c *gin.Context) {
 	id, err := utils.GetIDParam(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	var book models.Book
-	if err := b.db.Read(&book, uint(id)); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	update, err := readUpdate(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	var updateDTO BookDTO
+	var book *models.Book
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		book, err = b.updateBook(c, tx, id, update, models.AuditActionUpdate)
+		return err
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.SetETag(c, book.Version)
+	c.JSON(http.StatusOK, book)
+}
+
+// updateBook applies an update to a book and records the change under action
+func (b *BooksController) updateBook(c *gin.Context, tx database.Service, id uint, update *updateDocument, action string) (*models.Book, error) {
+	var book models.Book
+	if err := tx.Read(c.Request.Context(), &book, id); err != nil {
+		return nil, err
+	}
+	if err := utils.CheckIfMatch(c, book.Version); err != nil {
+		return nil, err
+	}
+
+	before := book
+	if err := tx.FindAssociation(c.Request.Context(), &book, "Genres", &before.Genres); err != nil {
+		return nil, err
+	}
+
 	// Set the ID to enable partial updates through the required_without=ID validation
-	updateDTO.ID = &id
+	dto := &BookDTO{ID: &id}
+	if _, err := update.decode(bookFields(before), dto); err != nil {
+		return nil, err
+	}
 
-	if err := c.ShouldBindJSON(&updateDTO); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	genres, err := checkBookReferences(c.Request.Context(), tx, dto)
+	if err != nil {
+		return nil, err
+	}
+
+	dto.ApplyToModel(&book)
+	if err := tx.Update(c.Request.Context(), &book); err != nil {
+		return nil, err
+	}
+
+	// Handle genre associations if provided
+	book.Genres = before.Genres
+	if dto.GenreIDs != nil {
+		book.Genres = genres
+		if err := tx.ReplaceAssociation(c.Request.Context(), &book, "Genres", book.Genres); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordAudit(c, tx, action, book.ID, &before, &book); err != nil {
+		return nil, err
+	}
+	return &book, recordRevision(c, tx, models.RevisionEntityBook, book.ID, bookFields(before), bookFields(book))
+}
+
+// rollbackBook sets a book back to the fields stored in a revision
+func (b *BooksController) rollbackBook(c *gin.Context, tx database.Service, id uint, revision *models.Revision) (any, error) {
+	update, err := jsonUpdate(revision.Data)
+	if err != nil {
+		return nil, err
+	}
+	return b.updateBook(c, tx, id, update, models.AuditActionRollback)
+}
+
+// @Summary List trashed books
+// @Description Get a list of the deleted books that can still be restored, with pagination
+// @Tags books admin
+// @Produce json
+// @Param limit query int false "Limit number of books returned"
+// @Param offset query int false "Offset for pagination"
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by including deleted_at, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.Book]
+// @Failure 403 {object} types.Problem
+// @Router /admin/books/trash [get]
+// @Authorize Bearer
+func (b *BooksController) listTrashedBooksHandler(c *gin.Context) {
+	listQuery, err := utils.ParseListQuery(c, database.TrashFields(database.BookFields))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var books []models.Book
+	page, err := b.db.ListTrash(c.Request.Context(), &books, listQuery)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	updateDTO.ApplyToModel(&book)
-	b.db.Update(&book)
+	c.JSON(http.StatusOK, utils.NewListResponse(c, books, page, listQuery))
+}
+
+// @Summary Restore book
+// @Description Restore a deleted book from the trash together with its genres.
+// @Description A book whose author is in the trash can only be restored after the author.
+// @Tags books admin
+// @Produce json
+// @Param id path int true "Book ID"
+// @Success 200 {object} models.Book
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/books/trash/{id}/restore [post]
+// @Authorize Bearer
+func (b *BooksController) restoreBookHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var book models.Book
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Restore(c.Request.Context(), &book, id); err != nil {
+			return err
+		}
+		if err := tx.FindAssociation(c.Request.Context(), &book, "Genres", &book.Genres); err != nil {
+			return err
+		}
+		if err := recordRevision(c, tx, models.RevisionEntityBook, book.ID, nil, bookFields(book)); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionRestore, book.ID, nil, &book)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.SetETag(c, book.Version)
 	c.JSON(http.StatusOK, book)
 }
+
+// @Summary Purge book
+// @Description Permanently delete a book in the trash together with its genre associations.
+// @Description Its cover has to be purged first.
+// @Tags books admin
+// @Produce json
+// @Param id path int true "Book ID"
+// @Success 204
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/books/trash/{id} [delete]
+// @Authorize Bearer
+func (b *BooksController) purgeBookHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var book models.Book
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Purge(c.Request.Context(), &book, id); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionPurge, book.ID, &book, nil)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// bookFields captures the fields of a book that updates change and its revisions keep
+func bookFields(book models.Book) BookDTO {
+	genreIDs := make([]uint, 0, len(book.Genres))
+	for _, genre := range book.Genres {
+		genreIDs = append(genreIDs, genre.ID)
+	}
+
+	return BookDTO{
+		ID:            &book.ID,
+		Title:         &book.Title,
+		PublishedDate: &book.PublishedDate,
+		Pages:         &book.Pages,
+		Description:   &book.Description,
+		DigitalOnly:   &book.DigitalOnly,
+		ISBN:          &book.ISBN,
+		Price:         &book.Price,
+		AuthorID:      &book.AuthorID,
+		GenreIDs:      &genreIDs,
+	}
+}
+
+// checkBookReferences makes sure the author and genres given in the DTO exist and returns the genres
+func checkBookReferences(ctx context.Context, tx database.Service, dto *BookDTO) ([]*models.Genre, error) {
+	if dto.AuthorID != nil {
+		if err := readReference(ctx, tx, "author_id", &models.Author{}, *dto.AuthorID); err != nil {
+			return nil, err
+		}
+	}
+	if dto.GenreIDs == nil {
+		return nil, nil
+	}
+	genres := []*models.Genre{}
+	err := readReferences(ctx, tx, "genre_ids", &genres, *dto.GenreIDs)
+	return genres, err
+}
@@ -1,10 +1,24 @@
 package admin
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"go-playground/internal/database"
 	"go-playground/internal/database/models"
+	"go-playground/internal/imaging"
+	"go-playground/internal/server/middleware"
+	"go-playground/internal/server/types"
 	"go-playground/internal/server/utils"
+	"go-playground/internal/storage"
+	"io"
+	"log"
 	"net/http"
+	"os"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -12,7 +26,21 @@ import (
 
 // CoverController handles cover-related routes
 type CoverController struct {
-	db database.Service
+	db      database.Service
+	storage storage.Storage
+
+	maxImageSize int64
+}
+
+// defaultMaxImageSize limits cover uploads unless COVER_MAX_IMAGE_SIZE is set
+const defaultMaxImageSize = 5 << 20
+
+// coverImageTypes maps the accepted image content types to their file extension
+var coverImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
 }
 
 // CoverDTO is used for both create and update operations
@@ -50,13 +78,31 @@ func (dto *CoverDTO) ToModel() models.Cover {
 // Register routes for the books module
 func RegisterCoverRoutes(r *gin.RouterGroup) {
 	controller := &CoverController{
-		db: database.New(),
+		db:      database.New(),
+		storage: storage.New(),
+
+		maxImageSize: defaultMaxImageSize,
+	}
+	if size, err := strconv.ParseInt(os.Getenv("COVER_MAX_IMAGE_SIZE"), 10, 64); err == nil && size > 0 {
+		controller.maxImageSize = size
 	}
 
-	r.GET("", controller.listCoversHandler)
-	r.POST("", controller.createCoverHandler)
-	r.DELETE("/:id", controller.deleteCoverHandler)
-	r.PATCH("/:id", controller.updateCoverHandler)
+	r.GET("", middleware.RequirePermission(models.PermissionCoversRead), controller.listCoversHandler)
+	r.POST("", middleware.RequirePermission(models.PermissionCoversWrite), controller.createCoverHandler)
+	r.DELETE("/:id", middleware.RequirePermission(models.PermissionCoversWrite), controller.deleteCoverHandler)
+	r.PATCH("/:id", middleware.RequirePermission(models.PermissionCoversWrite), controller.updateCoverHandler)
+	r.GET("/trash", middleware.RequirePermission(models.PermissionCoversRead), controller.listTrashedCoversHandler)
+	r.POST("/trash/:id/restore", middleware.RequirePermission(models.PermissionCoversWrite), controller.restoreCoverHandler)
+	r.DELETE("/trash/:id", middleware.RequirePermission(models.PermissionCoversWrite), controller.purgeCoverHandler)
+	r.POST("/:id/image", middleware.RequirePermission(models.PermissionCoversWrite), controller.uploadCoverImageHandler)
+	r.DELETE("/:id/image", middleware.RequirePermission(models.PermissionCoversWrite), controller.deleteCoverImageHandler)
+
+	revisions := &RevisionsController{
+		db:         controller.db,
+		entityType: models.RevisionEntityCover,
+		rollback:   controller.rollbackCover,
+	}
+	registerRevisionRoutes(r, revisions, models.PermissionCoversRead, models.PermissionCoversWrite)
 }
 
 // @Summary List covers
@@ -65,20 +111,27 @@ func RegisterCoverRoutes(r *gin.RouterGroup) {
 // @Produce json
 // @Param limit query int false "Limit number of covers returned"
 // @Param offset query int false "Offset for pagination"
-// @Success 200 {array} models.Cover
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.Cover]
+// @Failure 403 {object} types.Problem
 // @Router /admin/covers [get]
 // @Authorize Bearer
 func (b *CoverController) listCoversHandler(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	listQuery, err := utils.ParseListQuery(c, database.CoverFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	covers, err := b.db.ListCovers(limit, offset)
+	covers, page, err := b.db.ListCovers(c.Request.Context(), listQuery)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, covers)
+	c.JSON(http.StatusOK, utils.NewListResponse(c, covers, page, listQuery))
 }
 
 // @Summary Create cover
@@ -88,31 +141,39 @@ func (b *CoverController) listCoversHandler(c *gin.Context) {
 // @Produce json
 // @Param cover body CoverDTO true "Cover to create"
 // @Success 201 {object} models.Cover
-// @Failure 400 {string} string
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/covers [post]
 // @Authorize Bearer
 func (b *CoverController) createCoverHandler(c *gin.Context) {
 	var inputDTO CoverDTO
 	if err := c.ShouldBindJSON(&inputDTO); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	cover := inputDTO.ToModel()
 
-	// Handle artist associations if provided
-	if inputDTO.ArtistIDs != nil && len(*inputDTO.ArtistIDs) > 0 {
-		var artists []*models.Artist
-		for _, artistID := range *inputDTO.ArtistIDs {
-			artist := &models.Artist{}
-			if err := b.db.Read(artist, artistID); err == nil {
-				artists = append(artists, artist)
-			}
+	err := b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		artists, err := checkCoverReferences(c.Request.Context(), tx, &inputDTO)
+		if err != nil {
+			return err
 		}
 		cover.Artists = artists
+		if err := tx.Create(c.Request.Context(), &cover); err != nil {
+			return err
+		}
+		if err := recordAudit(c, tx, models.AuditActionCreate, cover.ID, nil, &cover); err != nil {
+			return err
+		}
+		return recordRevision(c, tx, models.RevisionEntityCover, cover.ID, nil, coverRevision(cover))
+	})
+	if err != nil {
+		c.Error(err)
+		return
 	}
-
-	b.db.Create(&cover)
+	utils.SetETag(c, cover.Version)
 	c.JSON(http.StatusCreated, cover)
 }
 
@@ -121,75 +182,531 @@ func (b *CoverController) createCoverHandler(c *gin.Context) {
 // @Tags covers admin
 // @Produce json
 // @Param id path int true "Cover ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
 // @Success 204
-// @Failure 404 {string} string
+// @Failure 404 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/covers/{id} [delete]
 // @Authorize Bearer
 func (b *CoverController) deleteCoverHandler(c *gin.Context) {
 	id, err := utils.GetIDParam(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	var cover models.Cover
-	if err := b.db.Read(&cover, uint(id)); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		var cover models.Cover
+		if err := tx.Read(c.Request.Context(), &cover, uint(id)); err != nil {
+			return err
+		}
+		if err := utils.CheckIfMatch(c, cover.Version); err != nil {
+			return err
+		}
+		if err := recordAudit(c, tx, models.AuditActionDelete, cover.ID, &cover, nil); err != nil {
+			return err
+		}
+		return tx.Delete(c.Request.Context(), &cover, uint(id))
+	})
+	if err != nil {
+		c.Error(err)
 		return
 	}
-
-	b.db.Delete(&cover, uint(id))
 	c.JSON(http.StatusNoContent, nil)
 }
 
 // @Summary Update cover
 // @Description Update a cover by ID
+// @Description Send the fields to change as JSON, a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Description selected by its Content-Type. Patches apply to the fields as they are, e.g. from the last GET.
+// @Description A patch can clear design_ideas and image_url with null and add or remove single artist_ids.
 // @Tags covers admin
-// @Accept json
+// @Accept json,application/merge-patch+json,application/json-patch+json
 // @Produce json
 // @Param id path int true "Cover ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
 // @Param cover body CoverDTO true "Cover fields to update"
 // @Success 200 {object} models.Cover
-// @Failure 400 {string} string
-// @Failure 404 {string} string
+// @Header 200 {string} ETag "ETag of the updated version"
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
 // @Router /admin/covers/{id} [patch]
 // @Authorize Bearer
 func (b *CoverController) updateCoverHandler(c *gin.Context) {
 	id, err := utils.GetIDParam(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	var cover models.Cover
-	if err := b.db.Read(&cover, uint(id)); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+	update, err := readUpdate(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var cover *models.Cover
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		cover, err = b.updateCover(c, tx, id, update, models.AuditActionUpdate)
+		return err
+	})
+	if err != nil {
+		c.Error(err)
 		return
 	}
+	utils.SetETag(c, cover.Version)
+	c.JSON(http.StatusOK, cover)
+}
+
+// updateCover applies an update to a cover and records the change under action.
+// Patches may set design_ideas and image_url to null to clear them.
+func (b *CoverController) updateCover(c *gin.Context, tx database.Service, id uint, update *updateDocument, action string) (*models.Cover, error) {
+	var cover models.Cover
+	if err := tx.Read(c.Request.Context(), &cover, id); err != nil {
+		return nil, err
+	}
+	if err := utils.CheckIfMatch(c, cover.Version); err != nil {
+		return nil, err
+	}
+
+	before := cover
+	if err := tx.FindAssociation(c.Request.Context(), &cover, "Artists", &before.Artists); err != nil {
+		return nil, err
+	}
 
-	var updateDTO CoverDTO
 	// Set the ID to enable partial updates through the required_without=ID validation
-	updateDTO.ID = &id
+	dto := &CoverDTO{ID: &id}
+	cleared, err := update.decode(coverFields(before), dto, "design_ideas", "image_url")
+	if err != nil {
+		return nil, err
+	}
 
-	if err := c.ShouldBindJSON(&updateDTO); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	artists, err := checkCoverReferences(c.Request.Context(), tx, dto)
+	if err != nil {
+		return nil, err
 	}
 
-	updateDTO.ApplyToModel(&cover)
+	dto.ApplyToModel(&cover)
+	for _, field := range cleared {
+		switch field {
+		case "design_ideas":
+			cover.DesignIdeas = sql.NullString{}
+		case "image_url":
+			cover.ImageURL = sql.NullString{}
+		}
+	}
+	if err := tx.Update(c.Request.Context(), &cover); err != nil {
+		return nil, err
+	}
 
 	// Handle artist associations if provided
-	if updateDTO.ArtistIDs != nil {
-		var artists []*models.Artist
-		for _, artistID := range *updateDTO.ArtistIDs {
-			artist := &models.Artist{}
-			if err := b.db.Read(artist, artistID); err == nil {
-				artists = append(artists, artist)
-			}
-		}
+	cover.Artists = before.Artists
+	if dto.ArtistIDs != nil {
 		cover.Artists = artists
+		if err := tx.ReplaceAssociation(c.Request.Context(), &cover, "Artists", cover.Artists); err != nil {
+			return nil, err
+		}
 	}
 
-	b.db.Update(&cover)
+	if err := recordAudit(c, tx, action, cover.ID, &before, &cover); err != nil {
+		return nil, err
+	}
+	return &cover, recordRevision(c, tx, models.RevisionEntityCover, cover.ID, coverRevision(before), coverRevision(cover))
+}
+
+// rollbackCover sets a cover back to the fields stored in a revision
+func (b *CoverController) rollbackCover(c *gin.Context, tx database.Service, id uint, revision *models.Revision) (any, error) {
+	update, err := jsonUpdate(revision.Data)
+	if err != nil {
+		return nil, err
+	}
+	return b.updateCover(c, tx, id, update, models.AuditActionRollback)
+}
+
+// coverFields captures the fields of a cover that updates change
+func coverFields(cover models.Cover) CoverDTO {
+	artistIDs := make([]uint, 0, len(cover.Artists))
+	for _, artist := range cover.Artists {
+		artistIDs = append(artistIDs, artist.ID)
+	}
+
+	fields := CoverDTO{
+		ID:        &cover.ID,
+		BookID:    &cover.BookID,
+		ArtistIDs: &artistIDs,
+	}
+	if cover.DesignIdeas.Valid {
+		fields.DesignIdeas = &cover.DesignIdeas.String
+	}
+	if cover.ImageURL.Valid {
+		fields.ImageURL = &cover.ImageURL.String
+	}
+	return fields
+}
+
+// coverRevision captures the fields of a cover that its revisions keep. The image_url is left
+// out, it belongs to the uploaded image and the files of a replaced image are not kept.
+func coverRevision(cover models.Cover) CoverDTO {
+	revision := coverFields(cover)
+	revision.ImageURL = nil
+	return revision
+}
+
+// @Summary List trashed covers
+// @Description Get a list of the deleted covers that can still be restored, with pagination
+// @Tags covers admin
+// @Produce json
+// @Param limit query int false "Limit number of covers returned"
+// @Param offset query int false "Offset for pagination"
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by including deleted_at, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.Cover]
+// @Failure 403 {object} types.Problem
+// @Router /admin/covers/trash [get]
+// @Authorize Bearer
+func (b *CoverController) listTrashedCoversHandler(c *gin.Context) {
+	listQuery, err := utils.ParseListQuery(c, database.TrashFields(database.CoverFields))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var covers []models.Cover
+	page, err := b.db.ListTrash(c.Request.Context(), &covers, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewListResponse(c, covers, page, listQuery))
+}
+
+// @Summary Restore cover
+// @Description Restore a deleted cover from the trash together with its artists and image.
+// @Description A cover whose book is in the trash can only be restored after the book.
+// @Tags covers admin
+// @Produce json
+// @Param id path int true "Cover ID"
+// @Success 200 {object} models.Cover
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/covers/trash/{id}/restore [post]
+// @Authorize Bearer
+func (b *CoverController) restoreCoverHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var cover models.Cover
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Restore(c.Request.Context(), &cover, id); err != nil {
+			return err
+		}
+		if err := tx.FindAssociation(c.Request.Context(), &cover, "Artists", &cover.Artists); err != nil {
+			return err
+		}
+		if err := recordRevision(c, tx, models.RevisionEntityCover, cover.ID, nil, coverRevision(cover)); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionRestore, cover.ID, nil, &cover)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	utils.SetETag(c, cover.Version)
 	c.JSON(http.StatusOK, cover)
 }
+
+// @Summary Purge cover
+// @Description Permanently delete a cover in the trash together with its artist associations,
+// @Description its uploaded image and the variants of the image.
+// @Tags covers admin
+// @Produce json
+// @Param id path int true "Cover ID"
+// @Success 204
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/covers/trash/{id} [delete]
+// @Authorize Bearer
+func (b *CoverController) purgeCoverHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var cover models.Cover
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Purge(c.Request.Context(), &cover, id); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionPurge, cover.ID, &cover, nil)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	b.deleteImages(c.Request.Context(), cover.ImageKeys())
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// checkCoverReferences makes sure the book and artists given in the DTO exist and returns the artists
+func checkCoverReferences(ctx context.Context, tx database.Service, dto *CoverDTO) ([]*models.Artist, error) {
+	if dto.BookID != nil {
+		if err := readReference(ctx, tx, "book_id", &models.Book{}, *dto.BookID); err != nil {
+			return nil, err
+		}
+	}
+	if dto.ArtistIDs == nil {
+		return nil, nil
+	}
+	artists := []*models.Artist{}
+	err := readReferences(ctx, tx, "artist_ids", &artists, *dto.ArtistIDs)
+	return artists, err
+}
+
+// @Summary Upload cover image
+// @Description Upload the image of a cover as multipart form data, replacing any previous image.
+// @Description JPEG, PNG, GIF and WebP images are accepted, the type is detected from the file content.
+// @Description Thumbnail, medium and large variants are generated in JPEG or PNG and in WebP, together
+// @Description with a dominant color and a blurhash placeholder. The image_url of the cover is set to
+// @Description the URL the original image is served from.
+// @Tags covers admin
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Cover ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
+// @Param image formData file true "Image file"
+// @Success 200 {object} models.Cover
+// @Header 200 {string} ETag "ETag of the updated version"
+// @Failure 400 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Failure 413 {object} types.Problem
+// @Failure 415 {object} types.Problem
+// @Router /admin/covers/{id}/image [post]
+// @Authorize Bearer
+func (b *CoverController) uploadCoverImageHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	cover, err := b.db.GetCover(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := utils.CheckIfMatch(c, cover.Version); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Leave some room for the multipart headers around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, b.maxImageSize+64<<10)
+
+	file, header, err := c.Request.FormFile("image")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.Error(utils.NewAPIError(http.StatusRequestEntityTooLarge, "payload_too_large", fmt.Sprintf("Image must not be larger than %d bytes", b.maxImageSize)))
+			return
+		}
+		c.Error(utils.ValidationFailed(types.FieldError{Field: "image", Message: "is required"}))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > b.maxImageSize {
+		c.Error(utils.NewAPIError(http.StatusRequestEntityTooLarge, "payload_too_large", fmt.Sprintf("Image must not be larger than %d bytes", b.maxImageSize)))
+		return
+	}
+
+	// Detect the type from the content, the client supplied Content-Type cannot be trusted
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		c.Error(utils.NewAPIError(http.StatusBadRequest, "invalid_image", "Could not read image file"))
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	extension, ok := coverImageTypes[contentType]
+	if !ok {
+		c.Error(utils.NewAPIError(http.StatusUnsupportedMediaType, "unsupported_media_type", "Image must be a JPEG, PNG, GIF or WebP file"))
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.Error(err)
+		return
+	}
+
+	processed, err := imaging.Process(file)
+	if err != nil {
+		if errors.Is(err, imaging.ErrTooManyPixels) {
+			c.Error(utils.NewAPIError(http.StatusRequestEntityTooLarge, "image_too_large", "Image dimensions are too large"))
+			return
+		}
+		c.Error(utils.NewAPIError(http.StatusUnsupportedMediaType, "unsupported_media_type", "Could not decode image"))
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// A new name for every upload lets clients cache images forever
+	name := make([]byte, 8)
+	if _, err := rand.Read(name); err != nil {
+		c.Error(err)
+		return
+	}
+	base := fmt.Sprintf("covers/%d/%s", cover.ID, hex.EncodeToString(name))
+	key := base + extension
+
+	ctx := c.Request.Context()
+	if err := b.storage.Put(ctx, key, file, header.Size, contentType); err != nil {
+		c.Error(err)
+		return
+	}
+	stored := []string{key}
+
+	var variants []models.CoverVariant
+	for _, variant := range processed.Variants {
+		variantKey := fmt.Sprintf("%s-%s%s", base, variant.Name, variant.Extension)
+		if err := b.storage.Put(ctx, variantKey, bytes.NewReader(variant.Data), int64(len(variant.Data)), variant.ContentType); err != nil {
+			b.deleteImages(ctx, stored)
+			c.Error(err)
+			return
+		}
+		stored = append(stored, variantKey)
+
+		variants = append(variants, models.CoverVariant{
+			Name:   variant.Name,
+			Format: variant.Format,
+			Width:  variant.Width,
+			Height: variant.Height,
+			URL:    utils.FileURL(c, variantKey),
+			Key:    variantKey,
+		})
+	}
+
+	previous := cover.ImageKeys()
+	before := *cover
+
+	cover.ImageKey = key
+	cover.ImageURL.String = utils.FileURL(c, key)
+	cover.ImageURL.Valid = true
+	cover.ImageWidth = processed.Width
+	cover.ImageHeight = processed.Height
+	cover.DominantColor = processed.DominantColor
+	cover.BlurHash = processed.BlurHash
+
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.SaveCoverImage(c.Request.Context(), cover, variants); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionUpdate, cover.ID, &before, cover)
+	})
+	if err != nil {
+		b.deleteImages(ctx, stored)
+		c.Error(err)
+		return
+	}
+
+	b.deleteImages(ctx, previous)
+	utils.SetETag(c, cover.Version)
+	c.JSON(http.StatusOK, cover)
+}
+
+// @Summary Delete cover image
+// @Description Delete the uploaded image of a cover with its variants and clear its image_url
+// @Tags covers admin
+// @Produce json
+// @Param id path int true "Cover ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
+// @Success 200 {object} models.Cover
+// @Header 200 {string} ETag "ETag of the updated version"
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/covers/{id}/image [delete]
+// @Authorize Bearer
+func (b *CoverController) deleteCoverImageHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	cover, err := b.db.GetCover(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := utils.CheckIfMatch(c, cover.Version); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if cover.ImageKey == "" {
+		c.Error(utils.NewAPIError(http.StatusNotFound, "not_found", "Cover has no uploaded image"))
+		return
+	}
+
+	previous := cover.ImageKeys()
+	before := *cover
+
+	cover.ImageKey = ""
+	cover.ImageURL.String = ""
+	cover.ImageURL.Valid = false
+	cover.ImageWidth = 0
+	cover.ImageHeight = 0
+	cover.DominantColor = ""
+	cover.BlurHash = ""
+
+	err = b.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.SaveCoverImage(c.Request.Context(), cover, []models.CoverVariant{}); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionUpdate, cover.ID, &before, cover)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	b.deleteImages(c.Request.Context(), previous)
+	utils.SetETag(c, cover.Version)
+	c.JSON(http.StatusOK, cover)
+}
+
+// deleteImages removes stored files that are no longer referenced.
+// Failures are only logged, a leftover file does not affect the cover.
+func (b *CoverController) deleteImages(ctx context.Context, keys []string) {
+	// Clean up even when the request was canceled, that is often why we are cleaning up
+	ctx = context.WithoutCancel(ctx)
+	for _, key := range keys {
+		if err := b.storage.Delete(ctx, key); err != nil {
+			log.Printf("could not delete cover image %s: %v", key, err)
+		}
+	}
+}
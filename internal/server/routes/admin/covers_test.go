@@ -0,0 +1,270 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/server/middleware"
+	"go-playground/internal/server/types"
+	"go-playground/internal/server/utils"
+	"go-playground/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// coverDB serves a single cover and records what the upload handler saves
+type coverDB struct {
+	database.Service
+
+	cover models.Cover
+	saved *models.Cover
+}
+
+func (db *coverDB) GetCover(ctx context.Context, id uint) (*models.Cover, error) {
+	if id != db.cover.ID {
+		return nil, &database.Error{Kind: database.ErrNotFound, Entity: "Cover"}
+	}
+	cover := db.cover
+	return &cover, nil
+}
+
+func (db *coverDB) WithTx(ctx context.Context, fn func(tx database.Service) error) error {
+	return fn(db)
+}
+
+func (db *coverDB) SaveCoverImage(ctx context.Context, cover *models.Cover, variants []models.CoverVariant) error {
+	cover.Variants = variants
+	db.saved = cover
+	return nil
+}
+
+func (db *coverDB) Create(ctx context.Context, entity any) error {
+	return nil
+}
+
+// newCoverTestRouter serves the upload handler of a cover controller backed by
+// coverDB and a local storage in a temporary directory
+func newCoverTestRouter(t *testing.T, maxImageSize int64) (*gin.Engine, *coverDB, string) {
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	files, err := storage.NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := &coverDB{cover: models.Cover{Model: gorm.Model{ID: 7}, Versioned: models.Versioned{Version: 1}}}
+	controller := &CoverController{db: db, storage: files, maxImageSize: maxImageSize}
+
+	r := gin.New()
+	r.Use(middleware.ErrorMiddleware())
+	r.Use(func(c *gin.Context) {
+		c.Set("user", &utils.Claims{UserID: 1})
+	})
+	r.POST("/covers/:id/image", controller.uploadCoverImageHandler)
+	return r, db, dir
+}
+
+// uploadImage posts data as the image field of a multipart form, declaring contentType for the file
+func uploadImage(r *gin.Engine, filename string, contentType string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="image"; filename="`+filename+`"`)
+	header.Set("Content-Type", contentType)
+	part, _ := form.CreatePart(header)
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/covers/7/image", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 8), G: 96, B: uint8(y * 8), A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// problemCode returns the code of the problem document in a response
+func problemCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	var problem types.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("response is not a problem document: %s", w.Body)
+	}
+	return problem.Code
+}
+
+func TestUploadCoverImageSniffsContentType(t *testing.T) {
+	r, db, dir := newCoverTestRouter(t, defaultMaxImageSize)
+
+	// The declared type and file name are ignored, the content is a PNG
+	w := uploadImage(r, "cover.jpg", "image/jpeg", encodePNG(t, 32, 24))
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload returned %d: %s", w.Code, w.Body)
+	}
+
+	if db.saved == nil {
+		t.Fatal("the cover image was not saved")
+	}
+	if !strings.HasPrefix(db.saved.ImageKey, "covers/7/") || !strings.HasSuffix(db.saved.ImageKey, ".png") {
+		t.Errorf("image stored under %q, want covers/7/*.png", db.saved.ImageKey)
+	}
+	if db.saved.ImageWidth != 32 || db.saved.ImageHeight != 24 {
+		t.Errorf("image is %dx%d, want 32x24", db.saved.ImageWidth, db.saved.ImageHeight)
+	}
+	if len(db.saved.Variants) == 0 {
+		t.Error("no variants were generated")
+	}
+
+	// The original and its variants are stored
+	stored := storedFiles(t, dir)
+	if len(stored) != len(db.saved.Variants)+1 {
+		t.Errorf("stored %v, want the image and %d variants", stored, len(db.saved.Variants))
+	}
+}
+
+func TestUploadCoverImageRejectsUnsupportedTypes(t *testing.T) {
+	// A PNG signature followed by anything but an image is sniffed as PNG, yet cannot be decoded
+	corrupt := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+	// A GIF of 0x0 pixels decodes fine, yet cannot be resized
+	var empty bytes.Buffer
+	if err := gif.Encode(&empty, image.NewPaletted(image.Rect(0, 0, 0, 0), palette.Plan9), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		data        []byte
+	}{
+		{"text declared as png", "image/png", []byte("just some text, not an image")},
+		{"pdf", "application/pdf", []byte("%PDF-1.7\n1 0 obj\n<<>>\nendobj\n")},
+		{"svg", "image/svg+xml", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)},
+		{"corrupt png", "image/png", corrupt},
+		{"empty gif", "image/gif", empty.Bytes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, db, dir := newCoverTestRouter(t, defaultMaxImageSize)
+
+			w := uploadImage(r, "cover.png", tt.contentType, tt.data)
+			if w.Code != http.StatusUnsupportedMediaType {
+				t.Fatalf("upload returned %d, want 415: %s", w.Code, w.Body)
+			}
+			if code := problemCode(t, w); code != "unsupported_media_type" {
+				t.Errorf("problem code is %q, want unsupported_media_type", code)
+			}
+			if db.saved != nil {
+				t.Error("a rejected image was saved")
+			}
+			if stored := storedFiles(t, dir); len(stored) > 0 {
+				t.Errorf("a rejected image was stored: %v", stored)
+			}
+		})
+	}
+}
+
+func TestUploadCoverImageRejectsLargeImages(t *testing.T) {
+	small := encodePNG(t, 32, 32)
+
+	// A valid PNG header claiming more pixels than imaging.MaxPixels, the checksum is fixed up
+	bomb := encodePNG(t, 1, 1)
+	binary.BigEndian.PutUint32(bomb[16:20], 20_000)
+	binary.BigEndian.PutUint32(bomb[20:24], 20_000)
+	binary.BigEndian.PutUint32(bomb[29:33], crc32.ChecksumIEEE(bomb[12:29]))
+
+	tests := []struct {
+		name         string
+		maxImageSize int64
+		data         []byte
+		code         string
+	}{
+		{"file larger than the limit", int64(len(small)) - 1, small, "payload_too_large"},
+		{"body far larger than the limit", 1024, append(small, bytes.Repeat([]byte{0}, 256<<10)...), "payload_too_large"},
+		{"too many pixels", defaultMaxImageSize, bomb, "image_too_large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, db, dir := newCoverTestRouter(t, tt.maxImageSize)
+
+			w := uploadImage(r, "cover.png", "image/png", tt.data)
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("upload returned %d, want 413: %s", w.Code, w.Body)
+			}
+			if code := problemCode(t, w); code != tt.code {
+				t.Errorf("problem code is %q, want %q", code, tt.code)
+			}
+			if db.saved != nil {
+				t.Error("a rejected image was saved")
+			}
+			if stored := storedFiles(t, dir); len(stored) > 0 {
+				t.Errorf("a rejected image was stored: %v", stored)
+			}
+		})
+	}
+}
+
+func TestUploadCoverImageRequiresFile(t *testing.T) {
+	r, _, _ := newCoverTestRouter(t, defaultMaxImageSize)
+
+	req := httptest.NewRequest(http.MethodPost, "/covers/7/image", strings.NewReader(""))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("upload without a file returned %d, want 422: %s", w.Code, w.Body)
+	}
+}
+
+// storedFiles lists the files below a local storage directory
+func storedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
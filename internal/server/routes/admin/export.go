@@ -0,0 +1,383 @@
+package admin
+
+import (
+	"context"
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/exporter"
+	"go-playground/internal/marc"
+	"go-playground/internal/onix"
+	"go-playground/internal/server/middleware"
+	"go-playground/internal/server/types"
+	"go-playground/internal/server/utils"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportController handles the catalog export routes
+type ExportController struct {
+	db database.Service
+
+	// onixSender names the sender, publisher and supplier of the ONIX export
+	onixSender string
+}
+
+// defaultONIXSender names the sender of the ONIX export unless ONIX_SENDER_NAME is set
+const defaultONIXSender = "Go Playground"
+
+// Register routes for the export module. The exports are streamed, keep them off the ETag
+// middleware, it would buffer the whole catalog.
+func RegisterExportRoutes(r *gin.RouterGroup) {
+	controller := &ExportController{
+		db: database.New(),
+
+		onixSender: defaultONIXSender,
+	}
+	if sender := os.Getenv("ONIX_SENDER_NAME"); sender != "" {
+		controller.onixSender = sender
+	}
+
+	r.GET("/books", middleware.RequirePermission(models.PermissionBooksRead), controller.exportBooksHandler)
+	r.GET("/authors", middleware.RequirePermission(models.PermissionAuthorsRead), controller.exportAuthorsHandler)
+	r.GET("/artists", middleware.RequirePermission(models.PermissionArtistsRead), controller.exportArtistsHandler)
+	r.GET("/covers", middleware.RequirePermission(models.PermissionCoversRead), controller.exportCoversHandler)
+	r.GET("/onix", middleware.RequirePermission(models.PermissionBooksRead), controller.exportONIXHandler)
+	r.GET("/marc", middleware.RequirePermission(models.PermissionBooksRead), controller.exportMARCHandler)
+}
+
+// @Summary Export books
+// @Description Stream every book with its author, cover and genres, ordered by ID. The format follows
+// @Description Accept: a JSON array (default), NDJSON with a book per line or CSV with a header row, where
+// @Description genres are separated by |. The CSV can be imported again. A response that fails after it
+// @Description started is cut off without its end, so it never looks complete.
+// @Tags export admin
+// @Produce json,application/x-ndjson,text/csv
+// @Success 200 {array} types.ExportBookRecord
+// @Header 200 {string} Content-Disposition "File name of the export"
+// @Failure 403 {object} types.Problem
+// @Failure 406 {object} types.Problem
+// @Router /admin/export/books [get]
+// @Authorize Bearer
+func (controller *ExportController) exportBooksHandler(c *gin.Context) {
+	controller.export(c, "books", types.ExportBookRecord{}, func(ctx context.Context, writer exporter.Writer) error {
+		return controller.db.ExportBooks(ctx, nil, func(book *models.Book) error {
+			genres := []string{}
+			for _, genre := range book.Genres {
+				genres = append(genres, genre.Slug)
+			}
+
+			record := types.ExportBookRecord{
+				ID:              book.ID,
+				ISBN:            book.ISBN,
+				Title:           book.Title,
+				PublishedDate:   book.PublishedDate.Format(time.DateOnly),
+				Pages:           book.Pages,
+				Description:     book.Description,
+				DigitalOnly:     book.DigitalOnly,
+				Price:           book.Price,
+				AuthorID:        book.AuthorID,
+				AuthorFirstName: book.Author.FirstName,
+				AuthorLastName:  book.Author.LastName,
+				Genres:          genres,
+				Version:         book.Version,
+				CreatedAt:       book.CreatedAt,
+				UpdatedAt:       book.UpdatedAt,
+			}
+			if book.Cover.ID != 0 {
+				record.CoverID = &book.Cover.ID
+				if book.Cover.ImageURL.Valid {
+					record.CoverImageURL = &book.Cover.ImageURL.String
+				}
+			}
+			return writer.Write(record)
+		})
+	})
+}
+
+// @Summary Export authors
+// @Description Stream every author, ordered by ID. The format follows Accept: a JSON array (default), NDJSON
+// @Description with an author per line or CSV with a header row.
+// @Tags export admin
+// @Produce json,application/x-ndjson,text/csv
+// @Success 200 {array} types.ExportAuthorRecord
+// @Header 200 {string} Content-Disposition "File name of the export"
+// @Failure 403 {object} types.Problem
+// @Failure 406 {object} types.Problem
+// @Router /admin/export/authors [get]
+// @Authorize Bearer
+func (controller *ExportController) exportAuthorsHandler(c *gin.Context) {
+	controller.export(c, "authors", types.ExportAuthorRecord{}, func(ctx context.Context, writer exporter.Writer) error {
+		return controller.db.ExportAuthors(ctx, func(author *models.Author) error {
+			return writer.Write(types.ExportAuthorRecord{
+				ID:        author.ID,
+				FirstName: author.FirstName,
+				LastName:  author.LastName,
+				Version:   author.Version,
+				CreatedAt: author.CreatedAt,
+				UpdatedAt: author.UpdatedAt,
+			})
+		})
+	})
+}
+
+// @Summary Export artists
+// @Description Stream every artist, ordered by ID. The format follows Accept: a JSON array (default), NDJSON
+// @Description with an artist per line or CSV with a header row.
+// @Tags export admin
+// @Produce json,application/x-ndjson,text/csv
+// @Success 200 {array} types.ExportArtistRecord
+// @Header 200 {string} Content-Disposition "File name of the export"
+// @Failure 403 {object} types.Problem
+// @Failure 406 {object} types.Problem
+// @Router /admin/export/artists [get]
+// @Authorize Bearer
+func (controller *ExportController) exportArtistsHandler(c *gin.Context) {
+	controller.export(c, "artists", types.ExportArtistRecord{}, func(ctx context.Context, writer exporter.Writer) error {
+		return controller.db.ExportArtists(ctx, func(artist *models.Artist) error {
+			return writer.Write(types.ExportArtistRecord{
+				ID:        artist.ID,
+				FirstName: artist.FirstName,
+				LastName:  artist.LastName,
+				Version:   artist.Version,
+				CreatedAt: artist.CreatedAt,
+				UpdatedAt: artist.UpdatedAt,
+			})
+		})
+	})
+}
+
+// @Summary Export covers
+// @Description Stream every cover with its book and artists, ordered by ID. The format follows Accept: a JSON
+// @Description array (default), NDJSON with a cover per line or CSV with a header row, where the artists are
+// @Description separated by |.
+// @Tags export admin
+// @Produce json,application/x-ndjson,text/csv
+// @Success 200 {array} types.ExportCoverRecord
+// @Header 200 {string} Content-Disposition "File name of the export"
+// @Failure 403 {object} types.Problem
+// @Failure 406 {object} types.Problem
+// @Router /admin/export/covers [get]
+// @Authorize Bearer
+func (controller *ExportController) exportCoversHandler(c *gin.Context) {
+	controller.export(c, "covers", types.ExportCoverRecord{}, func(ctx context.Context, writer exporter.Writer) error {
+		return controller.db.ExportCovers(ctx, func(cover *models.Cover) error {
+			record := types.ExportCoverRecord{
+				ID:            cover.ID,
+				BookID:        cover.BookID,
+				ImageWidth:    cover.ImageWidth,
+				ImageHeight:   cover.ImageHeight,
+				DominantColor: cover.DominantColor,
+				ArtistIDs:     []uint{},
+				Artists:       []string{},
+				Version:       cover.Version,
+				CreatedAt:     cover.CreatedAt,
+				UpdatedAt:     cover.UpdatedAt,
+			}
+			if cover.Book != nil {
+				record.BookTitle = cover.Book.Title
+				record.BookISBN = cover.Book.ISBN
+			}
+			if cover.DesignIdeas.Valid {
+				record.DesignIdeas = &cover.DesignIdeas.String
+			}
+			if cover.ImageURL.Valid {
+				record.ImageURL = &cover.ImageURL.String
+			}
+			for _, artist := range cover.Artists {
+				record.ArtistIDs = append(record.ArtistIDs, artist.ID)
+				record.Artists = append(record.Artists, artist.FirstName+" "+artist.LastName)
+			}
+			return writer.Write(record)
+		})
+	})
+}
+
+// @Summary Export ONIX
+// @Description Stream every book as a product of an ONIX 3.0 message with reference tags, for retailers. A product
+// @Description has the ISBN and the ID of the book, its title, author (role A01), page count, genres in the
+// @Description proprietary subject scheme "Genres", description, front cover image, publication date and its
+// @Description price in ONIX_CURRENCY, published and supplied by ONIX_SENDER_NAME. The message can be imported again.
+// @Tags export admin
+// @Produce xml
+// @Success 200 {string} string "ONIX message"
+// @Header 200 {string} Content-Disposition "File name of the export"
+// @Failure 403 {object} types.Problem
+// @Router /admin/export/onix [get]
+// @Authorize Bearer
+func (controller *ExportController) exportONIXHandler(c *gin.Context) {
+	now := time.Now().UTC()
+	header := onix.Header{
+		Sender:       onix.Sender{SenderName: controller.onixSender},
+		SentDateTime: onix.SentDateTime(now),
+	}
+	currency := onix.Currency()
+
+	streamExport(c, "onix-"+now.Format("20060102")+".xml", "application/xml", func(ctx context.Context) error {
+		writer, err := onix.NewWriter(c.Writer, header)
+		if err != nil {
+			return err
+		}
+		err = controller.db.ExportBooks(ctx, nil, func(book *models.Book) error {
+			return writer.Write(onix.ProductOf(book, controller.onixSender, currency))
+		})
+		if err != nil {
+			return err
+		}
+		return writer.Close()
+	})
+}
+
+// @Summary Export MARC
+// @Description Stream books as MARC 21 bibliographic records for libraries, binary (application/marc, default) or
+// @Description MARCXML (application/marcxml+xml) as Accept asks. Every book is exported unless id or q pick some:
+// @Description id names books, q exports the books a search finds like GET /search. A record has the ID of the
+// @Description book as its control number (001), its ISBN and price (020), author (100), title (245), year of
+// @Description publication (264), pages (300), description (520), genres (655) and cover image (856). The records
+// @Description can be imported again.
+// @Tags export admin
+// @Produce application/marc,application/marcxml+xml
+// @Param id query []int false "IDs of the books to export, missing ones are left out" collectionFormat(multi)
+// @Param q query string false "Search query of the books to export"
+// @Param limit query int false "Maximum books found by q, 1 to 100, default 10"
+// @Success 200 {string} string "MARC records"
+// @Header 200 {string} Content-Disposition "File name of the export"
+// @Failure 400 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Failure 406 {object} types.Problem
+// @Failure 503 {object} types.Problem
+// @Router /admin/export/marc [get]
+// @Authorize Bearer
+func (controller *ExportController) exportMARCHandler(c *gin.Context) {
+	mediaType := c.NegotiateFormat(marc.MediaTypes[marc.FormatBinary], marc.MediaTypes[marc.FormatXML])
+	format, extension := marc.FormatBinary, ".mrc"
+	switch mediaType {
+	case marc.MediaTypes[marc.FormatBinary]:
+	case marc.MediaTypes[marc.FormatXML]:
+		format, extension = marc.FormatXML, ".xml"
+	default:
+		c.Error(utils.NewAPIError(http.StatusNotAcceptable, "not_acceptable", "Accept one of application/marc or application/marcxml+xml"))
+		return
+	}
+
+	var ids []uint
+	for _, value := range c.QueryArray("id") {
+		id, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			c.Error(utils.NewAPIError(http.StatusBadRequest, "invalid_query", "Invalid id format"))
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+	if query := c.Query("q"); query != "" {
+		limit, err := utils.ParseLimit(c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		found, err := searchBooks(c.Request.Context(), controller.db, query, limit)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		ids = append(found, ids...)
+	}
+
+	fileName := "books-" + time.Now().UTC().Format("20060102") + extension
+	if len(ids) == 1 {
+		fileName = "book-" + strconv.FormatUint(uint64(ids[0]), 10) + extension
+	}
+	streamExport(c, fileName, mediaType, func(ctx context.Context) error {
+		writer, err := marc.NewWriter(c.Writer, format)
+		if err != nil {
+			return err
+		}
+		err = controller.db.ExportBooks(ctx, ids, func(book *models.Book) error {
+			return writer.Write(marc.RecordOf(book))
+		})
+		if err != nil {
+			return err
+		}
+		return writer.Close()
+	})
+}
+
+// searchBooks finds the IDs of up to limit books matching a search query, it is an empty
+// list rather than nil when there are none
+func searchBooks(ctx context.Context, db database.Service, query string, limit int) ([]uint, error) {
+	results, err := db.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	ids := []uint{}
+	for _, result := range results {
+		if result.EntityType == database.SearchEntityBook {
+			ids = append(ids, result.EntityID)
+		}
+	}
+	return ids, nil
+}
+
+// export streams the records written by fn in the format the client accepts, as a file named
+// after the entity. record is an empty record, it names the columns of a CSV file.
+func (controller *ExportController) export(c *gin.Context, entity string, record any, fn func(ctx context.Context, writer exporter.Writer) error) {
+	mediaType := c.NegotiateFormat(
+		exporter.MediaTypes[exporter.FormatJSON],
+		exporter.MediaTypes[exporter.FormatNDJSON],
+		exporter.MediaTypes[exporter.FormatCSV],
+	)
+	format := exporter.FormatOf(mediaType)
+	if format == "" {
+		c.Error(utils.NewAPIError(http.StatusNotAcceptable, "not_acceptable", "Accept one of application/json, application/x-ndjson or text/csv"))
+		return
+	}
+
+	writer, err := exporter.NewWriter(c.Writer, format, record)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	streamExport(c, entity+"-"+time.Now().UTC().Format("20060102")+"."+format, mediaType, func(ctx context.Context) error {
+		if err := fn(ctx, writer); err != nil {
+			return err
+		}
+		return writer.Close()
+	})
+}
+
+// streamExport sends the file written by fn as an attachment. The writers of the exports hold
+// back the start of the file, until then a failure is answered like any other.
+func streamExport(c *gin.Context, fileName string, mediaType string, fn func(ctx context.Context) error) {
+	// An export takes as long as the client reads, the write timeout of the server is for the
+	// other responses
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("could not lift the write deadline of export %s: %v", fileName, err)
+	}
+
+	c.Header("Content-Type", mediaType+"; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Header("Cache-Control", "no-store")
+
+	err := fn(c.Request.Context())
+	if err == nil {
+		return
+	}
+
+	if !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Disposition")
+		c.Error(err)
+		return
+	}
+
+	// The status is sent, cut the response off so the client does not take the part it got
+	// for the whole export
+	log.Printf("export %s failed after it started: %v", fileName, err)
+	if conn, _, err := c.Writer.Hijack(); err == nil {
+		conn.Close()
+	}
+}
@@ -0,0 +1,290 @@
+package admin
+
+import (
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/server/middleware"
+	"go-playground/internal/server/types"
+	"go-playground/internal/server/utils"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GenresController handles genre-related routes
+type GenresController struct {
+	db database.Service
+}
+
+// GenreDTO is used for both create and update operations
+// Using pointers for fields allows us to distinguish between zero values and not provided values
+type GenreDTO struct {
+	ID       *uint   `json:"id" binding:"-"` // Added ID field for validation purposes
+	Name     *string `json:"name" binding:"required_without=ID"`
+	Slug     *string `json:"slug"`
+	ParentID *uint   `json:"parent_id"` // 0 moves the genre to the top level
+}
+
+// ApplyToModel applies the DTO data to a model instance
+func (dto *GenreDTO) ApplyToModel(genre *models.Genre) {
+	if dto.Name != nil {
+		genre.Name = *dto.Name
+	}
+	if dto.Slug != nil {
+		genre.Slug = utils.Slugify(*dto.Slug)
+	}
+	if dto.ParentID != nil {
+		if *dto.ParentID == 0 {
+			genre.ParentID = nil
+		} else {
+			parentID := *dto.ParentID
+			genre.ParentID = &parentID
+		}
+		genre.Parent = nil
+	}
+	if genre.Slug == "" {
+		genre.Slug = utils.Slugify(genre.Name)
+	}
+}
+
+// ToModel creates a new model from the DTO
+func (dto *GenreDTO) ToModel() models.Genre {
+	genre := models.Genre{}
+	dto.ApplyToModel(&genre)
+	return genre
+}
+
+// Register routes for the genres module
+func RegisterGenreRoutes(r *gin.RouterGroup) {
+	controller := &GenresController{
+		db: database.New(),
+	}
+
+	r.GET("", middleware.RequirePermission(models.PermissionGenresRead), controller.listGenresHandler)
+	r.POST("", middleware.RequirePermission(models.PermissionGenresWrite), controller.createGenreHandler)
+	r.DELETE("/:id", middleware.RequirePermission(models.PermissionGenresWrite), controller.deleteGenreHandler)
+	r.PATCH("/:id", middleware.RequirePermission(models.PermissionGenresWrite), controller.updateGenreHandler)
+}
+
+// @Summary List genres
+// @Description Get a list of all genres with pagination
+// @Tags genres admin
+// @Produce json
+// @Param limit query int false "Limit number of genres returned"
+// @Param offset query int false "Offset for pagination"
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.Genre]
+// @Failure 403 {object} types.Problem
+// @Router /admin/genres [get]
+// @Authorize Bearer
+func (controller *GenresController) listGenresHandler(c *gin.Context) {
+	listQuery, err := utils.ParseListQuery(c, database.GenreFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var genres []models.Genre
+	page, err := controller.db.List(c.Request.Context(), &genres, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewListResponse(c, genres, page, listQuery))
+}
+
+// @Summary Create genre
+// @Description Create a new genre, optionally below a parent genre
+// @Tags genres admin
+// @Accept json
+// @Produce json
+// @Param genre body GenreDTO true "Genre to create"
+// @Success 201 {object} models.Genre
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/genres [post]
+// @Authorize Bearer
+func (controller *GenresController) createGenreHandler(c *gin.Context) {
+	var inputDTO GenreDTO
+	if err := c.ShouldBindJSON(&inputDTO); err != nil {
+		c.Error(err)
+		return
+	}
+
+	genre := inputDTO.ToModel()
+	err := controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if genre.ParentID != nil {
+			if err := readReference(c.Request.Context(), tx, "parent_id", &models.Genre{}, *genre.ParentID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(c.Request.Context(), &genre); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionCreate, genre.ID, nil, &genre)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.SetETag(c, genre.Version)
+	c.JSON(http.StatusCreated, genre)
+}
+
+// @Summary Delete genre
+// @Description Delete a genre by ID. Child genres are moved up to the deleted genre's parent.
+// @Tags genres admin
+// @Produce json
+// @Param id path int true "Genre ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
+// @Success 204
+// @Failure 404 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/genres/{id} [delete]
+// @Authorize Bearer
+func (controller *GenresController) deleteGenreHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		genre, err := tx.GetGenre(c.Request.Context(), id)
+		if err != nil {
+			return err
+		}
+		if err := utils.CheckIfMatch(c, genre.Version); err != nil {
+			return err
+		}
+
+		for i := range genre.Children {
+			child := genre.Children[i]
+			before := child
+			child.ParentID = genre.ParentID
+			if err := tx.Update(c.Request.Context(), &child); err != nil {
+				return err
+			}
+			if err := recordAudit(c, tx, models.AuditActionUpdate, child.ID, &before, &child); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.ReplaceAssociation(c.Request.Context(), genre, "Books", []*models.Book{}); err != nil {
+			return err
+		}
+		if err := tx.Delete(c.Request.Context(), &models.Genre{}, id); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionDelete, genre.ID, genre, nil)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Update genre
+// @Description Update a genre by ID
+// @Description Send the fields to change as JSON, a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Description selected by its Content-Type. Patches apply to the fields as they are, e.g. from the last GET.
+// @Description A patch can move the genre to the top level by setting parent_id to null.
+// @Tags genres admin
+// @Accept json,application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path int true "Genre ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
+// @Param genre body GenreDTO true "Updated genre data"
+// @Success 200 {object} models.Genre
+// @Header 200 {string} ETag "ETag of the updated version"
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/genres/{id} [patch]
+// @Authorize Bearer
+func (controller *GenresController) updateGenreHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	update, err := readUpdate(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var genre models.Genre
+	err = controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Read(c.Request.Context(), &genre, id); err != nil {
+			return err
+		}
+		if err := utils.CheckIfMatch(c, genre.Version); err != nil {
+			return err
+		}
+
+		// Set the ID to enable partial updates through the required_without=ID validation
+		updateDTO := GenreDTO{ID: &id}
+		cleared, err := update.decode(genreFields(genre), &updateDTO, "parent_id")
+		if err != nil {
+			return err
+		}
+
+		before := genre
+		updateDTO.ApplyToModel(&genre)
+		if slices.Contains(cleared, "parent_id") {
+			genre.ParentID = nil
+			genre.Parent = nil
+		}
+
+		// Moving a genre below itself or one of its own descendants would create a cycle
+		if genre.ParentID != nil {
+			descendants, err := tx.GenreDescendantIDs(c.Request.Context(), id)
+			if err != nil {
+				return err
+			}
+			if slices.Contains(descendants, *genre.ParentID) {
+				return utils.ValidationFailed(types.FieldError{Field: "parent_id", Message: "cannot be the genre itself or one of its children"})
+			}
+			if err := readReference(c.Request.Context(), tx, "parent_id", &models.Genre{}, *genre.ParentID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Update(c.Request.Context(), &genre); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditActionUpdate, genre.ID, &before, &genre)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.SetETag(c, genre.Version)
+	c.JSON(http.StatusOK, genre)
+}
+
+// genreFields captures the fields of a genre that updates change
+func genreFields(genre models.Genre) GenreDTO {
+	return GenreDTO{
+		ID:       &genre.ID,
+		Name:     &genre.Name,
+		Slug:     &genre.Slug,
+		ParentID: genre.ParentID,
+	}
+}
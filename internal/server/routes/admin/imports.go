@@ -0,0 +1,316 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/importer"
+	"go-playground/internal/server/middleware"
+	"go-playground/internal/server/types"
+	"go-playground/internal/server/utils"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportsController handles the bulk import routes
+type ImportsController struct {
+	db database.Service
+
+	maxFileSize int64
+}
+
+// defaultMaxImportSize limits import files unless IMPORT_MAX_FILE_SIZE is set
+const defaultMaxImportSize = 32 << 20
+
+// syncImportRows is the most rows imported while the request waits, larger files are
+// imported in the background and their job is polled for the outcome
+const syncImportRows = 100
+
+// importPermissions lists the permissions needed to import each entity, books can create authors
+// and covers
+var importPermissions = map[string][]string{
+	importer.EntityBooks:   {models.PermissionBooksWrite, models.PermissionAuthorsWrite, models.PermissionCoversWrite},
+	importer.EntityAuthors: {models.PermissionAuthorsWrite},
+	importer.EntityArtists: {models.PermissionArtistsWrite},
+}
+
+// Register routes for the imports module
+func RegisterImportRoutes(r *gin.RouterGroup) {
+	controller := &ImportsController{
+		db: database.New(),
+
+		maxFileSize: defaultMaxImportSize,
+	}
+	if size, err := strconv.ParseInt(os.Getenv("IMPORT_MAX_FILE_SIZE"), 10, 64); err == nil && size > 0 {
+		controller.maxFileSize = size
+	}
+
+	// Jobs run in the process that accepted them, the ones left by the last run never finish
+	if err := controller.db.FailUnfinishedImportJobs(context.Background(), "The server stopped before the import finished"); err != nil {
+		log.Printf("could not fail unfinished import jobs: %v", err)
+	}
+
+	r.GET("", middleware.RequirePermission(models.PermissionImportsRead), controller.listImportJobsHandler)
+	r.GET("/:id", middleware.RequirePermission(models.PermissionImportsRead), controller.getImportJobHandler)
+	r.POST("", middleware.RequirePermission(models.PermissionImportsWrite), controller.createImportHandler)
+}
+
+// @Summary List import jobs
+// @Description Get the bulk imports with pagination, without the rows they rejected
+// @Tags imports admin
+// @Produce json
+// @Param limit query int false "Limit number of jobs returned"
+// @Param offset query int false "Offset for pagination"
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.ImportJob]
+// @Failure 400 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/imports [get]
+// @Authorize Bearer
+func (controller *ImportsController) listImportJobsHandler(c *gin.Context) {
+	listQuery, err := utils.ParseListQuery(c, database.ImportJobFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var jobs []models.ImportJob
+	page, err := controller.db.List(c.Request.Context(), &jobs, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for i := range jobs {
+		jobs[i].Errors = nil
+	}
+
+	c.JSON(http.StatusOK, utils.NewListResponse(c, jobs, page, listQuery))
+}
+
+// @Summary Get import job
+// @Description Get the status of a bulk import with the counts of its rows so far and the rows it rejected
+// @Tags imports admin
+// @Produce json
+// @Param id path int true "Import job ID"
+// @Success 200 {object} models.ImportJob
+// @Failure 400 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/imports/{id} [get]
+// @Authorize Bearer
+func (controller *ImportsController) getImportJobHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var job models.ImportJob
+	if err := controller.db.Read(c.Request.Context(), &job, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// @Summary Import file
+// @Description Import books, authors or artists from a CSV file with a header row or an NDJSON file with
+// @Description an object per line, uploaded as multipart form data. Columns named like a field are imported
+// @Description into it, mapping renames other columns, e.g. {"Book Title": "title"}, the rest is ignored.
+// @Description Books are matched by ISBN and updated with the given fields, or created with all of them:
+// @Description isbn, title, published_date (YYYY-MM-DD or a year), pages, description, price, digital_only, genres
+// @Description (slugs or names separated by | or ;) and author ("First Last" or "Last, First", or
+// @Description author_first_name and author_last_name). The author is matched by name and created when there
+// @Description is none. The optional cover_image_url links an image to the cover of the book, which is created
+// @Description when there is none, unless an image was uploaded for it.
+// @Description Authors and artists take first_name and last_name or name, existing ones are kept.
+// @Description Books can also be imported from an ONIX 3.0 message with reference tags (format onix). Its products
+// @Description map onto books by ISBN: the distinctive title, the first contributor with role A01 as the author,
+// @Description the publication date, the page count, the description, the front cover image link, genres in the
+// @Description proprietary subject scheme "Genres" and the retail price in ONIX_CURRENCY. Products to delete or
+// @Description without a price in that currency are rejected, their errors name the ONIX element.
+// @Description Books can also be imported from MARC 21 records, binary (format marc, .mrc) or MARCXML (format
+// @Description marcxml): the ISBN and price of 020, the title of 245, the author of 100 or else 700, the year of
+// @Description 264, 260 or 008, the pages of 300, the summary of 520, whether it is online from 338 and the
+// @Description 856 labelled "Cover image". Binary records are counted in place of lines. Deleted records,
+// @Description records of other material than books and MARC-8 records beyond ASCII are rejected.
+// @Description Every row is saved on its own, rejected rows are listed in the errors of the job with their line.
+// @Description A dry run checks every row the same way without saving anything. Files of up to 100 rows are
+// @Description imported before the response with 201, larger ones in the background with 202, poll the job
+// @Description at the Location until it succeeded or failed.
+// @Tags imports admin
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV, NDJSON, ONIX or MARC file"
+// @Param entity formData string true "What the file holds, one of books, authors or artists"
+// @Param format formData string false "csv, ndjson, onix, marc or marcxml, detected from the file name by default (.xml is onix, .mrc marc)"
+// @Param mapping formData string false "JSON object mapping columns of the file to fields"
+// @Param dry_run formData bool false "Only validate the rows"
+// @Success 201 {object} models.ImportJob
+// @Success 202 {object} models.ImportJob
+// @Header 201,202 {string} Location "URL of the import job"
+// @Failure 400 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Failure 413 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Router /admin/imports [post]
+// @Authorize Bearer
+func (controller *ImportsController) createImportHandler(c *gin.Context) {
+	// Leave some room for the multipart headers around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, controller.maxFileSize+64<<10)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.Error(utils.NewAPIError(http.StatusRequestEntityTooLarge, "payload_too_large", fmt.Sprintf("File must not be larger than %d bytes", controller.maxFileSize)))
+			return
+		}
+		c.Error(utils.ValidationFailed(types.FieldError{Field: "file", Message: "is required"}))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > controller.maxFileSize {
+		c.Error(utils.NewAPIError(http.StatusRequestEntityTooLarge, "payload_too_large", fmt.Sprintf("File must not be larger than %d bytes", controller.maxFileSize)))
+		return
+	}
+
+	job := models.ImportJob{
+		ActorID:  requestActor(c).userID,
+		Entity:   c.PostForm("entity"),
+		Format:   c.DefaultPostForm("format", importer.FormatOf(header.Filename)),
+		FileName: header.Filename,
+		Status:   models.ImportStatusPending,
+	}
+
+	var fieldErrors []types.FieldError
+	permissions, ok := importPermissions[job.Entity]
+	if !ok {
+		fieldErrors = append(fieldErrors, types.FieldError{Field: "entity", Message: "must be one of books, authors or artists"})
+	}
+	if !slices.Contains([]string{importer.FormatCSV, importer.FormatNDJSON, importer.FormatONIX, importer.FormatMARC, importer.FormatMARCXML}, job.Format) {
+		fieldErrors = append(fieldErrors, types.FieldError{Field: "format", Message: "must be csv, ndjson, onix, marc or marcxml"})
+	}
+	var mapping map[string]string
+	if value := c.PostForm("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			fieldErrors = append(fieldErrors, types.FieldError{Field: "mapping", Message: "must be a JSON object of column names to fields"})
+		}
+	}
+	if value := c.PostForm("dry_run"); value != "" {
+		if job.DryRun, err = strconv.ParseBool(value); err != nil {
+			fieldErrors = append(fieldErrors, types.FieldError{Field: "dry_run", Message: "must be true or false"})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		c.Error(utils.ValidationFailed(fieldErrors...))
+		return
+	}
+
+	claims := c.MustGet("user").(*utils.Claims)
+	for _, permission := range permissions {
+		if !claims.HasPermission(permission) {
+			c.Error(utils.NewAPIError(http.StatusForbidden, "missing_permission", "Missing permission "+permission))
+			return
+		}
+	}
+
+	rows, err := importer.Read(file, job.Format, job.Entity, mapping)
+	if err != nil {
+		var fileErr *importer.FileError
+		if errors.As(err, &fileErr) {
+			c.Error(utils.NewAPIError(http.StatusBadRequest, "invalid_file", fileErr.Error()))
+			return
+		}
+		c.Error(err)
+		return
+	}
+	job.Total = len(rows)
+
+	if err := controller.db.Create(c.Request.Context(), &job); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Header("Location", c.Request.URL.JoinPath(strconv.FormatUint(uint64(job.ID), 10)).Path)
+
+	actor := requestActor(c)
+	if len(rows) > syncImportRows {
+		// The request context ends with the response, the job outlives it
+		response := job
+		go controller.run(context.WithoutCancel(c.Request.Context()), actor, &job, rows)
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	controller.run(c.Request.Context(), actor, &job, rows)
+	c.JSON(http.StatusCreated, job)
+}
+
+// run imports the rows of a job, saving its progress as it goes
+func (controller *ImportsController) run(ctx context.Context, actor actor, job *models.ImportJob, rows []importer.Row) {
+	// The job is saved even when the import was canceled
+	save := func(job *models.ImportJob) {
+		if err := controller.db.Update(context.WithoutCancel(ctx), job); err != nil {
+			log.Printf("could not save import job %d: %v", job.ID, err)
+		}
+	}
+
+	job.Status = models.ImportStatusRunning
+	save(job)
+
+	im := importer.New(controller.db, importRecorder(actor))
+	im.Progress = save
+	err := im.Run(ctx, job, rows)
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Status = models.ImportStatusSucceeded
+	if err != nil {
+		job.Status = models.ImportStatusFailed
+		job.Message = err.Error()
+	}
+	save(job)
+}
+
+// importRecorder records the changes of an import made by actor like the other admin routes do,
+// in the audit log and as revisions
+func importRecorder(actor actor) importer.Recorder {
+	return func(ctx context.Context, tx database.Service, action string, id uint, before any, after any) error {
+		if err := addAuditEntry(ctx, tx, actor, action, id, before, after); err != nil {
+			return err
+		}
+
+		switch entity := after.(type) {
+		case *models.Book:
+			var beforeFields any
+			if before != nil {
+				beforeFields = bookFields(*before.(*models.Book))
+			}
+			return addRevision(ctx, tx, actor.userID, models.RevisionEntityBook, id, beforeFields, bookFields(*entity))
+		case *models.Author:
+			return addRevision(ctx, tx, actor.userID, models.RevisionEntityAuthor, id, nil, authorFields(*entity))
+		case *models.Artist:
+			return addRevision(ctx, tx, actor.userID, models.RevisionEntityArtist, id, nil, artistFields(*entity))
+		case *models.Cover:
+			var beforeFields any
+			if before != nil {
+				beforeFields = coverRevision(*before.(*models.Cover))
+			}
+			return addRevision(ctx, tx, actor.userID, models.RevisionEntityCover, id, beforeFields, coverRevision(*entity))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,127 @@
+package admin
+
+import (
+	"encoding/json"
+	"go-playground/internal/patch"
+	"go-playground/internal/server/types"
+	"go-playground/internal/server/utils"
+	"io"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// updateDocument is the body of an update, either the fields to set as plain JSON or a patch
+type updateDocument struct {
+	mediaType  string
+	body       []byte
+	mergePatch any
+	operations []patch.Operation
+}
+
+// readUpdate reads the body of an update request. The patch formats are selected by their
+// Content-Type, any other body is read as plain JSON like before patches were supported.
+// Malformed patches fail here, before the entity is read.
+func readUpdate(c *gin.Context) (*updateDocument, error) {
+	update := &updateDocument{mediaType: c.ContentType()}
+	if update.mediaType != patch.MergePatchType && update.mediaType != patch.JSONPatchType {
+		update.mediaType = "application/json"
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	update.body = body
+
+	switch update.mediaType {
+	case patch.MergePatchType:
+		err = json.Unmarshal(body, &update.mergePatch)
+	case patch.JSONPatchType:
+		update.operations, err = patch.Parse(body)
+	}
+	return update, err
+}
+
+// jsonUpdate creates an update that sets the given fields
+func jsonUpdate(fields any) (*updateDocument, error) {
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return &updateDocument{mediaType: "application/json", body: body}, nil
+}
+
+// decode fills dto with the fields the update changes and validates it. current is the DTO
+// of the entity as it is, patches are applied to its JSON form. A patch may only set the
+// nullable fields to null, those are returned so the caller can clear them.
+func (update *updateDocument) decode(current any, dto any, nullable ...string) ([]string, error) {
+	if update.mediaType == "application/json" {
+		return nil, binding.JSON.BindBody(update.body, dto)
+	}
+
+	original, err := jsonFields(current)
+	if err != nil {
+		return nil, err
+	}
+	document, err := jsonFields(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched any
+	if update.mediaType == patch.MergePatchType {
+		patched = patch.Merge(document, update.mergePatch)
+	} else if patched, err = patch.Apply(document, update.operations); err != nil {
+		return nil, err
+	}
+	fields, ok := patched.(map[string]any)
+	if !ok {
+		return nil, utils.NewAPIError(http.StatusUnprocessableEntity, "validation_failed", "The patched document must be an object")
+	}
+
+	var (
+		cleared     []string
+		fieldErrors []types.FieldError
+		changed     = map[string]any{}
+	)
+	for _, name := range slices.Sorted(maps.Keys(mergeKeys(original, fields))) {
+		value, ok := fields[name]
+		switch {
+		case !hasKey(original, name):
+			fieldErrors = append(fieldErrors, types.FieldError{Field: name, Message: "is not a field"})
+		case reflect.DeepEqual(original[name], value):
+		case name == "id":
+			fieldErrors = append(fieldErrors, types.FieldError{Field: name, Message: "cannot be changed"})
+		case !ok || value == nil:
+			if !slices.Contains(nullable, name) {
+				fieldErrors = append(fieldErrors, types.FieldError{Field: name, Message: "cannot be null"})
+				continue
+			}
+			cleared = append(cleared, name)
+		default:
+			changed[name] = value
+		}
+	}
+	if len(fieldErrors) > 0 {
+		return nil, utils.ValidationFailed(fieldErrors...)
+	}
+
+	data, err := json.Marshal(changed)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, dto); err != nil {
+		return nil, err
+	}
+	return cleared, binding.Validator.ValidateStruct(dto)
+}
+
+func hasKey(fields map[string]any, name string) bool {
+	_, ok := fields[name]
+	return ok
+}
@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go-playground/internal/database/models"
+	"go-playground/internal/patch"
+	"go-playground/internal/server/utils"
+
+	"gorm.io/gorm"
+)
+
+// decodeUserUpdate decodes an update of the given media type to a user the way updateUserHandler does
+func decodeUserUpdate(t *testing.T, mediaType string, body string, user models.User) (UserDTO, error) {
+	t.Helper()
+
+	update := &updateDocument{mediaType: mediaType, body: []byte(body)}
+	var err error
+	switch mediaType {
+	case patch.MergePatchType:
+		err = json.Unmarshal(update.body, &update.mergePatch)
+	case patch.JSONPatchType:
+		update.operations, err = patch.Parse(update.body)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dto := UserDTO{ID: &user.ID}
+	_, err = update.decode(userFields(user), &dto)
+	return dto, err
+}
+
+func TestUserUpdateSetsPassword(t *testing.T) {
+	user := models.User{Model: gorm.Model{ID: 4}, Username: "editor", Password: "$2a$10$hash"}
+
+	tests := []struct {
+		name      string
+		mediaType string
+		body      string
+	}{
+		{"json", "application/json", `{"password": "correct horse"}`},
+		{"merge patch", patch.MergePatchType, `{"password": "correct horse"}`},
+		{"json patch replace", patch.JSONPatchType, `[{"op": "replace", "path": "/password", "value": "correct horse"}]`},
+		{"json patch add", patch.JSONPatchType, `[{"op": "add", "path": "/password", "value": "correct horse"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dto, err := decodeUserUpdate(t, tt.mediaType, tt.body, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dto.Password == nil || *dto.Password != "correct horse" {
+				t.Fatalf("decoded password %v, want correct horse", dto.Password)
+			}
+			if dto.Username != nil {
+				t.Errorf("decoded username %q, the update does not change it", *dto.Username)
+			}
+		})
+	}
+}
+
+func TestUserUpdateHidesPassword(t *testing.T) {
+	user := models.User{Model: gorm.Model{ID: 4}, Username: "editor", Password: "$2a$10$hash"}
+
+	// A patch leaving the password alone does not change it
+	dto, err := decodeUserUpdate(t, patch.MergePatchType, `{"username": "author", "password": null}`, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dto.Password != nil {
+		t.Errorf("a merge patch removing the password decoded %q", *dto.Password)
+	}
+
+	// The hash cannot be read back, not even by testing for it
+	_, err = decodeUserUpdate(t, patch.JSONPatchType, `[{"op": "test", "path": "/password", "value": "$2a$10$hash"}]`, user)
+	if !errors.Is(err, patch.ErrFailed) {
+		t.Errorf("testing for the hash returned %v, want patch.ErrFailed", err)
+	}
+	_, err = decodeUserUpdate(t, patch.JSONPatchType, `[{"op": "copy", "from": "/password", "path": "/username"}]`, user)
+	var apiErr *utils.APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnprocessableEntity {
+		t.Errorf("copying the password to the username returned %v, want a validation error", err)
+	}
+
+	// New passwords are validated like those of new users
+	_, err = decodeUserUpdate(t, patch.MergePatchType, `{"password": "short"}`, user)
+	if err == nil {
+		t.Error("a password of 5 characters was accepted")
+	}
+}
@@ -0,0 +1,29 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"go-playground/internal/database"
+	"go-playground/internal/server/types"
+	"go-playground/internal/server/utils"
+)
+
+// readReference loads the record a request field refers to by ID.
+// A missing record is a validation error of that field, not a 404 of the request.
+func readReference(ctx context.Context, db database.Service, field string, entity any, id uint) error {
+	err := db.Read(ctx, entity, id)
+	if errors.Is(err, database.ErrNotFound) {
+		return utils.ValidationFailed(types.FieldError{Field: field, Message: "does not exist"})
+	}
+	return err
+}
+
+// readReferences loads every record a request field refers to by ID, failing
+// with a validation error of that field that names the missing IDs.
+func readReferences(ctx context.Context, db database.Service, field string, entities any, ids []uint) error {
+	err := db.ReadAll(ctx, entities, ids)
+	if errors.Is(err, database.ErrNotFound) {
+		return utils.ValidationFailed(types.FieldError{Field: field, Message: err.Error()})
+	}
+	return err
+}
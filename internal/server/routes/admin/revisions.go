@@ -0,0 +1,276 @@
+package admin
+
+import (
+	"context"
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/server/middleware"
+	"go-playground/internal/server/types"
+	"go-playground/internal/server/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RevisionsController handles the revision routes of one entity type
+type RevisionsController struct {
+	db         database.Service
+	entityType string
+
+	// rollback applies the fields of a revision to the entity with the given ID, records
+	// the change and returns the updated entity
+	rollback func(c *gin.Context, tx database.Service, id uint, revision *models.Revision) (any, error)
+}
+
+// registerRevisionRoutes adds the revision routes below the routes of an entity
+func registerRevisionRoutes(r *gin.RouterGroup, controller *RevisionsController, readPermission string, writePermission string) {
+	r.GET("/:id/revisions", middleware.RequirePermission(readPermission), controller.listRevisionsHandler)
+	r.GET("/:id/revisions/diff", middleware.RequirePermission(readPermission), controller.diffRevisionsHandler)
+	r.GET("/:id/revisions/:version", middleware.RequirePermission(readPermission), controller.getRevisionHandler)
+	r.POST("/:id/revisions/:version/rollback", middleware.RequirePermission(writePermission), controller.rollbackRevisionHandler)
+}
+
+// @Summary List revisions
+// @Description Get the revisions of a book, author, artist or cover with pagination, oldest first.
+// @Description A revision is stored for every change, it holds the fields as they were after the change.
+// @Tags revisions admin
+// @Produce json
+// @Param id path int true "Entity ID"
+// @Param limit query int false "Limit number of revisions returned"
+// @Param offset query int false "Offset for pagination"
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[models.Revision]
+// @Failure 400 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/books/{id}/revisions [get]
+// @Router /admin/authors/{id}/revisions [get]
+// @Router /admin/artists/{id}/revisions [get]
+// @Router /admin/covers/{id}/revisions [get]
+// @Authorize Bearer
+func (controller *RevisionsController) listRevisionsHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	listQuery, err := utils.ParseListQuery(c, database.RevisionFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	revisions, page, err := controller.db.ListRevisions(c.Request.Context(), controller.entityType, id, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewListResponse(c, revisions, page, listQuery))
+}
+
+// @Summary Get revision
+// @Description Get a revision of a book, author, artist or cover by its version
+// @Tags revisions admin
+// @Produce json
+// @Param id path int true "Entity ID"
+// @Param version path int true "Revision version"
+// @Success 200 {object} models.Revision
+// @Failure 400 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/books/{id}/revisions/{version} [get]
+// @Router /admin/authors/{id}/revisions/{version} [get]
+// @Router /admin/artists/{id}/revisions/{version} [get]
+// @Router /admin/covers/{id}/revisions/{version} [get]
+// @Authorize Bearer
+func (controller *RevisionsController) getRevisionHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, err := parseVersion(c.Param("version"), "version")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	revision, err := controller.db.GetRevision(c.Request.Context(), controller.entityType, id, version)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// @Summary Diff revisions
+// @Description Compare two revisions of a book, author, artist or cover field by field.
+// @Description Only the fields that differ are listed, with their value in from as before and in to as after.
+// @Tags revisions admin
+// @Produce json
+// @Param id path int true "Entity ID"
+// @Param from query int true "Version to compare from"
+// @Param to query int true "Version to compare to"
+// @Success 200 {object} types.RevisionDiffResponse
+// @Failure 400 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/books/{id}/revisions/diff [get]
+// @Router /admin/authors/{id}/revisions/diff [get]
+// @Router /admin/artists/{id}/revisions/diff [get]
+// @Router /admin/covers/{id}/revisions/diff [get]
+// @Authorize Bearer
+func (controller *RevisionsController) diffRevisionsHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	from, err := parseVersion(c.Query("from"), "from")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	to, err := parseVersion(c.Query("to"), "to")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	fromRevision, err := controller.db.GetRevision(c.Request.Context(), controller.entityType, id, from)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	toRevision, err := controller.db.GetRevision(c.Request.Context(), controller.entityType, id, to)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.RevisionDiffResponse{
+		EntityType: controller.entityType,
+		EntityID:   id,
+		From:       from,
+		To:         to,
+		Changes:    diffFields(fromRevision.Data, toRevision.Data),
+	})
+}
+
+// @Summary Roll back to revision
+// @Description Set the fields of a book, author, artist or cover back to a revision. The rollback is a
+// @Description change of its own, it is stored as the next revision and can be rolled back as well.
+// @Description Fails when an entity the revision refers to, e.g. the author of a book, no longer exists.
+// @Tags revisions admin
+// @Produce json
+// @Param id path int true "Entity ID"
+// @Param version path int true "Revision version"
+// @Param If-Match header string false "ETag of the version the rollback is based on, e.g. \"3\""
+// @Success 200 {object} models.Book "The updated book, author, artist or cover"
+// @Header 200 {string} ETag "ETag of the updated version"
+// @Failure 400 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/books/{id}/revisions/{version}/rollback [post]
+// @Router /admin/authors/{id}/revisions/{version}/rollback [post]
+// @Router /admin/artists/{id}/revisions/{version}/rollback [post]
+// @Router /admin/covers/{id}/revisions/{version}/rollback [post]
+// @Authorize Bearer
+func (controller *RevisionsController) rollbackRevisionHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, err := parseVersion(c.Param("version"), "version")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var entity any
+	err = controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		revision, err := tx.GetRevision(c.Request.Context(), controller.entityType, id, version)
+		if err != nil {
+			return err
+		}
+		entity, err = controller.rollback(c, tx, id, revision)
+		return err
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if versioned, ok := entity.(models.VersionedModel); ok {
+		utils.SetETag(c, versioned.CurrentVersion())
+	}
+	c.JSON(http.StatusOK, entity)
+}
+
+// recordRevision stores the state of an entity after a change as its next revision, using the
+// transaction of the change. before is nil for created entities, otherwise it is kept as the
+// first revision of an entity changed for the first time since revisions are stored.
+// The states are DTOs, fields without a value are left out and left alone by a rollback.
+func recordRevision(c *gin.Context, tx database.Service, entityType string, entityID uint, before any, after any) error {
+	return addRevision(c.Request.Context(), tx, requestActor(c).userID, entityType, entityID, before, after)
+}
+
+// addRevision stores the state of an entity after a change made by the user, like recordRevision
+func addRevision(ctx context.Context, tx database.Service, actorID uint, entityType string, entityID uint, before any, after any) error {
+	data, err := revisionData(after)
+	if err != nil {
+		return err
+	}
+
+	var baseline models.Snapshot
+	if before != nil {
+		if baseline, err = revisionData(before); err != nil {
+			return err
+		}
+	}
+
+	revision := models.Revision{
+		EntityType: entityType,
+		EntityID:   entityID,
+		ActorID:    actorID,
+		Data:       data,
+	}
+	return tx.AddRevision(ctx, &revision, baseline)
+}
+
+// revisionData turns a DTO into the snapshot of a revision, the ID is kept by the revision itself
+func revisionData(dto any) (models.Snapshot, error) {
+	fields, err := jsonFields(dto)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(fields, "id")
+	for name, value := range fields {
+		if value == nil {
+			delete(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// parseVersion parses a revision version given in the named parameter
+func parseVersion(value string, name string) (uint, error) {
+	version, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || version == 0 {
+		return 0, utils.NewAPIError(http.StatusBadRequest, "invalid_version", "Invalid "+name+" format: must be a positive number")
+	}
+	return uint(version), nil
+}
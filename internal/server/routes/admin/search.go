@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/server/middleware"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchController handles search index maintenance routes
+type SearchController struct {
+	db database.Service
+}
+
+// Register routes for the search module
+func RegisterSearchRoutes(r *gin.RouterGroup) {
+	controller := &SearchController{
+		db: database.New(),
+	}
+
+	r.POST("/rebuild", middleware.RequirePermission(models.PermissionSearchAdmin), controller.rebuildSearchIndexHandler)
+}
+
+// @Summary Rebuild search index
+// @Description Reindex all books, authors and artists
+// @Tags search admin
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 503 {object} types.Problem
+// @Failure 500 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/search/rebuild [post]
+// @Authorize Bearer
+func (controller *SearchController) rebuildSearchIndexHandler(c *gin.Context) {
+	if err := controller.db.RebuildSearchIndex(c.Request.Context()); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Search index rebuilt"})
+}
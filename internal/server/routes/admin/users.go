@@ -0,0 +1,388 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/server/middleware"
+	"go-playground/internal/server/types"
+	"go-playground/internal/server/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsersController handles user-related routes
+type UsersController struct {
+	db database.Service
+}
+
+// UserDTO is used for both create and update operations
+// Using pointers for fields allows us to distinguish between zero values and not provided values
+type UserDTO struct {
+	ID                 *uint   `json:"id" binding:"-"` // Added ID field for validation purposes
+	Username           *string `json:"username" binding:"required_without=ID"`
+	Password           *string `json:"password" binding:"required_without=ID,omitempty,min=8"`
+	Role               *string `json:"role" binding:"required_without=ID"`
+	Disabled           *bool   `json:"disabled"`
+	MustChangePassword *bool   `json:"must_change_password"`
+}
+
+// ApplyToModel applies the DTO data to a model instance.
+// The password is hashed here, the role has to be resolved by the caller.
+func (dto *UserDTO) ApplyToModel(user *models.User) error {
+	if dto.Username != nil {
+		user.Username = *dto.Username
+	}
+	if dto.Password != nil {
+		hash, err := utils.HashPassword(*dto.Password)
+		if err != nil {
+			return err
+		}
+		user.Password = hash
+	}
+	if dto.Disabled != nil {
+		user.Disabled = *dto.Disabled
+	}
+	if dto.MustChangePassword != nil {
+		user.MustChangePassword = *dto.MustChangePassword
+	}
+	return nil
+}
+
+// ToModel creates a new model from the DTO
+func (dto *UserDTO) ToModel() (models.User, error) {
+	user := models.User{}
+	err := dto.ApplyToModel(&user)
+	return user, err
+}
+
+// Register routes for the users module
+func RegisterUserRoutes(r *gin.RouterGroup) {
+	controller := &UsersController{
+		db: database.New(),
+	}
+
+	r.GET("", middleware.RequirePermission(models.PermissionUsersRead), controller.listUsersHandler)
+	r.GET("/:id", middleware.RequirePermission(models.PermissionUsersRead), controller.getUserHandler)
+	r.POST("", middleware.RequirePermission(models.PermissionUsersWrite), controller.createUserHandler)
+	r.DELETE("/:id", middleware.RequirePermission(models.PermissionUsersWrite), controller.deleteUserHandler)
+	r.PATCH("/:id", middleware.RequirePermission(models.PermissionUsersWrite), controller.updateUserHandler)
+}
+
+// @Summary List users
+// @Description Get a list of all users with pagination
+// @Tags users admin
+// @Produce json
+// @Param limit query int false "Limit number of users returned"
+// @Param offset query int false "Offset for pagination"
+// @Param after query string false "Cursor from next_cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Success 200 {object} types.ListResponse[types.UserResponse]
+// @Failure 403 {object} types.Problem
+// @Router /admin/users [get]
+// @Authorize Bearer
+func (controller *UsersController) listUsersHandler(c *gin.Context) {
+	listQuery, err := utils.ParseListQuery(c, database.UserFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	users, page, err := controller.db.ListUsers(c.Request.Context(), listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var response []types.UserResponse
+	for _, user := range users {
+		response = append(response, toUserResponse(user))
+	}
+
+	c.JSON(http.StatusOK, utils.NewListResponse(c, response, page, listQuery))
+}
+
+// @Summary Get user
+// @Description Get a user by ID
+// @Tags users admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} types.UserResponse
+// @Failure 404 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/users/{id} [get]
+// @Authorize Bearer
+func (controller *UsersController) getUserHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := controller.db.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.SetETag(c, user.Version)
+	c.JSON(http.StatusOK, toUserResponse(*user))
+}
+
+// @Summary Create user
+// @Description Create a new user with a role
+// @Tags users admin
+// @Accept json
+// @Produce json
+// @Param user body UserDTO true "User to create"
+// @Success 201 {object} types.UserResponse
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/users [post]
+// @Authorize Bearer
+func (controller *UsersController) createUserHandler(c *gin.Context) {
+	var inputDTO UserDTO
+	if err := c.ShouldBindJSON(&inputDTO); err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := inputDTO.ToModel()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := checkUsernameAvailable(c.Request.Context(), tx, *inputDTO.Username); err != nil {
+			return err
+		}
+
+		role, err := readRole(c.Request.Context(), tx, *inputDTO.Role)
+		if err != nil {
+			return err
+		}
+		user.RoleID = &role.ID
+
+		if err := tx.Create(c.Request.Context(), &user); err != nil {
+			return err
+		}
+		user.Role = role
+		return recordAudit(c, tx, models.AuditActionCreate, user.ID, nil, &user)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.SetETag(c, user.Version)
+	c.JSON(http.StatusCreated, toUserResponse(user))
+}
+
+// @Summary Delete user
+// @Description Delete a user by ID
+// @Tags users admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
+// @Success 204
+// @Failure 400 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/users/{id} [delete]
+// @Authorize Bearer
+func (controller *UsersController) deleteUserHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if claims := c.MustGet("user").(*utils.Claims); claims.UserID == id {
+		c.Error(utils.NewAPIError(http.StatusBadRequest, "own_account", "You cannot delete your own account"))
+		return
+	}
+
+	err = controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		var user models.User
+		if err := tx.Read(c.Request.Context(), &user, id); err != nil {
+			return err
+		}
+		if err := utils.CheckIfMatch(c, user.Version); err != nil {
+			return err
+		}
+
+		if err := tx.RevokeUserSessions(c.Request.Context(), id); err != nil {
+			return err
+		}
+		if err := recordAudit(c, tx, models.AuditActionDelete, user.ID, &user, nil); err != nil {
+			return err
+		}
+		return tx.Delete(c.Request.Context(), &user, id)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Update user
+// @Description Update a user by ID. Disabling a user or resetting their password also revokes all of their sessions.
+// @Description Send the fields to change as JSON, a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// @Description selected by its Content-Type. Patches apply to the fields as they are, e.g. from the last GET.
+// @Tags users admin
+// @Accept json,application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param If-Match header string false "ETag of the version the change is based on, e.g. \"3\""
+// @Param user body UserDTO true "Updated user data"
+// @Success 200 {object} types.UserResponse
+// @Header 200 {string} ETag "ETag of the updated version"
+// @Failure 400 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 409 {object} types.Problem
+// @Failure 412 {object} types.Problem
+// @Failure 428 {object} types.Problem
+// @Failure 403 {object} types.Problem
+// @Router /admin/users/{id} [patch]
+// @Authorize Bearer
+func (controller *UsersController) updateUserHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	update, err := readUpdate(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var user *models.User
+	err = controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		user, err = tx.GetUserByID(c.Request.Context(), id)
+		if err != nil {
+			return err
+		}
+		if err := utils.CheckIfMatch(c, user.Version); err != nil {
+			return err
+		}
+		before := *user
+
+		// Set the ID to enable partial updates through the required_without=ID validation
+		updateDTO := UserDTO{ID: &id}
+		if _, err := update.decode(userFields(*user), &updateDTO); err != nil {
+			return err
+		}
+
+		claims := c.MustGet("user").(*utils.Claims)
+		if claims.UserID == id && ((updateDTO.Disabled != nil && *updateDTO.Disabled) || updateDTO.Role != nil) {
+			return utils.NewAPIError(http.StatusBadRequest, "own_account", "You cannot disable or change the role of your own account")
+		}
+
+		if updateDTO.Username != nil && *updateDTO.Username != user.Username {
+			if err := checkUsernameAvailable(c.Request.Context(), tx, *updateDTO.Username); err != nil {
+				return err
+			}
+		}
+
+		if updateDTO.Role != nil {
+			role, err := readRole(c.Request.Context(), tx, *updateDTO.Role)
+			if err != nil {
+				return err
+			}
+			user.RoleID = &role.ID
+			user.Role = role
+		}
+
+		wasDisabled := user.Disabled
+		if err := updateDTO.ApplyToModel(user); err != nil {
+			return err
+		}
+
+		// Save through the role ID only, the role itself is never modified here
+		role := user.Role
+		user.Role = nil
+		if err := tx.Update(c.Request.Context(), user); err != nil {
+			return err
+		}
+		user.Role = role
+
+		// A reset password logs the user out like disabling them, old refresh tokens are not to outlive it
+		if (user.Disabled && !wasDisabled) || user.Password != before.Password {
+			if err := tx.RevokeUserSessions(c.Request.Context(), id); err != nil {
+				return err
+			}
+		}
+		return recordAudit(c, tx, models.AuditActionUpdate, user.ID, &before, user)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	utils.SetETag(c, user.Version)
+	c.JSON(http.StatusOK, toUserResponse(*user))
+}
+
+// checkUsernameAvailable fails with a conflict when another user has the name
+func checkUsernameAvailable(ctx context.Context, db database.Service, username string) error {
+	_, err := db.GetUser(ctx, username)
+	if err == nil {
+		return utils.NewAPIError(http.StatusConflict, "username_taken", "Username is already taken")
+	}
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// readRole loads a role by name, an unknown name is a validation error of the role field
+func readRole(ctx context.Context, db database.Service, name string) (*models.Role, error) {
+	role, err := db.GetRole(ctx, name)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, utils.ValidationFailed(types.FieldError{Field: "role", Message: "does not exist"})
+	}
+	return role, err
+}
+
+// userFields captures the fields of a user that updates change. The password is write-only,
+// patches see it as null, so they can set a new one but never read the hash.
+func userFields(user models.User) UserDTO {
+	fields := UserDTO{
+		ID:                 &user.ID,
+		Username:           &user.Username,
+		Password:           nil,
+		Disabled:           &user.Disabled,
+		MustChangePassword: &user.MustChangePassword,
+	}
+	if user.Role != nil {
+		fields.Role = &user.Role.Name
+	}
+	return fields
+}
+
+func toUserResponse(user models.User) types.UserResponse {
+	response := types.UserResponse{
+		ID:                 user.ID,
+		Username:           user.Username,
+		Disabled:           user.Disabled,
+		MustChangePassword: user.MustChangePassword,
+		Version:            user.Version,
+		CreatedAt:          user.CreatedAt,
+		UpdatedAt:          user.UpdatedAt,
+	}
+	if user.Role != nil {
+		response.Role = user.Role.Name
+	}
+	return response
+}
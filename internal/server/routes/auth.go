@@ -1,7 +1,11 @@
 package routes
 
 import (
+	"errors"
 	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/server/middleware"
+	"go-playground/internal/server/types"
 	"go-playground/internal/server/utils"
 	"net/http"
 	"time"
@@ -17,15 +21,37 @@ func RegisterAuthRoutes(r *gin.RouterGroup) {
 	r.POST("/login", controller.loginHandler)
 	r.POST("/logout", controller.logoutHandler)
 	r.POST("/refresh", controller.refreshHandler)
+	r.POST("/password", middleware.AuthMiddleware(), controller.changePasswordHandler)
+	r.GET("/sessions", middleware.AuthMiddleware(), controller.listSessionsHandler)
+	r.DELETE("/sessions/:id", middleware.AuthMiddleware(), controller.revokeSessionHandler)
 }
 
+// refreshTokenLifetime is how long a session can go without being refreshed
+const refreshTokenLifetime = time.Hour * 24 * 7
+
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
 type LoginResponse struct {
-	AuthToken string `json:"authToken" binding:"required"`
+	AuthToken          string `json:"authToken" binding:"required"`
+	MustChangePassword bool   `json:"mustChangePassword"`
+}
+
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
 }
 
 type AuthController struct {
@@ -39,53 +65,71 @@ type AuthController struct {
 // @Produce json
 // @Param login body LoginRequest true "Login request"
 // @Success 200 {object} LoginResponse
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem
+// @Failure 422 {object} types.Problem
 // @Router /auth/login [post]
 func (controller *AuthController) loginHandler(c *gin.Context) {
 	var loginReq LoginRequest
 	if err := c.ShouldBindJSON(&loginReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	user, err := controller.db.GetUser(loginReq.Username)
-
+	user, err := controller.db.GetUser(c.Request.Context(), loginReq.Username)
+	if errors.Is(err, database.ErrNotFound) {
+		c.Error(utils.ErrInvalidCredentials)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		c.Error(err)
 		return
 	}
 
 	validLogin, err := utils.VerifyPassword(user.Password, loginReq.Password)
 	if err != nil || !validLogin {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		c.Error(utils.ErrInvalidCredentials)
+		return
+	}
+
+	if user.Disabled {
+		c.Error(utils.NewAPIError(http.StatusForbidden, "account_disabled", "Account is disabled"))
 		return
 	}
 
 	token, err := utils.GenerateJWT(*user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		c.Error(err)
 		return
 	}
 
-	user.RefreshToken, err = utils.GenerateRefreshToken()
-	user.ExpriesAt = time.Now().Add(time.Hour * 24 * 7)
+	refreshToken, session, err := newSession(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate refresh token"})
+		c.Error(err)
+		return
+	}
+	session.UserID = user.ID
+	session.FamilyID, err = utils.GenerateRefreshToken()
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	controller.db.Update(user)
+	if err := controller.db.Create(c.Request.Context(), session); err != nil {
+		c.Error(err)
+		return
+	}
 
-	c.SetCookie("refreshToken", user.RefreshToken, 60*60*24*7, "/", "", true, true)
+	setRefreshCookie(c, refreshToken)
 
-	c.JSON(http.StatusOK, gin.H{
-		"authToken": token,
+	c.JSON(http.StatusOK, LoginResponse{
+		AuthToken:          token,
+		MustChangePassword: user.MustChangePassword,
 	})
 }
 
 // @Summary Logout
-// @Description Logout user and clear JWT token
+// @Description Logout user and revoke the session of the refresh token
 // @Tags auth
 // @Produce json
 // @Success 200 {object} map[string]string
@@ -93,13 +137,15 @@ func (controller *AuthController) loginHandler(c *gin.Context) {
 func (controller *AuthController) logoutHandler(c *gin.Context) {
 	token, err := c.Cookie("refreshToken")
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		c.Error(utils.ErrInvalidCredentials)
 		return
 	}
 	// Clear the cookie
 	c.SetCookie("refreshToken", "", -1, "/", "", true, true)
-	// Clear the refresh token in the database
-	controller.db.ClearRefreshToken(token)
+	// Revoke the session in the database
+	if session, err := controller.db.GetSessionByTokenHash(c.Request.Context(), utils.HashRefreshToken(token)); err == nil {
+		controller.db.RevokeSessionFamily(c.Request.Context(), session.FamilyID)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Logout successful",
@@ -107,31 +153,245 @@ func (controller *AuthController) logoutHandler(c *gin.Context) {
 }
 
 // @Summary Refresh token
-// @Description Refresh JWT token
+// @Description Refresh JWT token. The refresh token cookie is rotated on every call,
+// @Description presenting an already rotated token revokes every session of that login.
 // @Tags auth
 // @Produce json
 // @Success 200 {object} LoginResponse
-// @Failure 401 {object} map[string]string
+// @Failure 401 {object} types.Problem
 // @Router /auth/refresh [post]
 func (controller *AuthController) refreshHandler(c *gin.Context) {
 	refreshToken, err := c.Cookie("refreshToken")
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		c.Error(utils.ErrInvalidCredentials)
+		return
+	}
+
+	session, err := controller.db.GetSessionByTokenHash(c.Request.Context(), utils.HashRefreshToken(refreshToken))
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		c.Error(err)
+		return
+	}
+	if err != nil || session.User == nil || session.User.Disabled {
+		c.Error(utils.ErrInvalidCredentials)
+		return
+	}
+
+	if session.ExpiresAt.Before(time.Now()) {
+		c.Error(utils.NewAPIError(http.StatusUnauthorized, "session_expired", "Session expired"))
 		return
 	}
-	user, err := controller.db.GetUserByRefreshToken(refreshToken)
+
+	nextToken, next, err := newSession(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		c.Error(err)
 		return
 	}
 
-	token, err := utils.GenerateJWT(*user)
+	if err := controller.db.RotateSession(c.Request.Context(), session, next); err != nil {
+		if errors.Is(err, database.ErrSessionReused) {
+			c.SetCookie("refreshToken", "", -1, "/", "", true, true)
+			c.Error(utils.ErrInvalidCredentials)
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	token, err := utils.GenerateJWT(*session.User)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"authToken": token,
+	setRefreshCookie(c, nextToken)
+
+	c.JSON(http.StatusOK, LoginResponse{
+		AuthToken:          token,
+		MustChangePassword: session.User.MustChangePassword,
 	})
 }
+
+// @Summary List sessions
+// @Description List the active sessions of the logged in user
+// @Tags auth
+// @Produce json
+// @Success 200 {array} SessionResponse
+// @Failure 401 {object} types.Problem
+// @Router /auth/sessions [get]
+// @Authorize Bearer
+func (controller *AuthController) listSessionsHandler(c *gin.Context) {
+	claims := c.MustGet("user").(*utils.Claims)
+
+	sessions, err := controller.db.ListUserSessions(c.Request.Context(), claims.UserID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	currentHash := ""
+	if token, err := c.Cookie("refreshToken"); err == nil {
+		currentHash = utils.HashRefreshToken(token)
+	}
+
+	response := []SessionResponse{}
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.TokenHash == currentHash,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Revoke session
+// @Description Revoke one of the sessions of the logged in user, logging that device out
+// @Tags auth
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 204
+// @Failure 404 {object} types.Problem
+// @Router /auth/sessions/{id} [delete]
+// @Authorize Bearer
+func (controller *AuthController) revokeSessionHandler(c *gin.Context) {
+	id, err := utils.GetIDParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	claims := c.MustGet("user").(*utils.Claims)
+
+	var session models.Session
+	if err := controller.db.Read(c.Request.Context(), &session, id); err != nil {
+		c.Error(err)
+		return
+	}
+	// Sessions of other users are as good as missing
+	if session.UserID != claims.UserID {
+		c.Error(utils.NewAPIError(http.StatusNotFound, "not_found", "Session not found"))
+		return
+	}
+
+	if err := controller.db.RevokeSessionFamily(c.Request.Context(), session.FamilyID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// newSession generates a refresh token and the session it belongs to for the requesting device
+func newSession(c *gin.Context) (string, *models.Session, error) {
+	token, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		TokenHash:  utils.HashRefreshToken(token),
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshTokenLifetime),
+	}
+	return token, session, nil
+}
+
+func setRefreshCookie(c *gin.Context, token string) {
+	c.SetCookie("refreshToken", token, int(refreshTokenLifetime.Seconds()), "/", "", true, true)
+}
+
+// @Summary Change password
+// @Description Change the password of the logged in user and return a new JWT token. Every session of the user
+// @Description is revoked, the requesting device gets a new one with a new refresh token cookie.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param password body ChangePasswordRequest true "Change password request"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} types.Problem
+// @Failure 401 {object} types.Problem
+// @Failure 422 {object} types.Problem
+// @Router /auth/password [post]
+// @Authorize Bearer
+func (controller *AuthController) changePasswordHandler(c *gin.Context) {
+	var passwordReq ChangePasswordRequest
+	if err := c.ShouldBindJSON(&passwordReq); err != nil {
+		c.Error(err)
+		return
+	}
+
+	claims := c.MustGet("user").(*utils.Claims)
+	user, err := controller.db.GetUserByID(c.Request.Context(), claims.UserID)
+	if errors.Is(err, database.ErrNotFound) {
+		c.Error(utils.ErrInvalidCredentials)
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	validLogin, err := utils.VerifyPassword(user.Password, passwordReq.CurrentPassword)
+	if err != nil || !validLogin {
+		c.Error(utils.ErrInvalidCredentials)
+		return
+	}
+
+	if passwordReq.NewPassword == passwordReq.CurrentPassword {
+		c.Error(utils.ValidationFailed(types.FieldError{Field: "new_password", Message: "must differ from the current password"}))
+		return
+	}
+
+	user.Password, err = utils.HashPassword(passwordReq.NewPassword)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	user.MustChangePassword = false
+
+	refreshToken, session, err := newSession(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	session.UserID = user.ID
+	session.FamilyID, err = utils.GenerateRefreshToken()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Refresh tokens issued with the old password must not outlive it
+	err = controller.db.WithTx(c.Request.Context(), func(tx database.Service) error {
+		if err := tx.Update(c.Request.Context(), user); err != nil {
+			return err
+		}
+		if err := tx.RevokeUserSessions(c.Request.Context(), user.ID); err != nil {
+			return err
+		}
+		return tx.Create(c.Request.Context(), session)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	token, err := utils.GenerateJWT(*user)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setRefreshCookie(c, refreshToken)
+
+	c.JSON(http.StatusOK, LoginResponse{AuthToken: token})
+}
@@ -0,0 +1,656 @@
+package routes
+
+import (
+	"bytes"
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+	"go-playground/internal/database/query"
+	"go-playground/internal/importer"
+	"go-playground/internal/onix"
+	"go-playground/internal/opds"
+	"go-playground/internal/server/utils"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OPDSController serves the catalog as OPDS feeds to e-reader apps
+type OPDSController struct {
+	db database.Service
+
+	// title names the catalog
+	title string
+	// bookURL is where a book is bought, with {id} and {isbn} in place of those of the book.
+	// Books link to themselves in the API when it is empty.
+	bookURL string
+	// currency of the prices of the books
+	currency string
+	// started dates the feeds that do not change with the catalog
+	started time.Time
+}
+
+// defaultOPDSTitle names the catalog unless OPDS_TITLE is set
+const defaultOPDSTitle = "Go Playground"
+
+// Register routes for the OPDS module, the feeds of both versions are below the version
+// they are written in
+func RegisterOPDSRoutes(r *gin.RouterGroup) {
+	controller := &OPDSController{
+		db: database.New(),
+
+		title:    defaultOPDSTitle,
+		bookURL:  os.Getenv("OPDS_BOOK_URL"),
+		currency: onix.Currency(),
+		started:  time.Now().UTC().Truncate(time.Second),
+	}
+	if title := os.Getenv("OPDS_TITLE"); title != "" {
+		controller.title = title
+	}
+
+	r.GET("", controller.startHandler)
+	r.GET("/:version", controller.rootHandler)
+	r.GET("/:version/books", controller.booksHandler)
+	r.GET("/:version/authors", controller.authorsHandler)
+	r.GET("/:version/genres", controller.genresHandler)
+	r.GET("/:version/search", controller.searchHandler)
+	r.GET("/:version/opensearch.xml", controller.openSearchHandler)
+}
+
+// OPDS
+// @Summary Start OPDS catalog
+// @Description Redirect to the root of the catalog in the OPDS version the client accepts, OPDS 2.0 for
+// @Description application/opds+json and OPDS 1.2 otherwise
+// @Tags opds
+// @Success 302
+// @Header 302 {string} Location "Root of the catalog"
+// @Router /opds [get]
+func (controller *OPDSController) startHandler(c *gin.Context) {
+	version := opds.Version1
+	if c.NegotiateFormat(opds.MediaTypeAtom, opds.MediaTypeJSON) == opds.MediaTypeJSON {
+		version = opds.Version2
+	}
+	c.Header("Vary", "Accept")
+	c.Redirect(http.StatusFound, controller.feedURL(c, version, "", nil))
+}
+
+// OPDS
+// @Summary OPDS root
+// @Description Navigation feed at the root of the catalog, linking to all books, new releases, digital books,
+// @Description authors and genres. version is v1.2 for Atom or v2 for JSON.
+// @Tags opds
+// @Produce application/atom+xml,application/opds+json
+// @Param version path string true "OPDS version, v1.2 or v2"
+// @Success 200 {string} string "Navigation feed"
+// @Failure 404 {object} types.Problem
+// @Router /opds/{version} [get]
+func (controller *OPDSController) rootHandler(c *gin.Context) {
+	version, ok := controller.version(c)
+	if !ok {
+		return
+	}
+
+	feed := controller.newFeed(c, version, controller.title, opds.KindNavigation)
+	feed.Updated = controller.started
+	feed.Navigation = []opds.Navigation{
+		{
+			Title:   "All books",
+			Href:    controller.feedURL(c, version, "/books", nil),
+			Kind:    opds.KindAcquisition,
+			Summary: "Every book of the catalog by title",
+		},
+		{
+			Title:   "New releases",
+			Href:    controller.feedURL(c, version, "/books", url.Values{"sort": {"-published_date"}}),
+			Rel:     opds.RelSortNew,
+			Kind:    opds.KindAcquisition,
+			Summary: "The latest books first",
+		},
+		{
+			Title:   "Digital books",
+			Href:    controller.feedURL(c, version, "/books", url.Values{"filter[digital_only]": {"true"}}),
+			Kind:    opds.KindAcquisition,
+			Summary: "Books published only in digital form",
+		},
+		{
+			Title:   "Authors",
+			Href:    controller.feedURL(c, version, "/authors", nil),
+			Kind:    opds.KindNavigation,
+			Summary: "Books by author",
+		},
+		{
+			Title:   "Genres",
+			Href:    controller.feedURL(c, version, "/genres", nil),
+			Kind:    opds.KindNavigation,
+			Summary: "Books by genre",
+		},
+	}
+	for i := range feed.Navigation {
+		feed.Navigation[i].Updated = controller.started
+	}
+
+	controller.write(c, version, feed)
+}
+
+// OPDS
+// @Summary OPDS books
+// @Description Acquisition feed of books, by title unless sorted otherwise, with facets to sort them, to show
+// @Description only digital books and to pick a genre or an author. A book has its author, genres, description,
+// @Description cover image and thumbnail, and a link to buy it at OPDS_BOOK_URL for its price. Takes the
+// @Description pagination, filters and sorting of GET /books.
+// @Tags opds
+// @Produce application/atom+xml,application/opds+json
+// @Param version path string true "OPDS version, v1.2 or v2"
+// @Param genre query string false "Slug of a genre, the books of its subgenres are included"
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Param after query string false "Cursor to continue after, replaces offset"
+// @Param filter query string false "Filter as filter[field][op]=value, op is one of eq, ne, lt, lte, gt, gte, like, in"
+// @Param sort query string false "Comma separated fields to sort by, prefix with - for descending"
+// @Success 200 {string} string "Acquisition feed"
+// @Failure 400 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Router /opds/{version}/books [get]
+func (controller *OPDSController) booksHandler(c *gin.Context) {
+	version, ok := controller.version(c)
+	if !ok {
+		return
+	}
+
+	values := c.Request.URL.Query()
+	if values.Get("sort") == "" {
+		values.Set("sort", "title")
+	}
+	listQuery, err := query.Parse(values, database.BookFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	title := "Books"
+	var books []models.Book
+	var page query.Page
+	if slug := c.Query("genre"); slug != "" {
+		genre, err := controller.db.GetGenreBySlug(c.Request.Context(), slug)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		genreIDs, err := controller.db.GenreDescendantIDs(c.Request.Context(), genre.ID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		books, page, err = controller.db.ListBooksByGenre(c.Request.Context(), genreIDs, listQuery)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		title = genre.Name
+	} else {
+		books, page, err = controller.db.ListBooks(c.Request.Context(), listQuery)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	feed := controller.newFeed(c, version, title, opds.KindAcquisition)
+	controller.addPublications(c, version, feed, books)
+	controller.paginate(c, feed, page, listQuery)
+
+	facets, err := controller.bookFacets(c, values, books)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	feed.Facets = facets
+
+	controller.write(c, version, feed)
+}
+
+// OPDS
+// @Summary OPDS authors
+// @Description Navigation feed of the authors by name, each linking to the acquisition feed of their books.
+// @Description Takes the pagination, filters and sorting of GET /authors.
+// @Tags opds
+// @Produce application/atom+xml,application/opds+json
+// @Param version path string true "OPDS version, v1.2 or v2"
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {string} string "Navigation feed"
+// @Failure 400 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Router /opds/{version}/authors [get]
+func (controller *OPDSController) authorsHandler(c *gin.Context) {
+	version, ok := controller.version(c)
+	if !ok {
+		return
+	}
+
+	values := c.Request.URL.Query()
+	if values.Get("sort") == "" {
+		values.Set("sort", "last_name,first_name")
+	}
+	listQuery, err := query.Parse(values, database.AuthorFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var authors []models.Author
+	page, err := controller.db.List(c.Request.Context(), &authors, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	feed := controller.newFeed(c, version, "Authors", opds.KindNavigation)
+	feed.Updated = controller.started
+	for _, author := range authors {
+		feed.Navigation = append(feed.Navigation, opds.Navigation{
+			Title:   strings.TrimSpace(author.FirstName + " " + author.LastName),
+			Href:    controller.feedURL(c, version, "/books", url.Values{"filter[author_id]": {strconv.FormatUint(uint64(author.ID), 10)}}),
+			Kind:    opds.KindAcquisition,
+			Updated: author.UpdatedAt,
+		})
+		if author.UpdatedAt.After(feed.Updated) {
+			feed.Updated = author.UpdatedAt
+		}
+	}
+	controller.paginate(c, feed, page, listQuery)
+
+	controller.write(c, version, feed)
+}
+
+// OPDS
+// @Summary OPDS genres
+// @Description Navigation feed of the genres by name, each linking to the acquisition feed of its books and
+// @Description those of its subgenres. Takes the pagination, filters and sorting of GET /genres.
+// @Tags opds
+// @Produce application/atom+xml,application/opds+json
+// @Param version path string true "OPDS version, v1.2 or v2"
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {string} string "Navigation feed"
+// @Failure 400 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Router /opds/{version}/genres [get]
+func (controller *OPDSController) genresHandler(c *gin.Context) {
+	version, ok := controller.version(c)
+	if !ok {
+		return
+	}
+
+	values := c.Request.URL.Query()
+	if values.Get("sort") == "" {
+		values.Set("sort", "name")
+	}
+	listQuery, err := query.Parse(values, database.GenreFields)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var genres []models.Genre
+	page, err := controller.db.List(c.Request.Context(), &genres, listQuery)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	feed := controller.newFeed(c, version, "Genres", opds.KindNavigation)
+	feed.Updated = controller.started
+	for _, genre := range genres {
+		feed.Navigation = append(feed.Navigation, opds.Navigation{
+			Title:   genre.Name,
+			Href:    controller.feedURL(c, version, "/books", url.Values{"genre": {genre.Slug}}),
+			Kind:    opds.KindAcquisition,
+			Updated: genre.UpdatedAt,
+		})
+		if genre.UpdatedAt.After(feed.Updated) {
+			feed.Updated = genre.UpdatedAt
+		}
+	}
+	controller.paginate(c, feed, page, listQuery)
+
+	controller.write(c, version, feed)
+}
+
+// OPDS
+// @Summary OPDS search
+// @Description Acquisition feed of the books a full-text search finds, best matches first. OPDS 1.2 clients
+// @Description find it through the OpenSearch description, OPDS 2.0 clients through the templated search link.
+// @Tags opds
+// @Produce application/atom+xml,application/opds+json
+// @Param version path string true "OPDS version, v1.2 or v2"
+// @Param q query string true "Search query"
+// @Param limit query int false "Maximum books"
+// @Success 200 {string} string "Acquisition feed"
+// @Failure 400 {object} types.Problem
+// @Failure 404 {object} types.Problem
+// @Failure 503 {object} types.Problem
+// @Router /opds/{version}/search [get]
+func (controller *OPDSController) searchHandler(c *gin.Context) {
+	version, ok := controller.version(c)
+	if !ok {
+		return
+	}
+
+	q := c.Query("q")
+	if q == "" {
+		c.Error(utils.NewAPIError(http.StatusBadRequest, "invalid_query", "Query parameter q is required"))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(query.DefaultLimit)))
+	if err != nil || limit < 1 {
+		c.Error(utils.NewAPIError(http.StatusBadRequest, "invalid_query", "Invalid limit format"))
+		return
+	}
+	limit = min(limit, query.MaxLimit)
+
+	results, err := controller.db.Search(c.Request.Context(), q, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var ids []uint
+	for _, result := range results {
+		if result.EntityType == database.SearchEntityBook {
+			ids = append(ids, result.EntityID)
+		}
+	}
+
+	var books []models.Book
+	if len(ids) > 0 {
+		listQuery := query.ListQuery{
+			Limit:   len(ids),
+			Filters: []query.Filter{{Field: "id", Column: "id", Operator: query.OpIn, Value: ids}},
+		}
+		books, _, err = controller.db.ListBooks(c.Request.Context(), listQuery)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		// Keep the order of the search, best matches first
+		slices.SortFunc(books, func(a, b models.Book) int {
+			return slices.Index(ids, a.ID) - slices.Index(ids, b.ID)
+		})
+	}
+
+	feed := controller.newFeed(c, version, "Search results for "+q, opds.KindAcquisition)
+	controller.addPublications(c, version, feed, books)
+	total := int64(len(books))
+	feed.Page = &opds.Page{Total: &total, Limit: limit}
+
+	controller.write(c, version, feed)
+}
+
+// OPDS
+// @Summary OPDS OpenSearch description
+// @Description OpenSearch description of the search of the OPDS 1.2 catalog
+// @Tags opds
+// @Produce application/opensearchdescription+xml
+// @Param version path string true "OPDS version, only v1.2"
+// @Success 200 {string} string "OpenSearch description"
+// @Failure 404 {object} types.Problem
+// @Router /opds/{version}/opensearch.xml [get]
+func (controller *OPDSController) openSearchHandler(c *gin.Context) {
+	version, ok := controller.version(c)
+	if !ok {
+		return
+	}
+	if version != opds.Version1 {
+		c.Error(utils.NewAPIError(http.StatusNotFound, "not_found", "OPDS 2.0 searches with a templated link"))
+		return
+	}
+
+	var body bytes.Buffer
+	template := controller.feedURL(c, version, "/search", nil) + "?q={searchTerms}"
+	if err := opds.WriteOpenSearch(&body, controller.title, template); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Data(http.StatusOK, opds.MediaTypeOpenSearch, body.Bytes())
+}
+
+// version reads the OPDS version of the path, answering with 404 when there is no such version
+func (controller *OPDSController) version(c *gin.Context) (string, bool) {
+	version := c.Param("version")
+	if version != opds.Version1 && version != opds.Version2 {
+		c.Error(utils.NewAPIError(http.StatusNotFound, "not_found", "OPDS version must be v1.2 or v2"))
+		return "", false
+	}
+	return version, true
+}
+
+// feedURL is the absolute URL of a feed of the version with the query values
+func (controller *OPDSController) feedURL(c *gin.Context, version string, feedPath string, values url.Values) string {
+	link := utils.PublicURL(c, "/api/v1/opds/"+version+feedPath)
+	if len(values) > 0 {
+		link += "?" + values.Encode()
+	}
+	return link
+}
+
+// newFeed starts a feed at the URL of the request, linked to the root of the catalog and its
+// search
+func (controller *OPDSController) newFeed(c *gin.Context, version string, title string, kind string) *opds.Feed {
+	feed := &opds.Feed{
+		ID:      utils.PublicURL(c, c.Request.URL.RequestURI()),
+		Title:   title,
+		Kind:    kind,
+		Author:  controller.title,
+		Updated: controller.started,
+		Links: []opds.Link{
+			{Rel: opds.RelStart, Href: controller.feedURL(c, version, "", nil), Kind: opds.KindNavigation, Title: controller.title},
+		},
+	}
+	// Every feed but the root is below it
+	if !strings.HasSuffix(c.FullPath(), "/:version") {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: controller.feedURL(c, version, "", nil), Kind: opds.KindNavigation})
+	}
+
+	if version == opds.Version1 {
+		feed.Links = append(feed.Links, opds.Link{
+			Rel:  opds.RelSearch,
+			Href: controller.feedURL(c, version, "/opensearch.xml", nil),
+			Type: opds.MediaTypeOpenSearch,
+		})
+	} else {
+		feed.Links = append(feed.Links, opds.Link{
+			Rel:       opds.RelSearch,
+			Href:      controller.feedURL(c, version, "/search", nil) + "{?q}",
+			Kind:      opds.KindAcquisition,
+			Templated: true,
+		})
+	}
+	return feed
+}
+
+// paginate links the first, previous and next pages of a feed
+func (controller *OPDSController) paginate(c *gin.Context, feed *opds.Feed, page query.Page, listQuery query.ListQuery) {
+	feed.Page = &opds.Page{Total: page.Total, Limit: listQuery.Limit, Offset: listQuery.Offset}
+
+	links := utils.NewPageLinks(c, page, listQuery)
+	if listQuery.Offset > 0 || listQuery.After != nil {
+		first := c.Request.URL.Query()
+		first.Del("offset")
+		first.Del("after")
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelFirst, Href: controller.pageURL(c, first), Kind: feed.Kind})
+	}
+	if links.Prev != nil {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelPrevious, Href: utils.PublicURL(c, *links.Prev), Kind: feed.Kind})
+	}
+	if links.Next != nil {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelNext, Href: utils.PublicURL(c, *links.Next), Kind: feed.Kind})
+	}
+}
+
+// pageURL is the absolute URL of the requested feed with other query values
+func (controller *OPDSController) pageURL(c *gin.Context, values url.Values) string {
+	link := utils.PublicURL(c, c.Request.URL.Path)
+	if len(values) > 0 {
+		link += "?" + values.Encode()
+	}
+	return link
+}
+
+// bookFacets offers the views of the books feed: sorted by title or newest first, all or the
+// digital books, of a genre and of one of the authors on the page. values are the query
+// values of the request, a facet changes one of them and starts from the first page.
+func (controller *OPDSController) bookFacets(c *gin.Context, values url.Values, books []models.Book) ([]opds.FacetGroup, error) {
+	facet := func(title string, key string, value string) opds.Facet {
+		changed := url.Values{}
+		for k, v := range values {
+			changed[k] = v
+		}
+		changed.Del("offset")
+		changed.Del("after")
+		if value == "" {
+			changed.Del(key)
+		} else {
+			changed.Set(key, value)
+		}
+		return opds.Facet{Title: title, Href: controller.pageURL(c, changed), Active: values.Get(key) == value}
+	}
+
+	groups := []opds.FacetGroup{
+		{Title: "Sort", Facets: []opds.Facet{
+			facet("Title", "sort", "title"),
+			facet("Newest", "sort", "-published_date"),
+		}},
+		{Title: "Availability", Facets: []opds.Facet{
+			facet("All books", "filter[digital_only]", ""),
+			facet("Digital only", "filter[digital_only]", "true"),
+		}},
+	}
+
+	var genres []models.Genre
+	if _, err := controller.db.List(c.Request.Context(), &genres, query.ListQuery{
+		Limit: query.MaxLimit,
+		Sort:  []query.Sort{{Field: "name", Column: "name"}},
+	}); err != nil {
+		return nil, err
+	}
+	genreGroup := opds.FacetGroup{Title: "Genre", Facets: []opds.Facet{facet("All genres", "genre", "")}}
+	for _, genre := range genres {
+		genreGroup.Facets = append(genreGroup.Facets, facet(genre.Name, "genre", genre.Slug))
+	}
+	groups = append(groups, genreGroup)
+
+	authorGroup := opds.FacetGroup{Title: "Author", Facets: []opds.Facet{facet("All authors", "filter[author_id]", "")}}
+	seen := map[uint]bool{}
+	for _, book := range books {
+		if book.AuthorID == 0 || seen[book.AuthorID] {
+			continue
+		}
+		seen[book.AuthorID] = true
+		name := strings.TrimSpace(book.Author.FirstName + " " + book.Author.LastName)
+		authorGroup.Facets = append(authorGroup.Facets, facet(name, "filter[author_id]", strconv.FormatUint(uint64(book.AuthorID), 10)))
+	}
+	groups = append(groups, authorGroup)
+
+	return groups, nil
+}
+
+// addPublications adds books to an acquisition feed, which is as recent as its latest book
+func (controller *OPDSController) addPublications(c *gin.Context, version string, feed *opds.Feed, books []models.Book) {
+	for _, book := range books {
+		feed.Publications = append(feed.Publications, controller.publication(c, version, book))
+		if book.UpdatedAt.After(feed.Updated) {
+			feed.Updated = book.UpdatedAt
+		}
+	}
+}
+
+// publication describes a book for an acquisition feed. Its cover is linked as the image and
+// the thumbnail variant as the thumbnail, in the format of the upload since e-readers
+// often cannot show WebP.
+func (controller *OPDSController) publication(c *gin.Context, version string, book models.Book) opds.Publication {
+	id := strconv.FormatUint(uint64(book.ID), 10)
+	self := utils.PublicURL(c, "/api/v1/books/"+id)
+
+	publication := opds.Publication{
+		ID:          self,
+		Title:       book.Title,
+		Published:   book.PublishedDate,
+		Modified:    book.UpdatedAt,
+		Description: book.Description,
+		Pages:       book.Pages,
+		Alternate:   self,
+		Buy:         opds.Acquisition{Href: self, Type: "application/json", Price: book.Price, Currency: controller.currency},
+	}
+	if isbn, ok := importer.NormalizeISBN(book.ISBN); ok {
+		publication.ID = "urn:isbn:" + isbn
+		publication.ISBN = isbn
+	}
+	if controller.bookURL != "" {
+		publication.Buy.Href = strings.NewReplacer("{id}", id, "{isbn}", url.PathEscape(publication.ISBN)).Replace(controller.bookURL)
+		publication.Buy.Type = "text/html"
+	}
+
+	if name := strings.TrimSpace(book.Author.FirstName + " " + book.Author.LastName); name != "" {
+		publication.Authors = append(publication.Authors, opds.Contributor{
+			Name: name,
+			Href: controller.feedURL(c, version, "/books", url.Values{"filter[author_id]": {strconv.FormatUint(uint64(book.AuthorID), 10)}}),
+		})
+	}
+	for _, genre := range book.Genres {
+		publication.Subjects = append(publication.Subjects, opds.Subject{
+			Name: genre.Name,
+			Code: genre.Slug,
+			Href: controller.feedURL(c, version, "/books", url.Values{"genre": {genre.Slug}}),
+		})
+	}
+
+	cover := book.Cover
+	if cover.ImageURL.Valid && cover.ImageURL.String != "" {
+		publication.Images = append(publication.Images, opds.Image{
+			Href:   cover.ImageURL.String,
+			Type:   mime.TypeByExtension(path.Ext(cover.ImageURL.String)),
+			Width:  cover.ImageWidth,
+			Height: cover.ImageHeight,
+		})
+	}
+	for _, variant := range cover.Variants {
+		if variant.Name == "thumbnail" && variant.Format != "webp" {
+			publication.Images = append(publication.Images, opds.Image{
+				Href:      variant.URL,
+				Type:      "image/" + variant.Format,
+				Width:     variant.Width,
+				Height:    variant.Height,
+				Thumbnail: true,
+			})
+		}
+	}
+
+	return publication
+}
+
+// write answers with a feed in the version of the request
+func (controller *OPDSController) write(c *gin.Context, version string, feed *opds.Feed) {
+	var body bytes.Buffer
+	mediaType := opds.MediaTypeJSON
+	write := opds.WriteJSON
+	if version == opds.Version1 {
+		mediaType = opds.MediaTypeNavigation
+		if feed.Kind == opds.KindAcquisition {
+			mediaType = opds.MediaTypeAcquisition
+		}
+		write = opds.WriteAtom
+	}
+
+	if err := write(&body, feed); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Data(http.StatusOK, mediaType, body.Bytes())
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -10,20 +11,34 @@ import (
 	_ "github.com/joho/godotenv/autoload"
 
 	"go-playground/internal/database"
+	"go-playground/internal/server/utils"
+	"go-playground/internal/storage"
 )
 
 type Server struct {
 	port int
 
-	db database.Service
+	db      database.Service
+	storage storage.Storage
 }
 
 func NewServer() *http.Server {
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
+
+	// Fail on startup rather than on the first login when the keys are misconfigured
+	if err := utils.LoadSigningKeys(); err != nil {
+		log.Fatalf("could not load JWT signing keys: %v", err)
+	}
+
 	NewServer := &Server{
 		port: port,
 
-		db: database.New(),
+		db:      database.New(),
+		storage: storage.New(),
+	}
+
+	if retention := trashRetention(); retention > 0 {
+		go NewServer.purgeTrashPeriodically(retention)
 	}
 
 	// Declare Server config
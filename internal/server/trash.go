@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"go-playground/internal/database"
+	"go-playground/internal/database/models"
+)
+
+// trashPurgeInterval is how often the trash is checked for expired items
+const trashPurgeInterval = time.Hour
+
+// trashRetention reads TRASH_RETENTION, a duration such as 720h. Deleted items are kept
+// forever unless it is set, purging them is up to the operator.
+func trashRetention() time.Duration {
+	value := os.Getenv("TRASH_RETENTION")
+	if value == "" {
+		return 0
+	}
+
+	retention, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid TRASH_RETENTION: %v", err)
+	}
+	return retention
+}
+
+// purgeTrashPeriodically permanently deletes the items that have been in the trash
+// for longer than the retention period, once at startup and then every interval.
+func (s *Server) purgeTrashPeriodically(retention time.Duration) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		s.purgeExpiredTrash(context.Background(), time.Now().Add(-retention))
+		<-ticker.C
+	}
+}
+
+// purgeExpiredTrash purges the items deleted before the given time. Covers go first,
+// then books and then authors, so a whole expired tree goes in a single run. Items
+// still referenced by newer ones are skipped until those expire too.
+func (s *Server) purgeExpiredTrash(ctx context.Context, before time.Time) {
+	kinds := []struct {
+		name   string
+		entity func() any
+	}{
+		{"covers", func() any { return &models.Cover{} }},
+		{"books", func() any { return &models.Book{} }},
+		{"authors", func() any { return &models.Author{} }},
+		{"artists", func() any { return &models.Artist{} }},
+	}
+
+	for _, kind := range kinds {
+		ids, err := s.db.ListTrashedBefore(ctx, kind.entity(), before)
+		if err != nil {
+			log.Printf("could not list expired %s in the trash: %v", kind.name, err)
+			continue
+		}
+
+		purged := 0
+		for _, id := range ids {
+			entity := kind.entity()
+			if err := s.db.Purge(ctx, entity, id); err != nil {
+				if !errors.Is(err, database.ErrConstraint) {
+					log.Printf("could not purge %s %d: %v", kind.name, id, err)
+				}
+				continue
+			}
+			purged++
+
+			if cover, ok := entity.(*models.Cover); ok {
+				for _, key := range cover.ImageKeys() {
+					if err := s.storage.Delete(ctx, key); err != nil {
+						log.Printf("could not delete cover image %s: %v", key, err)
+					}
+				}
+			}
+		}
+
+		if purged > 0 {
+			log.Printf("purged %d expired %s from the trash", purged, kind.name)
+		}
+	}
+}
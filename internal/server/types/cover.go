@@ -2,6 +2,20 @@ package types
 
 // ListCoverResponse is the response struct for the listCoversHandler
 type ListCoverResponse struct {
-	ID       uint   `json:"id"`
-	ImageURL string `json:"image_url"`
+	ID            uint                   `json:"id"`
+	ImageURL      string                 `json:"image_url"`
+	Width         int                    `json:"width,omitempty"`
+	Height        int                    `json:"height,omitempty"`
+	DominantColor string                 `json:"dominant_color,omitempty"`
+	BlurHash      string                 `json:"blurhash,omitempty"`
+	Variants      []CoverVariantResponse `json:"variants"`
+}
+
+// CoverVariantResponse is a resized copy of a cover image, e.g. for srcset
+type CoverVariantResponse struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	URL    string `json:"url"`
 }
@@ -0,0 +1,65 @@
+package types
+
+import "time"
+
+// The records of the catalog export are flat, so every format has the same fields and the CSV
+// columns are named after them. A book export can be imported again as it is.
+
+// ExportBookRecord is a book in the export of the exportHandler
+type ExportBookRecord struct {
+	ID              uint      `json:"id"`
+	ISBN            string    `json:"isbn"`
+	Title           string    `json:"title"`
+	PublishedDate   string    `json:"published_date"`
+	Pages           uint      `json:"pages"`
+	Description     string    `json:"description"`
+	DigitalOnly     bool      `json:"digital_only"`
+	Price           float32   `json:"price"`
+	AuthorID        uint      `json:"author_id"`
+	AuthorFirstName string    `json:"author_first_name"`
+	AuthorLastName  string    `json:"author_last_name"`
+	Genres          []string  `json:"genres"` // Slugs
+	CoverID         *uint     `json:"cover_id"`
+	CoverImageURL   *string   `json:"cover_image_url"`
+	Version         uint      `json:"version"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ExportAuthorRecord is an author in the export of the exportHandler
+type ExportAuthorRecord struct {
+	ID        uint      `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Version   uint      `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ExportArtistRecord is an artist in the export of the exportHandler
+type ExportArtistRecord struct {
+	ID        uint      `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Version   uint      `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ExportCoverRecord is a cover in the export of the exportHandler
+type ExportCoverRecord struct {
+	ID            uint      `json:"id"`
+	BookID        uint      `json:"book_id"`
+	BookTitle     string    `json:"book_title"`
+	BookISBN      string    `json:"book_isbn"`
+	DesignIdeas   *string   `json:"design_ideas"`
+	ImageURL      *string   `json:"image_url"`
+	ImageWidth    int       `json:"image_width"`
+	ImageHeight   int       `json:"image_height"`
+	DominantColor string    `json:"dominant_color"`
+	ArtistIDs     []uint    `json:"artist_ids"`
+	Artists       []string  `json:"artists"` // "First Last", in the order of artist_ids
+	Version       uint      `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package types
+
+// ListGenreResponse is the response struct for the listGenresHandler
+type ListGenreResponse struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// GetGenreResponse is the response struct for the getGenreHandler
+type GetGenreResponse struct {
+	ListGenreResponse
+	Parent   *ListGenreResponse  `json:"parent"`
+	Children []ListGenreResponse `json:"children"`
+}
@@ -0,0 +1,19 @@
+package types
+
+// ListResponse is the envelope returned by every list endpoint.
+// NextCursor can be passed as the after parameter to continue with keyset pagination,
+// the pages continued that way have no Total.
+type ListResponse[T any] struct {
+	Data       []T       `json:"data"`
+	Total      *int64    `json:"total"`
+	Limit      int       `json:"limit"`
+	Offset     int       `json:"offset"`
+	NextCursor *string   `json:"next_cursor"`
+	Links      PageLinks `json:"links"`
+}
+
+// PageLinks point to the neighbouring pages of a ListResponse, nil when there is none
+type PageLinks struct {
+	Next *string `json:"next"`
+	Prev *string `json:"prev"`
+}
@@ -0,0 +1,19 @@
+package types
+
+// Problem is the body of every error response, an RFC 7807 problem document.
+// Code is stable and meant for clients to switch on, Detail is for humans.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a problem with a single field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,12 @@
+package types
+
+import "go-playground/internal/database/models"
+
+// RevisionDiffResponse is the response struct for the diffRevisionsHandler
+type RevisionDiffResponse struct {
+	EntityType string         `json:"entity_type"`
+	EntityID   uint           `json:"entity_id"`
+	From       uint           `json:"from"`
+	To         uint           `json:"to"`
+	Changes    models.Changes `json:"changes"`
+}
@@ -0,0 +1,17 @@
+package types
+
+// SearchHit is a single highlighted match in the SearchResponse
+type SearchHit struct {
+	ID      uint    `json:"id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+// SearchResponse is the response struct for the searchHandler, grouped by entity type
+type SearchResponse struct {
+	Query   string      `json:"query"`
+	Books   []SearchHit `json:"books"`
+	Authors []SearchHit `json:"authors"`
+	Artists []SearchHit `json:"artists"`
+}
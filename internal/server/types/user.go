@@ -0,0 +1,15 @@
+package types
+
+import "time"
+
+// UserResponse is the response struct for the admin user handlers, it never includes credentials
+type UserResponse struct {
+	ID                 uint      `json:"id"`
+	Username           string    `json:"username"`
+	Role               string    `json:"role"`
+	Disabled           bool      `json:"disabled"`
+	MustChangePassword bool      `json:"must_change_password"`
+	Version            uint      `json:"version"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
@@ -2,8 +2,11 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -88,7 +91,9 @@ func HashPassword(password string) (string, error) {
 func VerifyPassword(storedHash, password string) (bool, error) {
 	// Extract just the salt and hash from the stored hash
 	parts := strings.Split(storedHash, "$")
-	fmt.Println(parts)
+	if len(parts) != 6 {
+		return false, errors.New("invalid password hash format")
+	}
 
 	// Decode the salt and hash
 	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
@@ -126,6 +131,13 @@ func GenerateRefreshToken() (string, error) {
 	return base64.RawStdEncoding.EncodeToString(b), nil
 }
 
+// HashRefreshToken returns the hash under which a refresh token is stored,
+// so a leaked database does not contain usable tokens
+func HashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
 func VerifyRefreshToken(storedToken, providedToken string) bool {
 	return subtle.ConstantTimeCompare([]byte(storedToken), []byte(providedToken)) == 1
 }
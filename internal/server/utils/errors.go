@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"go-playground/internal/server/types"
+	"net/http"
+)
+
+// APIError is an error handlers pass to c.Error to answer with a specific problem response.
+// Code is part of the API, keep it stable once clients may depend on it.
+type APIError struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []types.FieldError
+}
+
+func (e *APIError) Error() string {
+	return e.Detail
+}
+
+// NewAPIError creates an error answered with the given status and code
+func NewAPIError(status int, code string, detail string) *APIError {
+	return &APIError{Status: status, Code: code, Detail: detail}
+}
+
+// ValidationFailed reports request fields that are well-formed but not acceptable
+func ValidationFailed(fields ...types.FieldError) *APIError {
+	return &APIError{
+		Status: http.StatusUnprocessableEntity,
+		Code:   "validation_failed",
+		Detail: "The request contains invalid fields",
+		Fields: fields,
+	}
+}
+
+// ErrInvalidCredentials is returned for every failed login, without telling which part was wrong
+var ErrInvalidCredentials = NewAPIError(http.StatusUnauthorized, "invalid_credentials", "Invalid credentials")
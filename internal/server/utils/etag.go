@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag returns the entity tag of a version of an entity, as sent in If-Match to change it
+func ETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// SetETag sends the entity tag of the version of the entity in the response
+func SetETag(c *gin.Context, version uint) {
+	c.Header("ETag", ETag(version))
+}
+
+// CheckIfMatch makes sure the If-Match header of a request changing an entity lists the current
+// version of the entity, so a change based on an outdated copy is refused instead of overwriting
+// the changes made since. Requests without the header go through, unless REQUIRE_IF_MATCH is set
+// and the request is a PATCH or DELETE.
+func CheckIfMatch(c *gin.Context, version uint) error {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		required, _ := strconv.ParseBool(os.Getenv("REQUIRE_IF_MATCH"))
+		if required && (c.Request.Method == http.MethodPatch || c.Request.Method == http.MethodDelete) {
+			return NewAPIError(http.StatusPreconditionRequired, "precondition_required", "The If-Match header with the ETag of the entity is required")
+		}
+		return nil
+	}
+
+	current := ETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		// Weak tags never match, If-Match uses the strong comparison (RFC 9110 13.1.1)
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == current {
+			return nil
+		}
+	}
+
+	SetETag(c, version)
+	return NewAPIError(http.StatusPreconditionFailed, "precondition_failed", fmt.Sprintf("The entity was changed since it was read, its current ETag is %s", current))
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FileURL returns the absolute URL under which a stored file is served.
+func FileURL(c *gin.Context, key string) string {
+	return PublicURL(c, "/api/v1/files/"+key)
+}
+
+// PublicURL returns the absolute URL of a path of the API.
+// PUBLIC_URL overrides the host of the request, e.g. when running behind a proxy.
+func PublicURL(c *gin.Context, path string) string {
+	base := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + c.Request.Host
+	}
+	return base + path
+}
@@ -3,24 +3,33 @@ package utils
 import (
 	"errors"
 	"go-playground/internal/database/models"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("very_secret")
-
 type Claims struct {
-	UserID   uint
-	Username string
+	UserID             uint
+	Username           string
+	Role               string
+	Permissions        []string
+	MustChangePassword bool `json:",omitempty"` // Limits the token to changing the password
 	jwt.RegisteredClaims
 }
 
+// HasPermission reports whether the token grants the given permission
+func (c *Claims) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
 func GenerateJWT(user models.User) (string, error) {
 	// Create the JWT claims, which includes the username and expiry time
 	claims := &Claims{
-		UserID:   user.ID,
-		Username: user.Username,
+		UserID:             user.ID,
+		Username:           user.Username,
+		Permissions:        []string{},
+		MustChangePassword: user.MustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "go-playground",
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -28,21 +37,20 @@ func GenerateJWT(user models.User) (string, error) {
 		},
 	}
 
-	// Create the JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	// Sign the token with the secret key
-	tokenString, err := token.SignedString(jwtSecret)
+	if user.Role != nil {
+		claims.Role = user.Role.Name
+		claims.Permissions = user.Role.PermissionNames()
+	}
 
-	return tokenString, err
+	// Create the JWT token and sign it with the active key
+	return signToken(claims)
 }
 
 func ValidateJWT(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
-	// Parse the token
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-		return jwtSecret, nil
-	})
+	// Parse the token, verifying it with the key named in its kid header
+	token, err := jwt.ParseWithClaims(tokenString, claims, verificationKey)
 
 	if err != nil {
 		return nil, err
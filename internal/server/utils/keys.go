@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is a key that tokens can be verified with, and signed with when private is set
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private any
+	public  any
+}
+
+// keySet holds every key that is currently accepted and the one used to sign new tokens.
+// Keeping retired keys around lets tokens signed before a rotation stay valid until they expire.
+type keySet struct {
+	signing *signingKey
+	keys    map[string]*signingKey
+}
+
+var (
+	loadedKeys     *keySet
+	loadedKeysErr  error
+	loadedKeysOnce sync.Once
+)
+
+// LoadSigningKeys reads the JWT keys from the environment:
+//
+//   - JWT_KEYS_DIR: directory of PEM files, the file name without extension is the key ID.
+//     Private RSA keys sign with RS256, private Ed25519 keys with EdDSA, public keys only verify.
+//   - JWT_SIGNING_KID: ID of the key used to sign new tokens, required when the directory
+//     holds more than one private key.
+//   - JWT_SECRET: shared HS256 secret, used to sign when no private key is configured.
+//
+// Without any configuration a random Ed25519 key is generated, so tokens do not survive a restart.
+func LoadSigningKeys() error {
+	loadedKeysOnce.Do(func() {
+		loadedKeys, loadedKeysErr = loadKeySet()
+	})
+	return loadedKeysErr
+}
+
+func keys() *keySet {
+	if err := LoadSigningKeys(); err != nil {
+		log.Fatalf("could not load JWT signing keys: %v", err)
+	}
+	return loadedKeys
+}
+
+func loadKeySet() (*keySet, error) {
+	set := &keySet{keys: map[string]*signingKey{}}
+
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			key, err := loadPEMKey(file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			set.keys[key.id] = key
+		}
+	}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		id := os.Getenv("JWT_SECRET_KID")
+		if id == "" {
+			id = "hs256"
+		}
+		set.keys[id] = &signingKey{id: id, method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+	}
+
+	if len(set.keys) == 0 {
+		log.Println("No JWT keys configured, using a random Ed25519 key")
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		set.keys["ephemeral"] = &signingKey{id: "ephemeral", method: jwt.SigningMethodEdDSA, private: private, public: public}
+	}
+
+	if kid := os.Getenv("JWT_SIGNING_KID"); kid != "" {
+		key, ok := set.keys[kid]
+		if !ok || key.private == nil {
+			return nil, fmt.Errorf("JWT_SIGNING_KID %q does not name a private key", kid)
+		}
+		set.signing = key
+		return set, nil
+	}
+
+	var candidates []*signingKey
+	for _, key := range set.keys {
+		if key.private != nil {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) != 1 {
+		return nil, errors.New("set JWT_SIGNING_KID to choose which key signs new tokens")
+	}
+	set.signing = candidates[0]
+
+	return set, nil
+}
+
+func loadPEMKey(file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{id: strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+
+	return key, nil
+}
+
+// signToken signs the claims with the active key and records its ID in the kid header
+func signToken(claims jwt.Claims) (string, error) {
+	key := keys().signing
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+// verificationKey looks up the key named by the kid header of a token
+func verificationKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := keys().keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+
+	return key.public, nil
+}
+
+// JSONWebKey is a public key in the JWK format (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is the document served at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// PublicKeySet returns the public keys other services can verify tokens with.
+// Shared HS256 secrets are never included.
+func PublicKeySet() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+
+	for _, key := range keys().keys {
+		jwk := JSONWebKey{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
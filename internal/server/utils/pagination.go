@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"go-playground/internal/database/query"
+	"go-playground/internal/server/types"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseListQuery parses the pagination, filter and sort parameters of the request
+// against the whitelisted fields of a model.
+func ParseListQuery(c *gin.Context, fields query.Fields) (query.ListQuery, error) {
+	return query.Parse(c.Request.URL.Query(), fields)
+}
+
+// ParseLimit reads the limit of endpoints that take no other list parameters, such as search.
+// It defaults to query.DefaultLimit, is at least 1 and is capped at query.MaxLimit like the limit of lists.
+func ParseLimit(c *gin.Context) (int, error) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(query.DefaultLimit)))
+	if err != nil || limit < 1 {
+		return 0, NewAPIError(http.StatusBadRequest, "invalid_query", "Invalid limit format")
+	}
+	return min(limit, query.MaxLimit), nil
+}
+
+// NewListResponse wraps a page of data in the list envelope, with links to the
+// next and previous pages that keep the current filters and sorting.
+// Requests paging with a cursor get cursor links, others keep offset links.
+func NewListResponse[T any](c *gin.Context, data []T, page query.Page, q query.ListQuery) types.ListResponse[T] {
+	if data == nil {
+		data = []T{}
+	}
+
+	response := types.ListResponse[T]{
+		Data:   data,
+		Total:  page.Total,
+		Limit:  q.Limit,
+		Offset: q.Offset,
+	}
+
+	if page.NextCursor != "" {
+		response.NextCursor = &page.NextCursor
+	}
+	response.Links = NewPageLinks(c, page, q)
+
+	return response
+}
+
+// NewPageLinks links the next and previous pages of a list, paging with a cursor when the
+// request did
+func NewPageLinks(c *gin.Context, page query.Page, q query.ListQuery) types.PageLinks {
+	var links types.PageLinks
+	if q.After != nil {
+		if page.NextCursor != "" {
+			next := pageURL(c, "after", page.NextCursor)
+			links.Next = &next
+		}
+		return links
+	}
+
+	if q.Limit > 0 && page.Total != nil && int64(q.Offset+q.Limit) < *page.Total {
+		next := pageURL(c, "offset", strconv.Itoa(q.Offset+q.Limit))
+		links.Next = &next
+	}
+	if q.Offset > 0 {
+		prev := pageURL(c, "offset", strconv.Itoa(max(q.Offset-q.Limit, 0)))
+		links.Prev = &prev
+	}
+	return links
+}
+
+// pageURL returns the current request URL with a single query parameter replaced
+func pageURL(c *gin.Context, key string, value string) string {
+	u := *c.Request.URL
+	values := u.Query()
+	values.Set(key, value)
+	u.RawQuery = values.Encode()
+	return u.RequestURI()
+}
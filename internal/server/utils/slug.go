@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Slugify converts a display name into a lowercase, URL friendly slug.
+// Runs of anything other than letters and digits collapse into a single hyphen.
+func Slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		if !hyphen && b.Len() > 0 {
+			b.WriteRune('-')
+			hyphen = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
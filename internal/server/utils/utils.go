@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"errors"
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -12,12 +12,12 @@ import (
 func GetIDParam(c *gin.Context) (uint, error) {
 	idStr := c.Param("id")
 	if idStr == "" {
-		return 0, errors.New("missing id parameter")
+		return 0, NewAPIError(http.StatusBadRequest, "invalid_id", "Missing id parameter")
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		return 0, errors.New("invalid id format: must be a postive number")
+		return 0, NewAPIError(http.StatusBadRequest, "invalid_id", "Invalid id format: must be a positive number")
 	}
 
 	return uint(id), nil
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+type bucketObject struct {
+	data        []byte
+	contentType string
+}
+
+// fakeBucket is an S3 endpoint holding objects in memory. It only serves path style
+// requests to its bucket and rejects every request without a valid signature.
+type fakeBucket struct {
+	t      *testing.T
+	bucket string
+
+	mu       sync.Mutex
+	objects  map[string]bucketObject
+	requests []string // method and escaped path of every request
+}
+
+func newFakeBucket(t *testing.T, bucket string) (*fakeBucket, *httptest.Server) {
+	fake := &fakeBucket{t: t, bucket: bucket, objects: map[string]bucketObject{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (b *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.requests = append(b.requests, r.Method+" "+r.URL.EscapedPath())
+
+	if err := b.verifySignature(r); err != nil {
+		http.Error(w, "SignatureDoesNotMatch: "+err.Error(), http.StatusForbidden)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.EscapedPath(), "/"+b.bucket+"/")
+	if !ok {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if int64(len(data)) != r.ContentLength {
+			http.Error(w, "IncompleteBody", http.StatusBadRequest)
+			return
+		}
+		b.objects[key] = bucketObject{data: data, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		object, ok := b.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Write(object.data)
+	case http.MethodDelete:
+		if _, ok := b.objects[key]; !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		delete(b.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verifySignature checks the Signature Version 4 Authorization header of a request
+// the way S3 does, from the request as it arrived.
+func (b *fakeBucket) verifySignature(r *http.Request) error {
+	amzDate := r.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return errors.New("missing X-Amz-Date")
+	}
+	if time.Since(signedAt).Abs() > 15*time.Minute {
+		return errors.New("request time too skewed")
+	}
+
+	scope := signedAt.Format("20060102") + "/us-east-1/s3/aws4_request"
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		"host:" + r.Host + "\nx-amz-content-sha256:" + r.Header.Get("X-Amz-Content-Sha256") + "\nx-amz-date:" + amzDate + "\n",
+		"host;x-amz-content-sha256;x-amz-date",
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+testSecretKey), signedAt.Format("20060102"))
+	key = hmacSHA256(key, "us-east-1")
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	want := "AWS4-HMAC-SHA256 Credential=" + testAccessKey + "/" + scope +
+		", SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=" + hex.EncodeToString(hmacSHA256(key, stringToSign))
+	if got := r.Header.Get("Authorization"); got != want {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func newTestS3(t *testing.T, endpoint string) *S3 {
+	s, err := NewS3(S3Config{
+		Endpoint:  endpoint + "/",
+		Bucket:    "covers",
+		AccessKey: testAccessKey,
+		SecretKey: testSecretKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestS3PutGetDelete(t *testing.T) {
+	fake, server := newFakeBucket(t, "covers")
+	s := newTestS3(t, server.URL)
+	ctx := context.Background()
+
+	key := "covers/1/front image+1.png"
+	data := "\x89PNG\r\n\x1a\nimage data"
+	if err := s.Put(ctx, key, strings.NewReader(data+"trailing bytes beyond the size"), int64(len(data)), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, info, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != data {
+		t.Errorf("Get returned %q, want %q", got, data)
+	}
+	if info.Size != int64(len(data)) || info.ContentType != "image/png" {
+		t.Errorf("Get returned size %d and type %q, want %d and image/png", info.Size, info.ContentType, len(data))
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete returned %v, want ErrNotFound", err)
+	}
+
+	// Requests address the bucket in the path, every segment of the key is escaped
+	want := []string{
+		"PUT /covers/covers/1/front%20image%2B1.png",
+		"GET /covers/covers/1/front%20image%2B1.png",
+		"DELETE /covers/covers/1/front%20image%2B1.png",
+		"GET /covers/covers/1/front%20image%2B1.png",
+	}
+	if strings.Join(fake.requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("bucket received\n%s\nwant\n%s", strings.Join(fake.requests, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestS3Errors(t *testing.T) {
+	fake, server := newFakeBucket(t, "covers")
+	s := newTestS3(t, server.URL)
+	ctx := context.Background()
+
+	if _, _, err := s.Get(ctx, "covers/missing.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing object returned %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "covers/missing.png"); err != nil {
+		t.Errorf("Delete of a missing object returned %v, want nil", err)
+	}
+
+	for _, key := range []string{"", "/covers/1.png", "covers/../1.png", `covers\1.png`} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), 1, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put of key %q returned %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if len(fake.requests) != 2 {
+		t.Errorf("invalid keys were sent to the bucket: %v", fake.requests)
+	}
+
+	// Any other error response is reported with its status and message
+	wrongKey := newTestS3(t, server.URL)
+	wrongKey.config.SecretKey = "wrong"
+	err := wrongKey.Put(ctx, "covers/1.png", strings.NewReader("x"), 1, "image/png")
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Put with a wrong secret returned %v", err)
+	}
+	for _, part := range []string{"PUT", "/covers/covers/1.png", "403 Forbidden", "SignatureDoesNotMatch"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("error %q does not contain %q", err, part)
+		}
+	}
+	if len(fake.objects) != 0 {
+		t.Errorf("a request with a wrong signature stored %d objects", len(fake.objects))
+	}
+}
+
+func TestNewS3(t *testing.T) {
+	if _, err := NewS3(S3Config{Bucket: "covers", AccessKey: "a", SecretKey: "b"}); err == nil {
+		t.Error("NewS3 accepted a config without an endpoint")
+	}
+	if _, err := NewS3(S3Config{Endpoint: "http://localhost:9000", Bucket: "covers"}); err == nil {
+		t.Error("NewS3 accepted a config without credentials")
+	}
+
+	s, err := NewS3(S3Config{Endpoint: "http://localhost:9000/", Bucket: "covers", AccessKey: "a", SecretKey: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.config.Region != "us-east-1" || s.config.Endpoint != "http://localhost:9000" {
+		t.Errorf("NewS3 kept region %q and endpoint %q", s.config.Region, s.config.Endpoint)
+	}
+}
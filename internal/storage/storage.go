@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	_ "github.com/joho/godotenv/autoload"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("object not found")
+
+// ErrInvalidKey is returned for keys that are empty or could escape the storage root
+var ErrInvalidKey = errors.New("invalid object key")
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// Storage is a blob store for uploaded files such as cover images.
+// Keys are slash separated paths like covers/1/image.png.
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing any existing object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Get opens the object stored under key. The caller closes the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+
+	// Delete removes the object stored under key, deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+var storageInstance Storage
+
+// New returns the storage backend selected by STORAGE_DRIVER, either "local" (default) or "s3".
+func New() Storage {
+	// Reuse the backend
+	if storageInstance != nil {
+		return storageInstance
+	}
+
+	var err error
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "./uploads"
+		}
+		storageInstance, err = NewLocal(dir)
+	case "s3":
+		storageInstance, err = NewS3(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	default:
+		err = fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+	return storageInstance
+}
+
+// validateKey rejects keys that could escape the storage root
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}